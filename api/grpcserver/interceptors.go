@@ -0,0 +1,111 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/undersleep7x/cryo-project/internal/metrics"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// load balancer probes call health without credentials
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+func UnaryAuth(token secrets.Secret) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := authorize(ctx, info.FullMethod, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamAuth(token secrets.Secret) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(stream.Context(), info.FullMethod, token); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// expects "authorization: Bearer <token>" metadata. the token is read per call so a rotation
+// picked up by the secret store applies without a restart
+func authorize(ctx context.Context, method string, token secrets.Secret) error {
+	if strings.HasPrefix(method, healthServicePrefix) {
+		return nil
+	}
+	expected, err := token.Value(ctx)
+	if err != nil {
+		log.Printf("Failed to resolve grpc auth token: %v", err)
+		return status.Error(codes.Unavailable, "authentication unavailable")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		presented, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(presented), []byte(expected)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+}
+
+// the grpc counterpart of the http route deadline. a caller's own tighter deadline still wins,
+// and streams are long lived so they're left without one
+func UnaryTimeout(d time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+func UnaryLogging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		log.Printf("gRPC %s %s (%s)", info.FullMethod, status.Code(err), time.Since(start))
+		return resp, err
+	}
+}
+
+func StreamLogging() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		log.Printf("gRPC stream %s opened", info.FullMethod)
+		err := handler(srv, stream)
+		log.Printf("gRPC stream %s closed %s (%s)", info.FullMethod, status.Code(err), time.Since(start))
+		return err
+	}
+}
+
+func UnaryMetrics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func StreamMetrics() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		observe(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observe(method string, start time.Time, err error) {
+	code := status.Code(err).String()
+	metrics.GRPCRequests.WithLabelValues(method, code).Inc()
+	metrics.GRPCDuration.WithLabelValues(method, code).Observe(time.Since(start).Seconds())
+}
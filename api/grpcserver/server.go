@@ -0,0 +1,27 @@
+package grpcserver
+
+import (
+	"time"
+
+	cryov1 "github.com/undersleep7x/cryo-project/api/proto/cryo/v1"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// grpc server for the price and transaction services. logging and metrics wrap auth so
+// rejected calls are still counted, and tracing continues the caller's traceparent. unary calls
+// get requestTimeout, as the http routes do
+func New(authToken secrets.Secret, requestTimeout time.Duration, priceServer cryov1.PriceServiceServer, txnServer cryov1.TransactionServiceServer) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(UnaryLogging(), UnaryMetrics(), UnaryAuth(authToken), UnaryTimeout(requestTimeout)),
+		grpc.ChainStreamInterceptor(StreamLogging(), StreamMetrics(), StreamAuth(authToken)),
+	)
+	cryov1.RegisterPriceServiceServer(server, priceServer)
+	cryov1.RegisterTransactionServiceServer(server, txnServer)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	return server
+}
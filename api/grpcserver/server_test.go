@@ -0,0 +1,193 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cryov1 "github.com/undersleep7x/cryo-project/api/proto/cryo/v1"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+	"github.com/undersleep7x/cryo-project/internal/accounts/accountsfake"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"github.com/undersleep7x/cryo-project/internal/crypto/keys"
+	"github.com/undersleep7x/cryo-project/internal/infra/cache"
+	"github.com/undersleep7x/cryo-project/internal/metrics"
+	"github.com/undersleep7x/cryo-project/internal/platform/redisstore/redisfake"
+	"github.com/undersleep7x/cryo-project/internal/prices"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+	"github.com/undersleep7x/cryo-project/internal/transactions"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testRequestTimeout = 5 * time.Second
+
+// the real services behind an in-memory listener, with the price provider stubbed and a
+// merchant holding a btc wallet registered
+func newTestConn(t *testing.T) (*grpc.ClientConn, string) {
+	originalFetchPrices := prices.FetchPrices
+	t.Cleanup(func() { prices.FetchPrices = originalFetchPrices })
+	prices.FetchPrices = func(ctx context.Context, cryptos []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+		dummyResponse := &resty.Response{RawResponse: &http.Response{StatusCode: http.StatusOK}}
+		dummyResponse.SetBody([]byte(`{"bitcoin": {"usd": 50000}}`))
+		return dummyResponse, nil
+	}
+
+	registry, err := assets.NewRegistry(assets.DefaultAssets)
+	require.NoError(t, err)
+	settings := prices.NewSettings(prices.Config{BaseURL: "https://dummy-coingecko.com", Timeout: 5, StreamPollInterval: time.Hour})
+	broker := prices.NewLocalPriceBroker()
+	priceService := prices.NewFetchCryptoPriceService(cache.NewPriceCache(redisfake.New()), settings)
+	txnConfig := transactions.Config{QuoteLockWindow: 15 * time.Minute, QuoteTolerance: 0.01, Keys: keys.NewDeriver(secrets.Static("test-key"))}
+	accountService := accounts.NewAccountService(accountsfake.New(), registry, accounts.Config{Keys: keys.NewDeriver(secrets.Static("test-key"))})
+	txnService := transactions.NewTransactionsService(transactions.NewTxnRepository(), priceService, registry, accountService, broker, txnConfig)
+
+	registration, err := accountService.RegisterUser(context.Background())
+	require.NoError(t, err)
+	merchant, err := accountService.CreateMerchant(context.Background(), accounts.MerchantRequest{AccountNumber: registration.AccountNumber, Name: "Test Merchant"})
+	require.NoError(t, err)
+	_, err = accountService.AttachWallet(context.Background(), accounts.OwnerMerchant, merchant.ID, registration.AccountNumber, accounts.WalletRequest{Currency: "BTC", Type: accounts.WalletOTA})
+	require.NoError(t, err)
+
+	server := New(secrets.Static("test-token"), testRequestTimeout,
+		prices.NewPriceGRPCServer(priceService, prices.NewPriceStreamer(priceService, broker, settings), registry),
+		transactions.NewTransactionGRPCServer(txnService))
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, merchant.ID
+}
+
+func authed(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer test-token")
+}
+
+func TestAuth(t *testing.T) {
+	conn, _ := newTestConn(t)
+	client := cryov1.NewPriceServiceClient(conn)
+	req := &cryov1.FetchPricesRequest{Cryptos: []string{"BTC"}, Currency: "usd"}
+	method := cryov1.PriceService_FetchPrices_FullMethodName
+	rejected := testutil.ToFloat64(metrics.GRPCRequests.WithLabelValues(method, codes.Unauthenticated.String()))
+
+	t.Run("Missing Token", func(t *testing.T) {
+		_, err := client.FetchPrices(context.Background(), req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Wrong Token", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer nope")
+		_, err := client.FetchPrices(ctx, req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		stream, err := client.StreamPrices(ctx, &cryov1.StreamPricesRequest{Cryptos: []string{"BTC"}, Currency: "usd"})
+		require.NoError(t, err)
+		_, err = stream.Recv() // stream errors surface on the first receive
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Health Is Open", func(t *testing.T) {
+		resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	})
+
+	assert.Equal(t, rejected+2, testutil.ToFloat64(metrics.GRPCRequests.WithLabelValues(method, codes.Unauthenticated.String())))
+}
+
+func TestPriceService(t *testing.T) {
+	conn, _ := newTestConn(t)
+	client := cryov1.NewPriceServiceClient(conn)
+
+	t.Run("Fetch Prices", func(t *testing.T) {
+		resp, err := client.FetchPrices(authed(context.Background()), &cryov1.FetchPricesRequest{Cryptos: []string{"BTC", "ETH"}, Currency: "USD"})
+		require.NoError(t, err)
+		assert.Equal(t, 50000.00, resp.Prices["BTC"].Price)
+		assert.Equal(t, cryov1.PriceStatus_PRICE_STATUS_OK, resp.Prices["BTC"].Status)
+		assert.Equal(t, cryov1.PriceStatus_PRICE_STATUS_NOT_FOUND, resp.Prices["ETH"].Status)
+	})
+
+	t.Run("Unsupported Asset", func(t *testing.T) {
+		_, err := client.FetchPrices(authed(context.Background()), &cryov1.FetchPricesRequest{Cryptos: []string{"notacoin"}, Currency: "usd"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Stream Prices", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(authed(context.Background()), 5*time.Second)
+		defer cancel()
+		stream, err := client.StreamPrices(ctx, &cryov1.StreamPricesRequest{Cryptos: []string{"BTC"}, Currency: "usd"})
+		require.NoError(t, err)
+
+		update, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "bitcoin", update.Crypto)
+		assert.Equal(t, 50000.00, update.Price)
+	})
+}
+
+func TestRequestDeadline(t *testing.T) {
+	conn, _ := newTestConn(t)
+	var deadline time.Time
+	var hasDeadline bool
+	prices.FetchPrices = func(ctx context.Context, cryptos []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+		deadline, hasDeadline = ctx.Deadline()
+		dummyResponse := &resty.Response{RawResponse: &http.Response{StatusCode: http.StatusOK}}
+		dummyResponse.SetBody([]byte(`{"bitcoin": {"usd": 50000}}`))
+		return dummyResponse, nil
+	}
+
+	// a caller without a deadline still gets the route's
+	start := time.Now()
+	_, err := cryov1.NewPriceServiceClient(conn).FetchPrices(authed(context.Background()), &cryov1.FetchPricesRequest{Cryptos: []string{"BTC"}, Currency: "usd"})
+	require.NoError(t, err)
+	require.True(t, hasDeadline)
+	assert.WithinDuration(t, start.Add(testRequestTimeout), deadline, time.Second)
+}
+
+func TestTransactionService(t *testing.T) {
+	conn, merchantId := newTestConn(t)
+	client := cryov1.NewTransactionServiceClient(conn)
+
+	t.Run("Create Invoice", func(t *testing.T) {
+		resp, err := client.CreateInvoice(authed(context.Background()), &cryov1.CreateInvoiceRequest{RecipientId: merchantId, Currency: "btc", FiatCurrency: "usd", FiatAmount: 100})
+		require.NoError(t, err)
+		assert.Equal(t, "BTC", resp.Currency)
+		assert.Equal(t, 0.002, resp.Amount)
+		assert.Equal(t, 50000.00, resp.Quote.Rate)
+	})
+
+	t.Run("Invalid Invoice", func(t *testing.T) {
+		_, err := client.CreateInvoice(authed(context.Background()), &cryov1.CreateInvoiceRequest{RecipientId: merchantId, Currency: "btc"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Send Payment", func(t *testing.T) {
+		resp, err := client.SendPayment(authed(context.Background()), &cryov1.SendPaymentRequest{SenderId: "user", Currency: "BTC", Amount: 0.5, PaymentAddress: "addr"})
+		require.NoError(t, err)
+		assert.Equal(t, "Pending", resp.Status)
+		assert.Equal(t, "addr", resp.PaymentAddress)
+	})
+
+	t.Run("Watch Requires Id", func(t *testing.T) {
+		stream, err := client.WatchTransaction(authed(context.Background()), &cryov1.WatchTransactionRequest{})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
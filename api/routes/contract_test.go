@@ -0,0 +1,404 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/api/openapi"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"github.com/undersleep7x/cryo-project/internal/prices"
+	"github.com/undersleep7x/cryo-project/internal/transactions"
+	"github.com/undersleep7x/cryo-project/pkg/client"
+)
+
+// the parts of the openapi document the contract is checked against
+type spec struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type schema struct {
+	Ref                  string            `json:"$ref"`
+	Type                 string            `json:"type"`
+	Format               string            `json:"format"`
+	Enum                 []string          `json:"enum"`
+	Items                *schema           `json:"items"`
+	Properties           map[string]schema `json:"properties"`
+	Required             []string          `json:"required"`
+	AdditionalProperties *schema           `json:"additionalProperties"`
+}
+
+type operation struct {
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema schema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+// structs handlers bind or return, by the schema that documents them
+var contractTypes = map[string]any{
+	"AssetPrice":         prices.AssetPrice{},
+	"HistoricalPrice":    prices.HistoricalPrice{},
+	"OHLCCandle":         prices.OHLCCandle{},
+	"PriceResponse":      client.PriceResponse{},
+	"PriceUpdate":        prices.PriceUpdate{},
+	"PrewarmStatus":      prices.PrewarmStatus{},
+	"PriceQuote":         transactions.PriceQuote{},
+	"InvoiceRequest":     transactions.InvoiceRequest{},
+	"InvoiceResponse":    transactions.InvoiceResponse{},
+	"PaymentRequest":     transactions.PaymentRequest{},
+	"PaymentResponse":    transactions.PaymentResponse{},
+	"TransactionDetails": transactions.TransactionDetails{},
+	"User":               accounts.User{},
+	"Registration":       accounts.Registration{},
+	"Merchant":           accounts.Merchant{},
+	"MerchantRequest":    accounts.MerchantRequest{},
+	"MerchantUpdate":     accounts.MerchantUpdate{},
+	"Wallet":             accounts.Wallet{},
+	"WalletRequest":      accounts.WalletRequest{},
+}
+
+// the sdk declares its own copies so consumers don't import the server, held to the same schemas
+var clientTypes = map[string]any{
+	"AssetPrice":         client.AssetPrice{},
+	"PriceQuote":         client.PriceQuote{},
+	"InvoiceRequest":     client.InvoiceRequest{},
+	"InvoiceResponse":    client.InvoiceResponse{},
+	"PaymentRequest":     client.PaymentRequest{},
+	"PaymentResponse":    client.PaymentResponse{},
+	"TransactionDetails": client.TransactionDetails{},
+}
+
+func loadSpec(t *testing.T) spec {
+	var s spec
+	require.NoError(t, json.Unmarshal(openapi.Spec, &s))
+	return s
+}
+
+func setupTestRouter() *gin.Engine {
+	router := gin.New()
+	server := config.ServerConfig{RequestTimeout: time.Second, HistoryTimeout: time.Second}
+	SetupRoutes(router, server, &prices.PriceHandler{}, &prices.PriceHistoryHandler{}, &prices.PriceStreamHandler{}, &prices.PrewarmHandler{}, &transactions.TransactionsHandler{}, &accounts.AccountsHandler{})
+	return router
+}
+
+// price services answering every call with fixed data, so real handler responses can be recorded
+type stubPriceService struct{}
+
+func (stubPriceService) FetchCryptoPrice(ctx context.Context, ids []string, currency string) (*prices.PriceResult, error) {
+	fetchedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &prices.PriceResult{Currency: currency, Prices: map[string]prices.AssetPrice{
+		"bitcoin":  {Crypto: "bitcoin", Status: prices.StatusOK, Price: 97000.5, Source: "coingecko", FetchedAt: &fetchedAt},
+		"ethereum": {Crypto: "ethereum", Status: prices.StatusNotFound, Error: "no price for usd"},
+	}}, nil
+}
+
+type stubHistoryService struct{}
+
+func (stubHistoryService) FetchHistoricalPrice(ctx context.Context, crypto string, currency string, at time.Time) (*prices.HistoricalPrice, error) {
+	return &prices.HistoricalPrice{Crypto: crypto, Currency: currency, Price: 97000.5, PricedAt: at, Source: "coingecko"}, nil
+}
+
+func (stubHistoryService) FetchOHLC(ctx context.Context, crypto string, currency string, interval string, from time.Time, to time.Time) ([]prices.OHLCCandle, error) {
+	candles := make([]prices.OHLCCandle, 0, 2)
+	for openTime := from; openTime.Before(to); openTime = openTime.Add(time.Hour) {
+		candles = append(candles, prices.OHLCCandle{Crypto: crypto, Currency: currency, Interval: interval, OpenTime: openTime, Open: 1, High: 2, Low: 0.5, Close: 1.5, Source: "coingecko"})
+	}
+	return candles, nil
+}
+
+func setupRecordingRouter(t *testing.T) *gin.Engine {
+	registry, err := assets.NewRegistry(assets.DefaultAssets)
+	require.NoError(t, err)
+	prewarmer := prices.NewPrewarmer(nil, prices.NewSettings(prices.Config{}), prices.PrewarmConfig{Watchlist: []prices.WatchPair{{Crypto: "bitcoin", Currency: "usd"}}})
+
+	router := gin.New()
+	server := config.ServerConfig{RequestTimeout: time.Second, HistoryTimeout: time.Second}
+	SetupRoutes(router, server, prices.NewPriceHandler(stubPriceService{}, registry), prices.NewPriceHistoryHandler(stubHistoryService{}, registry), &prices.PriceStreamHandler{}, prices.NewPrewarmHandler(prewarmer), &transactions.TransactionsHandler{}, &accounts.AccountsHandler{})
+	return router
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	s := loadSpec(t)
+
+	routed := map[string]bool{}
+	for _, route := range setupTestRouter().Routes() {
+		path, ok := strings.CutPrefix(route.Path, apiVersion)
+		if !ok {
+			continue
+		}
+		path = specPath(path)
+		operation := strings.ToLower(route.Method) + " " + path
+		routed[operation] = true
+		_, documented := s.Paths[path][strings.ToLower(route.Method)]
+		assert.True(t, documented, "%s is routed but missing from the spec", operation)
+	}
+	for path, operations := range s.Paths {
+		for method := range operations {
+			assert.True(t, routed[method+" "+path], "%s %s is in the spec but not routed", method, path)
+		}
+	}
+}
+
+func TestSpecMatchesStructs(t *testing.T) {
+	s := loadSpec(t)
+
+	for name, value := range contractTypes {
+		t.Run(name, func(t *testing.T) {
+			assertMatchesSchema(t, s, name, value)
+		})
+	}
+	for name, value := range clientTypes {
+		t.Run("client."+name, func(t *testing.T) {
+			assertMatchesSchema(t, s, name, value)
+		})
+	}
+}
+
+// the gin.H envelopes handlers build aren't structs, so their responses are recorded and checked
+// against the schema the spec documents for the status returned
+func TestResponsesMatchSpec(t *testing.T) {
+	s := loadSpec(t)
+	router := setupRecordingRouter(t)
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"Prices With Errors", "/price?crypto=btc,eth&currency=usd", http.StatusMultiStatus},
+		{"Historical Price", "/price/history?crypto=btc&currency=usd&at=2026-01-02T03:04:05Z", http.StatusOK},
+		{"Candles", "/price/ohlc?crypto=btc&currency=usd&interval=1h&from=2026-01-02T00:00:00Z&to=2026-01-02T02:00:00Z", http.StatusOK},
+		{"Watchlist", "/price/watchlist", http.StatusOK},
+		{"Missing Parameter", "/price?crypto=btc", http.StatusBadRequest},
+		{"Unsupported Asset", "/price/history?crypto=notacoin&currency=usd&at=2026-01-02T03:04:05Z", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", apiVersion+tt.target, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+
+			path, _, _ := strings.Cut(tt.target, "?")
+			var op operation
+			require.NoError(t, json.Unmarshal(s.Paths[path]["get"], &op))
+			response, ok := op.Responses[strconv.Itoa(tt.status)]
+			require.True(t, ok, "%d isn't documented for get %s", tt.status, path)
+			content, ok := response.Content["application/json"]
+			require.True(t, ok, "%d for get %s has no json body", tt.status, path)
+
+			var body any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assertValid(t, s, content.Schema, body, "body")
+		})
+	}
+}
+
+func TestOpenAPIRoute(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+	setupTestRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, string(openapi.Spec), w.Body.String())
+}
+
+func TestDeprecatedAliases(t *testing.T) {
+	router := setupTestRouter()
+
+	t.Run("Legacy Path", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code) // same handler, still validates params
+		assert.Equal(t, "true", w.Header().Get("Deprecation"))
+		assert.Equal(t, `</v1/price>; rel="successor-version"`, w.Header().Get("Link"))
+	})
+
+	t.Run("Versioned Path", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/price", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get("Deprecation"))
+	})
+}
+
+func assertMatchesSchema(t *testing.T, s spec, name string, value any) {
+	documented, ok := s.Components.Schemas[name]
+	require.True(t, ok, "schema %s missing from the spec", name)
+
+	fields := jsonFields(reflect.TypeOf(value))
+	assert.Equal(t, sortedKeys(documented.Properties), sortedKeys(fields), "properties of %s drifted", name)
+	for field, goField := range fields {
+		property, ok := documented.Properties[field]
+		if !ok {
+			continue
+		}
+		assert.True(t, compatible(goField.Type, property), "%s.%s is %s in go but %+v in the spec", name, field, goField.Type, property)
+	}
+	for _, field := range documented.Required {
+		goField, ok := fields[field]
+		if assert.True(t, ok, "%s requires %s but go has no such field", name, field) {
+			assert.NotContains(t, goField.Tag.Get("json"), ",omitempty", "%s requires %s but go omits it when empty", name, field)
+		}
+	}
+}
+
+// check a decoded json value against a spec schema, following refs and enforcing required
+// properties. at is where in the body value sits, for failure messages
+func assertValid(t *testing.T, s spec, documented schema, value any, at string) {
+	t.Helper()
+	if documented.Ref != "" {
+		resolved, ok := s.Components.Schemas[strings.TrimPrefix(documented.Ref, "#/components/schemas/")]
+		require.True(t, ok, "%s: unresolved %s", at, documented.Ref)
+		assertValid(t, s, resolved, value, at)
+		return
+	}
+
+	switch documented.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !assert.True(t, ok, "%s: want an object, got %T", at, value) {
+			return
+		}
+		for _, field := range documented.Required {
+			assert.Contains(t, object, field, "%s: missing required %s", at, field)
+		}
+		for field, fieldValue := range object {
+			property, ok := documented.Properties[field]
+			if !ok && documented.AdditionalProperties == nil {
+				assert.Fail(t, "undocumented property", "%s.%s", at, field)
+				continue
+			}
+			if !ok {
+				property = *documented.AdditionalProperties
+			}
+			assertValid(t, s, property, fieldValue, at+"."+field)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !assert.True(t, ok, "%s: want an array, got %T", at, value) {
+			return
+		}
+		assert.NotEmpty(t, items, "%s: empty array proves nothing about its items", at)
+		for i, item := range items {
+			assertValid(t, s, *documented.Items, item, at+"["+strconv.Itoa(i)+"]")
+		}
+	case "string":
+		str, ok := value.(string)
+		if !assert.True(t, ok, "%s: want a string, got %T", at, value) {
+			return
+		}
+		if documented.Format == "date-time" {
+			_, err := time.Parse(time.RFC3339, str)
+			assert.NoError(t, err, "%s: want a date-time", at)
+		}
+		if len(documented.Enum) > 0 {
+			assert.Contains(t, documented.Enum, str, "%s: not one of the documented values", at)
+		}
+	case "number":
+		_, ok := value.(float64)
+		assert.True(t, ok, "%s: want a number, got %T", at, value)
+	case "integer":
+		number, ok := value.(float64)
+		assert.True(t, ok && number == float64(int64(number)), "%s: want an integer, got %v", at, value)
+	case "boolean":
+		_, ok := value.(bool)
+		assert.True(t, ok, "%s: want a boolean, got %T", at, value)
+	default:
+		assert.Fail(t, "unsupported schema type", "%s: %q", at, documented.Type)
+	}
+}
+
+// gin's :param segments are written {param} in the spec
+func specPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if param, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + param + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// json properties of a struct by name, flattening embedded structs the way encoding/json does
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			for embedded, embeddedField := range jsonFields(field.Type) {
+				fields[embedded] = embeddedField
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+func compatible(goType reflect.Type, property schema) bool {
+	if goType.Kind() == reflect.Pointer {
+		goType = goType.Elem()
+	}
+	if property.Ref != "" {
+		_, ok := contractTypes[strings.TrimPrefix(property.Ref, "#/components/schemas/")]
+		return ok && goType.Kind() == reflect.Struct && strings.HasSuffix(property.Ref, "/"+goType.Name())
+	}
+	if goType == reflect.TypeOf(time.Time{}) {
+		return property.Type == "string" && property.Format == "date-time"
+	}
+	switch goType.Kind() {
+	case reflect.String:
+		return property.Type == "string"
+	case reflect.Bool:
+		return property.Type == "boolean"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return property.Type == "integer"
+	case reflect.Float32, reflect.Float64:
+		return property.Type == "number"
+	case reflect.Slice:
+		return property.Type == "array" && property.Items != nil && compatible(goType.Elem(), *property.Items)
+	case reflect.Map:
+		return property.Type == "object" && property.AdditionalProperties != nil && compatible(goType.Elem(), *property.AdditionalProperties)
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
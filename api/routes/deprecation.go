@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// mark responses from an old path as deprecated and point clients at the versioned one
+func Deprecated(successorPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successorPrefix+c.FullPath()+">; rel=\"successor-version\"")
+		c.Next()
+	}
+}
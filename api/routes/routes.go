@@ -2,13 +2,66 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/undersleep7x/cryo-project/api/openapi"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"github.com/undersleep7x/cryo-project/internal/metrics"
 	"github.com/undersleep7x/cryo-project/internal/prices"
 	"github.com/undersleep7x/cryo-project/internal/transactions"
 )
 
-func SetupRoutes(router *gin.Engine, priceHandler *prices.PriceHandler, txnHandler *transactions.TransactionsHandler) {
-    router.GET("/", Ping) // ping route
-	router.GET("/price", priceHandler.FetchPrices)// route for sourcing pricing data from CoinGecko API
-	router.POST("/invoice", txnHandler.CreateInvoice) // create a new transaction (p2p payment, invoice, refund, etc)
-	router.POST("/send-payment", txnHandler.SendPayment)
+const apiVersion = "/v1"
+
+func SetupRoutes(router *gin.Engine, server config.ServerConfig, priceHandler *prices.PriceHandler, historyHandler *prices.PriceHistoryHandler, streamHandler *prices.PriceStreamHandler, prewarmHandler *prices.PrewarmHandler, txnHandler *transactions.TransactionsHandler, accountsHandler *accounts.AccountsHandler) {
+	router.GET("/", Ping) // ping route
+	router.GET("/metrics", gin.WrapH(metrics.Handler())) // prometheus scrape endpoint
+
+	v1 := router.Group(apiVersion)
+	v1.GET("/openapi.json", openapi.Handler) // spec for everything registered under /v1
+	registerAPI(v1, server, priceHandler, historyHandler, streamHandler, prewarmHandler, txnHandler)
+	registerAccounts(v1, server, accountsHandler) // added after v1, so no legacy alias
+	registerTransactionReads(v1, server, txnHandler)
+
+	// pre-v1 paths kept for existing clients, answered by the same handlers
+	legacy := router.Group("", Deprecated(apiVersion))
+	registerAPI(legacy, server, priceHandler, historyHandler, streamHandler, prewarmHandler, txnHandler)
+}
+
+func registerAPI(group *gin.RouterGroup, server config.ServerConfig, priceHandler *prices.PriceHandler, historyHandler *prices.PriceHistoryHandler, streamHandler *prices.PriceStreamHandler, prewarmHandler *prices.PrewarmHandler, txnHandler *transactions.TransactionsHandler) {
+	requestTimeout := Timeout(server.RequestTimeout)
+	historyTimeout := Timeout(server.HistoryTimeout)
+	group.GET("/price", requestTimeout, priceHandler.FetchPrices)// route for sourcing pricing data from CoinGecko API
+	group.GET("/price/history", historyTimeout, historyHandler.FetchHistoricalPrice) // price at a point in time, persisted after first lookup
+	group.GET("/price/ohlc", historyTimeout, historyHandler.FetchOHLC) // candles for a window, persisted once buckets close
+	group.GET("/price/stream", streamHandler.StreamPrices) // price change pushes over SSE or websocket, long lived so no deadline
+	group.GET("/price/watchlist", prewarmHandler.FetchStatus) // pre-warm refresh status for watched pairs
+	group.POST("/invoice", requestTimeout, txnHandler.CreateInvoice) // create a new transaction (p2p payment, invoice, refund, etc)
+	group.POST("/send-payment", requestTimeout, txnHandler.SendPayment)
+}
+
+// also added after v1. a single transaction is public by id, a merchant's listing needs the
+// owning account's number in the X-Account-Number header
+func registerTransactionReads(group *gin.RouterGroup, server config.ServerConfig, txnHandler *transactions.TransactionsHandler) {
+	requestTimeout := Timeout(server.RequestTimeout)
+	group.GET("/transactions", requestTimeout, txnHandler.ListTransactions) // ?merchant_id=, newest first
+	group.GET("/transactions/:id", requestTimeout, txnHandler.GetTransaction)
+}
+
+// changes to a user or merchant, and merchant and wallet listings, need the owning account's
+// number in the X-Account-Number header
+func registerAccounts(group *gin.RouterGroup, server config.ServerConfig, accountsHandler *accounts.AccountsHandler) {
+	requestTimeout := Timeout(server.RequestTimeout)
+	group.POST("/users", requestTimeout, accountsHandler.RegisterUser) // new account, the account number is only returned here
+	group.GET("/users/:id", requestTimeout, accountsHandler.GetUser)
+	group.GET("/users/:id/merchants", requestTimeout, accountsHandler.ListMerchants)
+	group.POST("/users/:id/wallets", requestTimeout, accountsHandler.AttachWallet(accounts.OwnerUser))
+	group.GET("/users/:id/wallets", requestTimeout, accountsHandler.ListWallets(accounts.OwnerUser))
+	group.DELETE("/users/:id/wallets/:walletId", requestTimeout, accountsHandler.RemoveWallet(accounts.OwnerUser))
+	group.POST("/merchants", requestTimeout, accountsHandler.CreateMerchant) // merchant under an existing account
+	group.GET("/merchants/:id", requestTimeout, accountsHandler.GetMerchant) // metadata only with the owner's account number
+	group.PATCH("/merchants/:id", requestTimeout, accountsHandler.UpdateMerchant)
+	group.DELETE("/merchants/:id", requestTimeout, accountsHandler.DeleteMerchant) // wallets go with it
+	group.POST("/merchants/:id/wallets", requestTimeout, accountsHandler.AttachWallet(accounts.OwnerMerchant))
+	group.GET("/merchants/:id/wallets", requestTimeout, accountsHandler.ListWallets(accounts.OwnerMerchant))
+	group.DELETE("/merchants/:id/wallets/:walletId", requestTimeout, accountsHandler.RemoveWallet(accounts.OwnerMerchant))
 }
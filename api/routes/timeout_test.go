@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	router := gin.New()
+	router.GET("/slow", Timeout(10*time.Millisecond), func(c *gin.Context) {
+		<-c.Request.Context().Done() // stands in for a cache, sql or provider call honoring ctx
+	})
+	router.GET("/fast", Timeout(time.Second), func(c *gin.Context) {
+		_, hasDeadline := c.Request.Context().Deadline()
+		c.JSON(http.StatusOK, gin.H{"deadline": hasDeadline})
+	})
+
+	t.Run("Deadline Exceeded", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/slow", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		assert.JSONEq(t, `{"error":"Request timed out"}`, w.Body.String())
+	})
+
+	t.Run("Within Deadline", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/fast", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"deadline":true}`, w.Body.String())
+	})
+}
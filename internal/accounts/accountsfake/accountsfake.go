@@ -0,0 +1,141 @@
+// Package accountsfake is an in-memory accounts.Repository for testing code that
+// issues invoices against real merchant and wallet records without a running Postgres.
+package accountsfake
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+)
+
+type Repository struct {
+	mu        sync.Mutex
+	users     map[string]accounts.User
+	merchants map[string]accounts.Merchant
+	wallets   map[string]accounts.Wallet
+}
+
+func New() *Repository {
+	return &Repository{
+		users:     make(map[string]accounts.User),
+		merchants: make(map[string]accounts.Merchant),
+		wallets:   make(map[string]accounts.Wallet),
+	}
+}
+
+func (f *Repository) SaveUser(ctx context.Context, user accounts.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users[user.ID] = user
+	return nil
+}
+
+func (f *Repository) FindUser(ctx context.Context, id string) (*accounts.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[id]
+	if !ok {
+		return nil, accounts.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (f *Repository) FindUserByAccount(ctx context.Context, accountHash string) (*accounts.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, user := range f.users {
+		if user.AccountHash == accountHash {
+			return &user, nil
+		}
+	}
+	return nil, accounts.ErrNotFound
+}
+
+func (f *Repository) SaveMerchant(ctx context.Context, merchant accounts.Merchant) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.merchants[merchant.ID] = merchant
+	return nil
+}
+
+func (f *Repository) FindMerchant(ctx context.Context, id string) (*accounts.Merchant, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	merchant, ok := f.merchants[id]
+	if !ok {
+		return nil, accounts.ErrNotFound
+	}
+	return &merchant, nil
+}
+
+// ordered by creation like the sql repository
+func (f *Repository) ListMerchants(ctx context.Context, accountRef string) ([]accounts.Merchant, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var merchants []accounts.Merchant
+	for _, merchant := range f.merchants {
+		if merchant.AccountRef == accountRef {
+			merchants = append(merchants, merchant)
+		}
+	}
+	sort.Slice(merchants, func(i, j int) bool { return merchants[i].CreatedAt.Before(merchants[j].CreatedAt) })
+	return merchants, nil
+}
+
+func (f *Repository) UpdateMerchant(ctx context.Context, merchant accounts.Merchant) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.merchants[merchant.ID]; !ok {
+		return accounts.ErrNotFound
+	}
+	f.merchants[merchant.ID] = merchant
+	return nil
+}
+
+func (f *Repository) DeleteMerchant(ctx context.Context, id string, walletOwnerRef string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.merchants[id]; !ok {
+		return accounts.ErrNotFound
+	}
+	delete(f.merchants, id)
+	for walletId, wallet := range f.wallets {
+		if wallet.OwnerRef == walletOwnerRef {
+			delete(f.wallets, walletId)
+		}
+	}
+	return nil
+}
+
+func (f *Repository) SaveWallet(ctx context.Context, wallet accounts.Wallet) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.wallets[wallet.ID] = wallet
+	return nil
+}
+
+func (f *Repository) ListWallets(ctx context.Context, ownerRef string) ([]accounts.Wallet, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var wallets []accounts.Wallet
+	for _, wallet := range f.wallets {
+		if wallet.OwnerRef == ownerRef {
+			wallets = append(wallets, wallet)
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].CreatedAt.Before(wallets[j].CreatedAt) })
+	return wallets, nil
+}
+
+func (f *Repository) DeleteWallet(ctx context.Context, ownerRef string, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	wallet, ok := f.wallets[id]
+	if !ok || wallet.OwnerRef != ownerRef {
+		return accounts.ErrNotFound
+	}
+	delete(f.wallets, id)
+	return nil
+}
@@ -0,0 +1,159 @@
+package accounts
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// account number of the user or merchant a request changes, or whose wallets it lists
+const AccountNumberHeader = "X-Account-Number"
+
+type AccountsHandler struct {
+	service AccountService
+}
+
+func NewAccountsHandler(service AccountService) *AccountsHandler {
+	return &AccountsHandler{service: service}
+}
+
+// handle POST /users, the account number is only ever returned here
+func (f *AccountsHandler) RegisterUser(c *gin.Context) {
+	registration, err := f.service.RegisterUser(c.Request.Context())
+	if err != nil {
+		writeAccountError(c, "RegisterUser", err)
+		return
+	}
+	c.JSON(http.StatusOK, registration)
+}
+
+func (f *AccountsHandler) GetUser(c *gin.Context) {
+	user, err := f.service.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeAccountError(c, "GetUser", err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (f *AccountsHandler) ListMerchants(c *gin.Context) {
+	merchants, err := f.service.ListMerchants(c.Request.Context(), c.Param("id"), c.GetHeader(AccountNumberHeader))
+	if err != nil {
+		writeAccountError(c, "ListMerchants", err)
+		return
+	}
+	if merchants == nil {
+		merchants = []Merchant{}
+	}
+	c.JSON(http.StatusOK, gin.H{"merchants": merchants})
+}
+
+func (f *AccountsHandler) CreateMerchant(c *gin.Context) {
+	var request MerchantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	merchant, err := f.service.CreateMerchant(c.Request.Context(), request)
+	if err != nil {
+		writeAccountError(c, "CreateMerchant", err)
+		return
+	}
+	c.JSON(http.StatusOK, merchant)
+}
+
+func (f *AccountsHandler) GetMerchant(c *gin.Context) {
+	merchant, err := f.service.GetMerchant(c.Request.Context(), c.Param("id"), c.GetHeader(AccountNumberHeader))
+	if err != nil {
+		writeAccountError(c, "GetMerchant", err)
+		return
+	}
+	c.JSON(http.StatusOK, merchant)
+}
+
+func (f *AccountsHandler) UpdateMerchant(c *gin.Context) {
+	var request MerchantUpdate
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	merchant, err := f.service.UpdateMerchant(c.Request.Context(), c.Param("id"), c.GetHeader(AccountNumberHeader), request)
+	if err != nil {
+		writeAccountError(c, "UpdateMerchant", err)
+		return
+	}
+	c.JSON(http.StatusOK, merchant)
+}
+
+func (f *AccountsHandler) DeleteMerchant(c *gin.Context) {
+	if err := f.service.DeleteMerchant(c.Request.Context(), c.Param("id"), c.GetHeader(AccountNumberHeader)); err != nil {
+		writeAccountError(c, "DeleteMerchant", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// wallet routes are the same for users and merchants, the owner comes from the :id param
+func (f *AccountsHandler) AttachWallet(ownerType OwnerType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request WalletRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+
+		wallet, err := f.service.AttachWallet(c.Request.Context(), ownerType, c.Param("id"), c.GetHeader(AccountNumberHeader), request)
+		if err != nil {
+			writeAccountError(c, "AttachWallet", err)
+			return
+		}
+		c.JSON(http.StatusOK, wallet)
+	}
+}
+
+func (f *AccountsHandler) ListWallets(ownerType OwnerType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wallets, err := f.service.ListWallets(c.Request.Context(), ownerType, c.Param("id"), c.GetHeader(AccountNumberHeader))
+		if err != nil {
+			writeAccountError(c, "ListWallets", err)
+			return
+		}
+		if wallets == nil {
+			wallets = []Wallet{}
+		}
+		c.JSON(http.StatusOK, gin.H{"wallets": wallets})
+	}
+}
+
+func (f *AccountsHandler) RemoveWallet(ownerType OwnerType) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := f.service.RemoveWallet(c.Request.Context(), ownerType, c.Param("id"), c.GetHeader(AccountNumberHeader), c.Param("walletId")); err != nil {
+			writeAccountError(c, "RemoveWallet", err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func writeAccountError(c *gin.Context, op string, err error) {
+	switch {
+	case c.Request.Context().Err() != nil: // route deadline hit or client went away mid request
+		log.Printf("%s abandoned: %v", op, err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+	case errors.Is(err, ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account number required in " + AccountNumberHeader})
+	case errors.Is(err, ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Internal Server Error when calling %s: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process account request"})
+	}
+}
@@ -0,0 +1,119 @@
+package accounts_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+)
+
+func setupAccountsRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	service, _ := newTestService(t)
+	handler := accounts.NewAccountsHandler(service)
+
+	router := gin.New()
+	router.POST("/users", handler.RegisterUser)
+	router.POST("/merchants", handler.CreateMerchant)
+	router.GET("/users/:id/merchants", handler.ListMerchants)
+	router.GET("/merchants/:id", handler.GetMerchant)
+	router.DELETE("/merchants/:id", handler.DeleteMerchant)
+	router.PATCH("/merchants/:id", handler.UpdateMerchant)
+	router.POST("/merchants/:id/wallets", handler.AttachWallet(accounts.OwnerMerchant))
+	router.GET("/merchants/:id/wallets", handler.ListWallets(accounts.OwnerMerchant))
+	return router
+}
+
+// accountNumber is sent in the account number header when set
+func serve(router *gin.Engine, method string, path string, body string, accountNumber ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for _, number := range accountNumber {
+		req.Header.Set(accounts.AccountNumberHeader, number)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAccountsHandler(t *testing.T) {
+	router := setupAccountsRouter(t)
+
+	w := serve(router, "POST", "/users", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var registration accounts.Registration
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &registration))
+	assert.NotEmpty(t, registration.AccountNumber)
+	assert.NotContains(t, w.Body.String(), "account_hash")
+
+	w = serve(router, "POST", "/merchants", `{"account_number": "`+registration.AccountNumber+`", "name": "Shop", "metadata": "tier=gold"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var merchant accounts.Merchant
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &merchant))
+	assert.Equal(t, "Shop", merchant.Name)
+
+	t.Run("Wallets", func(t *testing.T) {
+		w := serve(router, "GET", "/merchants/"+merchant.ID+"/wallets", "", registration.AccountNumber)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"wallets": []}`, w.Body.String())
+
+		w = serve(router, "POST", "/merchants/"+merchant.ID+"/wallets", `{"currency": "btc", "wallet_type": "ota"}`, registration.AccountNumber)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"currency":"BTC"`)
+
+		w = serve(router, "POST", "/merchants/"+merchant.ID+"/wallets", `{"currency": "btc", "wallet_type": "static"}`, registration.AccountNumber)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Not The Owner", func(t *testing.T) {
+		w := serve(router, "POST", "/users", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var other accounts.Registration
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &other))
+		static := `{"currency": "btc", "wallet_type": "static", "address": "bc1qattacker"}`
+
+		for _, accountNumber := range [][]string{nil, {other.AccountNumber}} {
+			expected := http.StatusUnauthorized
+			if accountNumber != nil {
+				expected = http.StatusForbidden
+			}
+			assert.Equal(t, expected, serve(router, "POST", "/merchants/"+merchant.ID+"/wallets", static, accountNumber...).Code)
+			assert.Equal(t, expected, serve(router, "GET", "/merchants/"+merchant.ID+"/wallets", "", accountNumber...).Code)
+			assert.Equal(t, expected, serve(router, "PATCH", "/merchants/"+merchant.ID, `{"name": "Hijacked"}`, accountNumber...).Code)
+			assert.Equal(t, expected, serve(router, "DELETE", "/merchants/"+merchant.ID, "", accountNumber...).Code)
+			assert.Equal(t, expected, serve(router, "GET", "/users/"+registration.ID+"/merchants", "", accountNumber...).Code)
+		}
+		w = serve(router, "GET", "/merchants/"+merchant.ID, "")
+		assert.Contains(t, w.Body.String(), `"name":"Shop"`)
+		assert.NotContains(t, w.Body.String(), "metadata")
+		assert.Equal(t, http.StatusForbidden, serve(router, "GET", "/merchants/"+merchant.ID, "", other.AccountNumber).Code)
+		w = serve(router, "GET", "/merchants/"+merchant.ID, "", registration.AccountNumber)
+		assert.Contains(t, w.Body.String(), `"metadata":"tier=gold"`)
+		w = serve(router, "GET", "/users/"+registration.ID+"/merchants", "", registration.AccountNumber)
+		assert.Contains(t, w.Body.String(), merchant.ID)
+	})
+
+	t.Run("Unknown Account", func(t *testing.T) {
+		w := serve(router, "POST", "/merchants", `{"account_number": "nope", "name": "Shop"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Malformed Body", func(t *testing.T) {
+		w := serve(router, "POST", "/merchants", `{`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		w := serve(router, "DELETE", "/merchants/"+merchant.ID, "", registration.AccountNumber)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = serve(router, "GET", "/merchants/"+merchant.ID, "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,90 @@
+package accounts
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid account request")
+	ErrNotFound       = errors.New("account record not found")
+	ErrUnauthorized   = errors.New("account number required")
+	ErrForbidden      = errors.New("account record belongs to another account")
+)
+
+type OwnerType string
+
+const (
+	OwnerUser     OwnerType = "user"
+	OwnerMerchant OwnerType = "merchant"
+)
+
+type WalletType string
+
+const (
+	WalletStatic WalletType = "static" // fixed receiving address
+	WalletHot    WalletType = "hot"    // online wallet used for payouts
+	WalletCold   WalletType = "cold"   // offline storage, never receives invoice payments directly
+	WalletOTA    WalletType = "ota"    // derives a one time address per invoice
+)
+
+func (t WalletType) Valid() bool {
+	switch t {
+	case WalletStatic, WalletHot, WalletCold, WalletOTA:
+		return true
+	}
+	return false
+}
+
+// wallets an invoice can be paid into, most private first
+var receivingOrder = []WalletType{WalletOTA, WalletStatic, WalletHot}
+
+// the plaintext account number only ever exists on the registration response
+type User struct {
+	ID          string     `json:"user_id"`
+	AccountHash string     `json:"-"` // account number under the account purpose key
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+type Merchant struct {
+	ID         string     `json:"merchant_id"`
+	AccountRef string     `json:"-"` // account hash under its merchant_owner key, links the merchant to its account
+	Name       string     `json:"name"`
+	Metadata   *string    `json:"metadata,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+type Wallet struct {
+	ID        string     `json:"wallet_id"`
+	OwnerRef  string     `json:"-"` // owner under its wallet_owner key
+	OwnerType OwnerType  `json:"owner_type"`
+	Currency  string     `json:"currency"`
+	Type      WalletType `json:"wallet_type"`
+	Address   *string    `json:"address,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type Registration struct {
+	User
+	AccountNumber string `json:"account_number"` // shown once, only its hash is stored
+}
+
+type MerchantRequest struct {
+	AccountNumber string  `json:"account_number"` // account the merchant is created under
+	Name          string  `json:"name"`
+	Metadata      *string `json:"metadata,omitempty"`
+}
+
+type MerchantUpdate struct {
+	Name     *string `json:"name,omitempty"`
+	Metadata *string `json:"metadata,omitempty"`
+}
+
+type WalletRequest struct {
+	Currency string     `json:"currency"`
+	Type     WalletType `json:"wallet_type"`
+	Address  *string    `json:"address,omitempty"` // required for every type but ota
+}
@@ -0,0 +1,215 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/undersleep7x/cryo-project/internal/crypto/envelope"
+	platformPostgres "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
+)
+
+// persistence for users, merchants and wallets, only hashed references are ever stored and
+// merchant metadata and wallet addresses are encrypted at rest
+type Repository interface {
+	SaveUser(ctx context.Context, user User) error
+	FindUser(ctx context.Context, id string) (*User, error)
+	FindUserByAccount(ctx context.Context, accountHash string) (*User, error)
+
+	SaveMerchant(ctx context.Context, merchant Merchant) error
+	FindMerchant(ctx context.Context, id string) (*Merchant, error)
+	ListMerchants(ctx context.Context, accountRef string) ([]Merchant, error)
+	UpdateMerchant(ctx context.Context, merchant Merchant) error
+	// removes the merchant along with every wallet held under walletOwnerRef
+	DeleteMerchant(ctx context.Context, id string, walletOwnerRef string) error
+
+	SaveWallet(ctx context.Context, wallet Wallet) error
+	ListWallets(ctx context.Context, ownerRef string) ([]Wallet, error)
+	DeleteWallet(ctx context.Context, ownerRef string, id string) error
+}
+
+type repository struct {
+	db       platformPostgres.Querier
+	metadata envelope.Column
+	address  envelope.Column
+}
+
+// db is the client, or a transaction when the writes are part of a larger unit of work.
+// legacyPlaintext reads values written before encryption as plaintext, see envelope.Column
+func NewRepository(db platformPostgres.Querier, sealer envelope.Envelope, legacyPlaintext bool) Repository {
+	return &repository{
+		db:       db,
+		metadata: envelope.NewColumn(sealer, "merchants", "metadata").WithLegacyPlaintext(legacyPlaintext),
+		address:  envelope.NewColumn(sealer, "wallets", "address").WithLegacyPlaintext(legacyPlaintext),
+	}
+}
+
+func (r *repository) SaveUser(ctx context.Context, user User) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO users (id, account_number, created_at)
+		VALUES ($1, $2, $3)`,
+		user.ID, user.AccountHash, user.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) FindUser(ctx context.Context, id string) (*User, error) {
+	return r.findUser(ctx, "id", id)
+}
+
+func (r *repository) FindUserByAccount(ctx context.Context, accountHash string) (*User, error) {
+	return r.findUser(ctx, "account_number", accountHash)
+}
+
+func (r *repository) findUser(ctx context.Context, column string, value string) (*User, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, account_number, created_at, updated_at
+		FROM users
+		WHERE `+column+` = $1`, value)
+
+	var u User
+	err := row.Scan(&u.ID, &u.AccountHash, &u.CreatedAt, &u.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+	return &u, nil
+}
+
+func (r *repository) SaveMerchant(ctx context.Context, merchant Merchant) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO merchants (id, account_ref, merchant_name, metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		merchant.ID, merchant.AccountRef, merchant.Name, r.metadata.Value(ctx, merchant.ID, merchant.Metadata), merchant.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert merchant: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) FindMerchant(ctx context.Context, id string) (*Merchant, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, account_ref, merchant_name, metadata, created_at, updated_at
+		FROM merchants
+		WHERE id = $1`, id)
+
+	var m Merchant
+	err := row.Scan(&m.ID, &m.AccountRef, &m.Name, r.metadata.Scan(ctx, &m.ID, &m.Metadata), &m.CreatedAt, &m.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchant: %w", err)
+	}
+	return &m, nil
+}
+
+func (r *repository) ListMerchants(ctx context.Context, accountRef string) ([]Merchant, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, account_ref, merchant_name, metadata, created_at, updated_at
+		FROM merchants
+		WHERE account_ref = $1
+		ORDER BY created_at`, accountRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merchants: %w", err)
+	}
+	defer rows.Close()
+
+	var merchants []Merchant
+	for rows.Next() {
+		var m Merchant
+		if err := rows.Scan(&m.ID, &m.AccountRef, &m.Name, r.metadata.Scan(ctx, &m.ID, &m.Metadata), &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan merchant: %w", err)
+		}
+		merchants = append(merchants, m)
+	}
+	return merchants, rows.Err()
+}
+
+func (r *repository) UpdateMerchant(ctx context.Context, merchant Merchant) error {
+	updatedAt := time.Now().UTC()
+	if merchant.UpdatedAt != nil {
+		updatedAt = merchant.UpdatedAt.UTC()
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE merchants
+		SET merchant_name = $2, metadata = $3, updated_at = $4
+		WHERE id = $1`,
+		merchant.ID, merchant.Name, r.metadata.Value(ctx, merchant.ID, merchant.Metadata), updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update merchant: %w", err)
+	}
+	return requireAffected(result)
+}
+
+func (r *repository) DeleteMerchant(ctx context.Context, id string, walletOwnerRef string) error {
+	return r.db.WithTx(ctx, func(tx platformPostgres.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM wallets WHERE owner_ref = $1`, walletOwnerRef); err != nil {
+			return fmt.Errorf("failed to delete merchant wallets: %w", err)
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM merchants WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete merchant: %w", err)
+		}
+		return requireAffected(result)
+	})
+}
+
+func (r *repository) SaveWallet(ctx context.Context, wallet Wallet) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO wallets (id, owner_ref, owner_type, currency, wallet_type, address, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		wallet.ID, wallet.OwnerRef, wallet.OwnerType, wallet.Currency, wallet.Type, r.address.Value(ctx, wallet.ID, wallet.Address), wallet.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert wallet: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) ListWallets(ctx context.Context, ownerRef string) ([]Wallet, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, owner_ref, owner_type, currency, wallet_type, address, created_at, updated_at
+		FROM wallets
+		WHERE owner_ref = $1
+		ORDER BY created_at`, ownerRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallets: %w", err)
+	}
+	defer rows.Close()
+
+	var wallets []Wallet
+	for rows.Next() {
+		var w Wallet
+		if err := rows.Scan(&w.ID, &w.OwnerRef, &w.OwnerType, &w.Currency, &w.Type, r.address.Scan(ctx, &w.ID, &w.Address), &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		wallets = append(wallets, w)
+	}
+	return wallets, rows.Err()
+}
+
+func (r *repository) DeleteWallet(ctx context.Context, ownerRef string, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM wallets WHERE id = $1 AND owner_ref = $2`, id, ownerRef)
+	if err != nil {
+		return fmt.Errorf("failed to delete wallet: %w", err)
+	}
+	return requireAffected(result)
+}
+
+// updates and deletes that touch nothing mean the record doesn't exist
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package accounts
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"github.com/undersleep7x/cryo-project/internal/crypto/keys"
+	utils "github.com/undersleep7x/cryo-project/internal/utils"
+)
+
+type Config struct {
+	Keys keys.Deriver // purpose scoped keys for account hashes and owner references
+}
+
+// users register accounts, create merchants under them and attach wallets to either
+type AccountService interface {
+	RegisterUser(ctx context.Context) (*Registration, error)
+	GetUser(ctx context.Context, id string) (*User, error)
+
+	CreateMerchant(ctx context.Context, r MerchantRequest) (*Merchant, error)
+	// every payer knows a merchant's id, so metadata is left out unless the owning account
+	// number is presented. ErrForbidden for another account's number
+	GetMerchant(ctx context.Context, id string, accountNumber string) (*Merchant, error)
+	// merchants under the user's account, found by recomputing its account_ref. the link is
+	// only shown to the holder of the account number
+	ListMerchants(ctx context.Context, userId string, accountNumber string) ([]Merchant, error)
+	// changes to a merchant or its wallets, and listing wallets, need the number of the account
+	// the owner belongs to. ErrUnauthorized without one, ErrForbidden when it's another account's
+	UpdateMerchant(ctx context.Context, id string, accountNumber string, r MerchantUpdate) (*Merchant, error)
+	DeleteMerchant(ctx context.Context, id string, accountNumber string) error
+
+	AttachWallet(ctx context.Context, ownerType OwnerType, ownerId string, accountNumber string, r WalletRequest) (*Wallet, error)
+	ListWallets(ctx context.Context, ownerType OwnerType, ownerId string, accountNumber string) ([]Wallet, error)
+	RemoveWallet(ctx context.Context, ownerType OwnerType, ownerId string, accountNumber string, walletId string) error
+	// the wallet a merchant's invoices in currency are paid into, looked up on the payer's behalf
+	ReceivingWallet(ctx context.Context, merchantId string, currency string) (*Wallet, error)
+}
+
+type accountServiceImpl struct {
+	r      Repository
+	assets assets.Registry
+	config Config
+}
+
+func NewAccountService(repository Repository, registry assets.Registry, cfg Config) AccountService {
+	return &accountServiceImpl{r: repository, assets: registry, config: cfg}
+}
+
+// random account number, 128 bits so it can't be guessed from its hash
+var newAccountNumber = func() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw), nil
+}
+
+func (s *accountServiceImpl) RegisterUser(ctx context.Context) (*Registration, error) {
+	accountNumber, err := newAccountNumber()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate account number: %w", err)
+	}
+	accountHash, err := s.config.Keys.Ref(ctx, keys.PurposeAccount, "", accountNumber, utils.TruncateNone)
+	if err != nil {
+		return nil, err
+	}
+
+	user := User{ID: uuid.NewString(), AccountHash: accountHash, CreatedAt: time.Now().UTC()}
+	if err := s.r.SaveUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return &Registration{User: user, AccountNumber: accountNumber}, nil
+}
+
+func (s *accountServiceImpl) GetUser(ctx context.Context, id string) (*User, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	return s.r.FindUser(ctx, id)
+}
+
+func (s *accountServiceImpl) CreateMerchant(ctx context.Context, r MerchantRequest) (*Merchant, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name must be set", ErrInvalidRequest)
+	}
+	if r.AccountNumber == "" {
+		return nil, fmt.Errorf("%w: account_number must be set", ErrInvalidRequest)
+	}
+	accountHash, err := s.config.Keys.Ref(ctx, keys.PurposeAccount, "", r.AccountNumber, utils.TruncateNone)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.r.FindUserByAccount(ctx, accountHash); err != nil {
+		return nil, err
+	}
+	accountRef, err := s.accountRef(ctx, accountHash)
+	if err != nil {
+		return nil, err
+	}
+
+	merchant := Merchant{ID: uuid.NewString(), AccountRef: accountRef, Name: name, Metadata: r.Metadata, CreatedAt: time.Now().UTC()}
+	if err := s.r.SaveMerchant(ctx, merchant); err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+func (s *accountServiceImpl) GetMerchant(ctx context.Context, id string, accountNumber string) (*Merchant, error) {
+	if accountNumber != "" {
+		return s.ownedMerchant(ctx, id, accountNumber)
+	}
+	merchant, err := s.findMerchant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	merchant.Metadata = nil
+	return merchant, nil
+}
+
+func (s *accountServiceImpl) ListMerchants(ctx context.Context, userId string, accountNumber string) ([]Merchant, error) {
+	user, err := s.ownedUser(ctx, userId, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	accountRef, err := s.accountRef(ctx, user.AccountHash)
+	if err != nil {
+		return nil, err
+	}
+	return s.r.ListMerchants(ctx, accountRef)
+}
+
+func (s *accountServiceImpl) UpdateMerchant(ctx context.Context, id string, accountNumber string, r MerchantUpdate) (*Merchant, error) {
+	merchant, err := s.ownedMerchant(ctx, id, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	if r.Name != nil {
+		name := strings.TrimSpace(*r.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name must not be empty", ErrInvalidRequest)
+		}
+		merchant.Name = name
+	}
+	if r.Metadata != nil {
+		merchant.Metadata = r.Metadata
+	}
+	updatedAt := time.Now().UTC()
+	merchant.UpdatedAt = &updatedAt
+
+	if err := s.r.UpdateMerchant(ctx, *merchant); err != nil {
+		return nil, err
+	}
+	return merchant, nil
+}
+
+func (s *accountServiceImpl) DeleteMerchant(ctx context.Context, id string, accountNumber string) error {
+	ownerRef, err := s.ownerRef(ctx, OwnerMerchant, id, accountNumber)
+	if err != nil {
+		return err
+	}
+	return s.r.DeleteMerchant(ctx, id, ownerRef)
+}
+
+func (s *accountServiceImpl) AttachWallet(ctx context.Context, ownerType OwnerType, ownerId string, accountNumber string, r WalletRequest) (*Wallet, error) {
+	asset, ok := s.assets.Lookup(r.Currency)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported currency %q", ErrInvalidRequest, r.Currency)
+	}
+	if !r.Type.Valid() {
+		return nil, fmt.Errorf("%w: wallet_type must be one of static, hot, cold or ota", ErrInvalidRequest)
+	}
+	// ota wallets derive an address per invoice, every other type receives at a fixed one
+	hasAddress := r.Address != nil && strings.TrimSpace(*r.Address) != ""
+	if r.Type == WalletOTA && hasAddress {
+		return nil, fmt.Errorf("%w: ota wallets must not set an address", ErrInvalidRequest)
+	}
+	if r.Type != WalletOTA && !hasAddress {
+		return nil, fmt.Errorf("%w: %s wallets need an address", ErrInvalidRequest, r.Type)
+	}
+	ownerRef, err := s.ownerRef(ctx, ownerType, ownerId, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet := Wallet{
+		ID:        uuid.NewString(),
+		OwnerRef:  ownerRef,
+		OwnerType: ownerType,
+		Currency:  asset.Ticker,
+		Type:      r.Type,
+		Address:   r.Address,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.r.SaveWallet(ctx, wallet); err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (s *accountServiceImpl) ListWallets(ctx context.Context, ownerType OwnerType, ownerId string, accountNumber string) ([]Wallet, error) {
+	ownerRef, err := s.ownerRef(ctx, ownerType, ownerId, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	return s.r.ListWallets(ctx, ownerRef)
+}
+
+func (s *accountServiceImpl) RemoveWallet(ctx context.Context, ownerType OwnerType, ownerId string, accountNumber string, walletId string) error {
+	if !validID(walletId) {
+		return ErrNotFound
+	}
+	ownerRef, err := s.ownerRef(ctx, ownerType, ownerId, accountNumber)
+	if err != nil {
+		return err
+	}
+	return s.r.DeleteWallet(ctx, ownerRef, walletId)
+}
+
+// picks by receivingOrder, ErrNotFound when the merchant holds no wallet that can receive currency
+func (s *accountServiceImpl) ReceivingWallet(ctx context.Context, merchantId string, currency string) (*Wallet, error) {
+	asset, ok := s.assets.Lookup(currency)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported currency %q", ErrInvalidRequest, currency)
+	}
+	merchant, err := s.findMerchant(ctx, merchantId)
+	if err != nil {
+		return nil, err
+	}
+	ownerRef, err := s.walletOwnerRef(ctx, merchant.ID)
+	if err != nil {
+		return nil, err
+	}
+	wallets, err := s.r.ListWallets(ctx, ownerRef)
+	if err != nil {
+		return nil, err
+	}
+	for _, walletType := range receivingOrder {
+		for _, wallet := range wallets {
+			if wallet.Currency == asset.Ticker && wallet.Type == walletType {
+				return &wallet, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: no %s wallet can receive payments", ErrNotFound, asset.Ticker)
+}
+
+// ties merchants to the account they were created under
+func (s *accountServiceImpl) accountRef(ctx context.Context, accountHash string) (string, error) {
+	return s.config.Keys.Ref(ctx, keys.PurposeMerchantOwner, accountHash, accountHash, utils.TruncateNone)
+}
+
+// hash of the account number a caller presented, the same hash users are stored under
+func (s *accountServiceImpl) accountHash(ctx context.Context, accountNumber string) (string, error) {
+	if accountNumber == "" {
+		return "", ErrUnauthorized
+	}
+	return s.config.Keys.Ref(ctx, keys.PurposeAccount, "", accountNumber, utils.TruncateNone)
+}
+
+// the merchant, provided it was created under the presented account
+func (s *accountServiceImpl) ownedMerchant(ctx context.Context, id string, accountNumber string) (*Merchant, error) {
+	merchant, err := s.findMerchant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	accountHash, err := s.accountHash(ctx, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	accountRef, err := s.accountRef(ctx, accountHash)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(accountRef), []byte(merchant.AccountRef)) != 1 {
+		return nil, ErrForbidden
+	}
+	return merchant, nil
+}
+
+func (s *accountServiceImpl) findMerchant(ctx context.Context, id string) (*Merchant, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	return s.r.FindMerchant(ctx, id)
+}
+
+// the user, provided the presented account number is theirs
+func (s *accountServiceImpl) ownedUser(ctx context.Context, id string, accountNumber string) (*User, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	accountHash, err := s.accountHash(ctx, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(accountHash), []byte(user.AccountHash)) != 1 {
+		return nil, ErrForbidden
+	}
+	return user, nil
+}
+
+// wallet_owner ref of an owner under the presented account, users are keyed by their account
+// hash and merchants by id
+func (s *accountServiceImpl) ownerRef(ctx context.Context, ownerType OwnerType, ownerId string, accountNumber string) (string, error) {
+	var owner string
+	switch ownerType {
+	case OwnerUser:
+		user, err := s.ownedUser(ctx, ownerId, accountNumber)
+		if err != nil {
+			return "", err
+		}
+		owner = user.AccountHash
+	case OwnerMerchant:
+		merchant, err := s.ownedMerchant(ctx, ownerId, accountNumber)
+		if err != nil {
+			return "", err
+		}
+		owner = merchant.ID
+	default:
+		return "", fmt.Errorf("%w: unknown owner type %q", ErrInvalidRequest, ownerType)
+	}
+	return s.walletOwnerRef(ctx, owner)
+}
+
+func (s *accountServiceImpl) walletOwnerRef(ctx context.Context, owner string) (string, error) {
+	return s.config.Keys.Ref(ctx, keys.PurposeWalletOwner, owner, owner, utils.TruncateNone)
+}
+
+// ids are uuids, anything else can't exist and would only fail the cast in postgres
+func validID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
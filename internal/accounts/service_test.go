@@ -0,0 +1,269 @@
+package accounts_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+	"github.com/undersleep7x/cryo-project/internal/accounts/accountsfake"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"github.com/undersleep7x/cryo-project/internal/crypto/keys"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+)
+
+func newTestService(t *testing.T) (accounts.AccountService, *accountsfake.Repository) {
+	registry, err := assets.NewRegistry(assets.DefaultAssets)
+	require.NoError(t, err)
+	repo := accountsfake.New()
+	return accounts.NewAccountService(repo, registry, accounts.Config{Keys: keys.NewDeriver(secrets.Static("test-key"))}), repo
+}
+
+// merchant under a fresh account, and the account number that owns it
+func newTestMerchant(t *testing.T, service accounts.AccountService) (*accounts.Merchant, string) {
+	registration, err := service.RegisterUser(context.Background())
+	require.NoError(t, err)
+	merchant, err := service.CreateMerchant(context.Background(), accounts.MerchantRequest{AccountNumber: registration.AccountNumber, Name: "Shop"})
+	require.NoError(t, err)
+	return merchant, registration.AccountNumber
+}
+
+func TestRegisterUser(t *testing.T) {
+	service, repo := newTestService(t)
+	ctx := context.Background()
+
+	registration, err := service.RegisterUser(ctx)
+	require.NoError(t, err)
+	assert.Len(t, registration.AccountNumber, 26)
+
+	stored, err := repo.FindUser(ctx, registration.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, registration.AccountNumber, stored.AccountHash) // only the hash is persisted
+	assert.Len(t, stored.AccountHash, 64)
+
+	t.Run("Unknown User", func(t *testing.T) {
+		_, err := service.GetUser(ctx, "not-a-uuid")
+		assert.ErrorIs(t, err, accounts.ErrNotFound)
+	})
+}
+
+func TestMerchants(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := context.Background()
+
+	t.Run("Unknown Account", func(t *testing.T) {
+		_, err := service.CreateMerchant(ctx, accounts.MerchantRequest{AccountNumber: "nope", Name: "Shop"})
+		assert.ErrorIs(t, err, accounts.ErrNotFound)
+	})
+
+	t.Run("Missing Name", func(t *testing.T) {
+		registration, err := service.RegisterUser(ctx)
+		require.NoError(t, err)
+		_, err = service.CreateMerchant(ctx, accounts.MerchantRequest{AccountNumber: registration.AccountNumber, Name: "  "})
+		assert.ErrorIs(t, err, accounts.ErrInvalidRequest)
+	})
+
+	t.Run("Same Account Same Ref", func(t *testing.T) {
+		registration, err := service.RegisterUser(ctx)
+		require.NoError(t, err)
+		first, err := service.CreateMerchant(ctx, accounts.MerchantRequest{AccountNumber: registration.AccountNumber, Name: "One"})
+		require.NoError(t, err)
+		second, err := service.CreateMerchant(ctx, accounts.MerchantRequest{AccountNumber: registration.AccountNumber, Name: "Two"})
+		require.NoError(t, err)
+
+		assert.NotEqual(t, first.ID, second.ID)
+		assert.Equal(t, first.AccountRef, second.AccountRef)
+
+		// found again from the user alone, no account number or plaintext link is stored
+		merchants, err := service.ListMerchants(ctx, registration.ID, registration.AccountNumber)
+		require.NoError(t, err)
+		require.Len(t, merchants, 2)
+		assert.Equal(t, first.ID, merchants[0].ID)
+		assert.Equal(t, second.ID, merchants[1].ID)
+
+		// the user -> merchant link isn't public
+		_, err = service.ListMerchants(ctx, registration.ID, "")
+		assert.ErrorIs(t, err, accounts.ErrUnauthorized)
+		other, err := service.RegisterUser(ctx)
+		require.NoError(t, err)
+		_, err = service.ListMerchants(ctx, registration.ID, other.AccountNumber)
+		assert.ErrorIs(t, err, accounts.ErrForbidden)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		merchant, accountNumber := newTestMerchant(t, service)
+		name, metadata := "Renamed", "tier=gold"
+
+		updated, err := service.UpdateMerchant(ctx, merchant.ID, accountNumber, accounts.MerchantUpdate{Name: &name, Metadata: &metadata})
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", updated.Name)
+		assert.Equal(t, "tier=gold", *updated.Metadata)
+		assert.NotNil(t, updated.UpdatedAt)
+
+		fetched, err := service.GetMerchant(ctx, merchant.ID, accountNumber)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", fetched.Name)
+		assert.Equal(t, "tier=gold", *fetched.Metadata)
+	})
+
+	t.Run("Metadata Only For The Owner", func(t *testing.T) {
+		registration, err := service.RegisterUser(ctx)
+		require.NoError(t, err)
+		metadata := "tier=gold"
+		merchant, err := service.CreateMerchant(ctx, accounts.MerchantRequest{AccountNumber: registration.AccountNumber, Name: "Shop", Metadata: &metadata})
+		require.NoError(t, err)
+
+		owned, err := service.GetMerchant(ctx, merchant.ID, registration.AccountNumber)
+		require.NoError(t, err)
+		assert.Equal(t, "tier=gold", *owned.Metadata)
+
+		// what a payer who only knows the recipient id sees
+		public, err := service.GetMerchant(ctx, merchant.ID, "")
+		require.NoError(t, err)
+		assert.Equal(t, "Shop", public.Name)
+		assert.Nil(t, public.Metadata)
+
+		other, err := service.RegisterUser(ctx)
+		require.NoError(t, err)
+		_, err = service.GetMerchant(ctx, merchant.ID, other.AccountNumber)
+		assert.ErrorIs(t, err, accounts.ErrForbidden)
+	})
+
+	t.Run("Delete Takes Wallets", func(t *testing.T) {
+		merchant, accountNumber := newTestMerchant(t, service)
+		_, err := service.AttachWallet(ctx, accounts.OwnerMerchant, merchant.ID, accountNumber, accounts.WalletRequest{Currency: "btc", Type: accounts.WalletOTA})
+		require.NoError(t, err)
+
+		require.NoError(t, service.DeleteMerchant(ctx, merchant.ID, accountNumber))
+		_, err = service.GetMerchant(ctx, merchant.ID, "")
+		assert.ErrorIs(t, err, accounts.ErrNotFound)
+		assert.ErrorIs(t, service.DeleteMerchant(ctx, merchant.ID, accountNumber), accounts.ErrNotFound)
+	})
+}
+
+func TestWallets(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := context.Background()
+	address := "bc1qaddress"
+
+	t.Run("Validation", func(t *testing.T) {
+		merchant, accountNumber := newTestMerchant(t, service)
+		tests := map[string]accounts.WalletRequest{
+			"Unsupported Currency": {Currency: "notacoin", Type: accounts.WalletOTA},
+			"Unknown Type":         {Currency: "btc", Type: "paper"},
+			"Static Needs Address": {Currency: "btc", Type: accounts.WalletStatic},
+			"OTA Has No Address":   {Currency: "btc", Type: accounts.WalletOTA, Address: &address},
+		}
+		for name, request := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := service.AttachWallet(ctx, accounts.OwnerMerchant, merchant.ID, accountNumber, request)
+				assert.ErrorIs(t, err, accounts.ErrInvalidRequest)
+			})
+		}
+	})
+
+	t.Run("Owners Are Isolated", func(t *testing.T) {
+		merchant, merchantAccount := newTestMerchant(t, service)
+		registration, err := service.RegisterUser(ctx)
+		require.NoError(t, err)
+
+		wallet, err := service.AttachWallet(ctx, accounts.OwnerUser, registration.ID, registration.AccountNumber, accounts.WalletRequest{Currency: "bitcoin", Type: accounts.WalletHot, Address: &address})
+		require.NoError(t, err)
+		assert.Equal(t, "BTC", wallet.Currency)
+
+		userWallets, err := service.ListWallets(ctx, accounts.OwnerUser, registration.ID, registration.AccountNumber)
+		require.NoError(t, err)
+		assert.Len(t, userWallets, 1)
+		merchantWallets, err := service.ListWallets(ctx, accounts.OwnerMerchant, merchant.ID, merchantAccount)
+		require.NoError(t, err)
+		assert.Empty(t, merchantWallets)
+
+		assert.ErrorIs(t, service.RemoveWallet(ctx, accounts.OwnerMerchant, merchant.ID, merchantAccount, wallet.ID), accounts.ErrNotFound)
+		assert.NoError(t, service.RemoveWallet(ctx, accounts.OwnerUser, registration.ID, registration.AccountNumber, wallet.ID))
+	})
+
+	t.Run("Receiving Wallet Order", func(t *testing.T) {
+		merchant, accountNumber := newTestMerchant(t, service)
+		attach := func(walletType accounts.WalletType, address *string) *accounts.Wallet {
+			wallet, err := service.AttachWallet(ctx, accounts.OwnerMerchant, merchant.ID, accountNumber, accounts.WalletRequest{Currency: "btc", Type: walletType, Address: address})
+			require.NoError(t, err)
+			return wallet
+		}
+
+		_, err := service.ReceivingWallet(ctx, merchant.ID, "btc")
+		assert.ErrorIs(t, err, accounts.ErrNotFound)
+
+		attach(accounts.WalletCold, &address)
+		_, err = service.ReceivingWallet(ctx, merchant.ID, "btc")
+		assert.ErrorIs(t, err, accounts.ErrNotFound) // cold storage never receives
+
+		hot := attach(accounts.WalletHot, &address)
+		wallet, err := service.ReceivingWallet(ctx, merchant.ID, "btc")
+		require.NoError(t, err)
+		assert.Equal(t, hot.ID, wallet.ID)
+
+		ota := attach(accounts.WalletOTA, nil)
+		wallet, err = service.ReceivingWallet(ctx, merchant.ID, "BTC")
+		require.NoError(t, err)
+		assert.Equal(t, ota.ID, wallet.ID)
+	})
+}
+
+func TestOwnership(t *testing.T) {
+	service, _ := newTestService(t)
+	ctx := context.Background()
+	address := "bc1qattacker"
+	merchant, accountNumber := newTestMerchant(t, service)
+	wallet, err := service.AttachWallet(ctx, accounts.OwnerMerchant, merchant.ID, accountNumber, accounts.WalletRequest{Currency: "btc", Type: accounts.WalletOTA})
+	require.NoError(t, err)
+	user, err := service.RegisterUser(ctx)
+	require.NoError(t, err)
+	other, err := service.RegisterUser(ctx)
+	require.NoError(t, err)
+	name := "Hijacked"
+
+	// every change, and listing wallets, is refused without the owning account number
+	calls := map[string]func(accountNumber string) error{
+		"Update Merchant": func(accountNumber string) error {
+			_, err := service.UpdateMerchant(ctx, merchant.ID, accountNumber, accounts.MerchantUpdate{Name: &name})
+			return err
+		},
+		"Delete Merchant": func(accountNumber string) error {
+			return service.DeleteMerchant(ctx, merchant.ID, accountNumber)
+		},
+		"Attach Merchant Wallet": func(accountNumber string) error {
+			_, err := service.AttachWallet(ctx, accounts.OwnerMerchant, merchant.ID, accountNumber, accounts.WalletRequest{Currency: "btc", Type: accounts.WalletStatic, Address: &address})
+			return err
+		},
+		"List Merchant Wallets": func(accountNumber string) error {
+			_, err := service.ListWallets(ctx, accounts.OwnerMerchant, merchant.ID, accountNumber)
+			return err
+		},
+		"Remove Merchant Wallet": func(accountNumber string) error {
+			return service.RemoveWallet(ctx, accounts.OwnerMerchant, merchant.ID, accountNumber, wallet.ID)
+		},
+		"Attach User Wallet": func(accountNumber string) error {
+			_, err := service.AttachWallet(ctx, accounts.OwnerUser, user.ID, accountNumber, accounts.WalletRequest{Currency: "btc", Type: accounts.WalletStatic, Address: &address})
+			return err
+		},
+		"List User Wallets": func(accountNumber string) error {
+			_, err := service.ListWallets(ctx, accounts.OwnerUser, user.ID, accountNumber)
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, call(""), accounts.ErrUnauthorized)
+			assert.ErrorIs(t, call(other.AccountNumber), accounts.ErrForbidden)
+		})
+	}
+
+	// nothing was changed, invoices are still paid into the owner's wallet
+	fetched, err := service.GetMerchant(ctx, merchant.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Shop", fetched.Name)
+	receiving, err := service.ReceivingWallet(ctx, merchant.ID, "btc")
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID, receiving.ID)
+}
@@ -2,73 +2,307 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	redis "github.com/redis/go-redis/v9"
+	"github.com/undersleep7x/cryo-project/api/grpcserver"
 	"github.com/undersleep7x/cryo-project/api/routes"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+	"github.com/undersleep7x/cryo-project/internal/assets"
 	"github.com/undersleep7x/cryo-project/internal/config"
+	"github.com/undersleep7x/cryo-project/internal/crypto/envelope"
+	"github.com/undersleep7x/cryo-project/internal/crypto/keys"
+	"github.com/undersleep7x/cryo-project/internal/crypto/rotation"
 	cacheInfra "github.com/undersleep7x/cryo-project/internal/infra/cache"
 	postgresInfra "github.com/undersleep7x/cryo-project/internal/infra/postgres"
+	redisInfra "github.com/undersleep7x/cryo-project/internal/infra/redis"
+	"github.com/undersleep7x/cryo-project/internal/locks"
+	"github.com/undersleep7x/cryo-project/internal/metrics"
 	platformPostgres "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
 	platformRedis "github.com/undersleep7x/cryo-project/internal/platform/redisstore"
 	"github.com/undersleep7x/cryo-project/internal/prices"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+	"github.com/undersleep7x/cryo-project/internal/tracing"
 	"github.com/undersleep7x/cryo-project/internal/transactions"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"google.golang.org/grpc"
 )
 
 type App struct {
-	Config     *config.AppConfig
-	RedisCache platformRedis.RedisClient
-	PostgresDB platformPostgres.PostgresClient
-	Router     *gin.Engine
+	Config          *config.AppConfig // config as loaded at startup, Reloader holds the live copy
+	Reloader        config.Reloader
+	RedisCache      platformRedis.RedisClient
+	PostgresDB      platformPostgres.PostgresClient
+	Router          *gin.Engine
+	GRPCServer      *grpc.Server // nil when grpc is disabled
+	Prewarmer       *prices.Prewarmer
+	Secrets         secrets.Store
+	Leader          locks.Elector // singleton jobs only run on the elected replica
+	Rotator         rotation.Rotator
+	shutdownTracing tracing.Shutdown
 }
 
-// load configuration file for implementation
-func loadAppConfig() *App {
-	cfg := config.LoadConfig()
+// secrets resolved through the configured provider, each falling back to its config value
+type appSecrets struct {
+	dbPassword       secrets.Secret
+	redisPassword    secrets.Secret
+	sentinelPassword secrets.Secret
+	referenceKey     secrets.Secret
+	grpcAuthToken    secrets.Secret
+}
 
+// wire every subsystem from the validated configuration
+func loadAppConfig(reloader config.Reloader) *App {
+	cfg := reloader.Current()
 	log.Println("Initializing logging...")
 	setupLogging(cfg)
 
+	log.Println("Initializing tracing...")
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	log.Println("Resolving secrets...")
+	secretStore, appSecrets := setupSecrets(cfg)
+
+	log.Println("Loading field encryption keys...")
+	keyManager, sealer := setupEnvelope(cfg)
+
 	log.Println("Initializing Postgres DB...")
-	postgresClient := setupPgDatabase(cfg)
+	postgresClient := setupPgDatabase(cfg, appSecrets.dbPassword)
+	checkSchema(cfg, postgresClient)
 
 	log.Println("Loading Redis cache...")
-	redisClient := setupRedisCache(cfg)
+	redisClient := setupRedisCache(cfg, appSecrets.redisPassword, appSecrets.sentinelPassword)
+
+	log.Println("Setting up leader election...")
+	leader := setupLeaderElection(cfg, redisClient, postgresClient)
+
+	rotator := rotation.NewRotator(rotation.NewRepository(postgresClient), sealer, keyManager, loadRotationConfig(cfg.Security))
+
+	log.Println("Loading asset registry...")
+	assetRegistry := setupAssetRegistry(cfg, postgresClient)
 
 	log.Println("Wiring interfaces and router...")
 	router := gin.Default()
-	priceCache := cacheInfra.NewPriceCache(redisClient)
-	priceConfig := prices.Config{
-		BaseURL:       "https://api.coingecko.com/api/v3",
-		Timeout:       5,
-		RetryAttempts: 3,
-	}
-	priceService := prices.NewFetchCryptoPriceService(priceCache, priceConfig)
-	priceHandler := prices.NewPriceHandler(priceService)
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName)) // continues inbound traceparent, root span per request
+	router.Use(metrics.Middleware())
+	metrics.RegisterDBStats(postgresClient.GetDB(), cfg.DB.Name)
+	priceCache, priceBroker := setupPriceCache(cfg, redisClient)
+	priceSettings := prices.NewSettings(loadPriceConfig(cfg.Prices))
+	priceService := prices.NewFetchCryptoPriceService(priceCache, priceSettings)
+	priceHandler := prices.NewPriceHandler(priceService, assetRegistry)
+	priceHistoryRepository := prices.NewPriceHistoryRepository(postgresClient)
+	priceHistoryService := prices.NewPriceHistoryService(priceHistoryRepository, priceSettings)
+	priceHistoryHandler := prices.NewPriceHistoryHandler(priceHistoryService, assetRegistry)
+	priceStreamer := prices.NewPriceStreamer(priceService, priceBroker, priceSettings)
+	priceStreamHandler := prices.NewPriceStreamHandler(priceStreamer, assetRegistry)
+	prewarmer := prices.NewPrewarmer(priceCache, priceSettings, loadPrewarmConfig(cfg, assetRegistry))
+	prewarmHandler := prices.NewPrewarmHandler(prewarmer)
+
+	// price ttls, provider and streaming settings are swapped live on config reload
+	config.OnChange(reloader, func(c *config.AppConfig) config.PricesConfig { return c.Prices }, func(p config.PricesConfig) {
+		priceSettings.Store(loadPriceConfig(p))
+		prewarmer.SetTiming(p.PrewarmLead, p.PrewarmMinInterval)
+	})
+
+	// refs stored in place of ids are hmacs under keys derived per purpose and owner
+	refKeys := keys.NewDeriver(appSecrets.referenceKey)
+	accountRepository := accounts.NewRepository(postgresClient, sealer, cfg.Security.LegacyPlaintext)
+	accountService := accounts.NewAccountService(accountRepository, assetRegistry, accounts.Config{Keys: refKeys})
+	accountsHandler := accounts.NewAccountsHandler(accountService)
 
 	txnRepository := transactions.NewTxnRepository()
-	txnService := transactions.NewTransactionsService(txnRepository)
+	txnConfig := transactions.Config{
+		QuoteLockWindow: cfg.Invoices.QuoteLockWindow,
+		QuoteTolerance:  cfg.Invoices.QuoteTolerance,
+		Keys:            refKeys,
+	}
+	txnService := transactions.NewTransactionsService(txnRepository, priceService, assetRegistry, accountService, priceBroker, txnConfig)
 	txnHandler := transactions.NewTransactionsHandler(txnService)
-	routes.SetupRoutes(router, priceHandler, txnHandler)
+	routes.SetupRoutes(router, cfg.Server, priceHandler, priceHistoryHandler, priceStreamHandler, prewarmHandler, txnHandler, accountsHandler)
+
+	// grpc shares the services behind the http handlers
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		priceGRPCServer := prices.NewPriceGRPCServer(priceService, priceStreamer, assetRegistry)
+		txnGRPCServer := transactions.NewTransactionGRPCServer(txnService)
+		grpcServer = grpcserver.New(appSecrets.grpcAuthToken, cfg.Server.RequestTimeout, priceGRPCServer, txnGRPCServer)
+	}
 
 	log.Println("Config initialized")
 
 	return &App{
-		Config:     cfg,
-		RedisCache: redisClient,
-		Router:     router,
-		PostgresDB: postgresClient,
+		Config:          cfg,
+		Reloader:        reloader,
+		RedisCache:      redisClient,
+		Router:          router,
+		GRPCServer:      grpcServer,
+		PostgresDB:      postgresClient,
+		Prewarmer:       prewarmer,
+		Secrets:         secretStore,
+		Leader:          leader,
+		Rotator:         rotator,
+		shutdownTracing: shutdownTracing,
+	}
+}
+
+// stop serving grpc and flush anything buffered before the process exits
+func (a *App) Close(ctx context.Context) {
+	if a.GRPCServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			a.GRPCServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done(): // open streams would otherwise hold shutdown forever
+			a.GRPCServer.Stop()
+		}
+	}
+	if err := a.shutdownTracing(ctx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
 	}
 }
 
+// build the secret store and resolve every secret up front so a bad provider fails startup,
+// refusing to run outside dev while any secret is still its shipped default
+func setupSecrets(cfg *config.AppConfig) (secrets.Store, appSecrets) {
+	var provider secrets.Provider
+	switch cfg.Secrets.Provider {
+	case "file":
+		provider = secrets.NewFileProvider(cfg.Secrets.Dir)
+	case "vault":
+		provider = secrets.NewVaultProvider(secrets.VaultConfig{
+			Addr:    cfg.Secrets.VaultAddr,
+			Token:   cfg.Secrets.VaultToken,
+			Mount:   cfg.Secrets.VaultMount,
+			Path:    cfg.Secrets.VaultPath,
+			Timeout: cfg.Secrets.VaultTimeout,
+		})
+	default:
+		provider = secrets.NewEnvProvider()
+	}
+	store := secrets.NewStore(provider)
+	resolved := appSecrets{
+		dbPassword:    store.Secret("db_password", cfg.DB.Password),
+		redisPassword: store.Secret("redis_password", cfg.Redis.Password),
+		// empty is a valid sentinel password, so it's not held to the default check below
+		sentinelPassword: store.Secret("redis_sentinel_password", cfg.Redis.SentinelPassword),
+		referenceKey:     store.Secret("hmac_reference_key", cfg.Security.ReferenceKey),
+		grpcAuthToken:    store.Secret("grpc_auth_token", cfg.GRPC.AuthToken),
+	}
+
+	defaults := config.Defaults()
+	knownDefaults := map[string]string{
+		"db_password":        defaults.DB.Password,
+		"redis_password":     defaults.Redis.Password,
+		"hmac_reference_key": defaults.Security.ReferenceKey,
+		"grpc_auth_token":    defaults.GRPC.AuthToken,
+	}
+	handles := map[string]secrets.Secret{
+		"db_password":        resolved.dbPassword,
+		"redis_password":     resolved.redisPassword,
+		"hmac_reference_key": resolved.referenceKey,
+		"grpc_auth_token":    resolved.grpcAuthToken,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var insecure []string
+	for name, secret := range handles {
+		value, err := secret.Value(ctx)
+		if err != nil {
+			log.Fatalf("Failed to resolve secrets from %s provider: %v", provider.Name(), err)
+		}
+		if value == knownDefaults[name] {
+			insecure = append(insecure, name)
+		}
+	}
+	if len(insecure) > 0 {
+		sort.Strings(insecure)
+		if cfg.Env != "dev" {
+			log.Fatalf("Refusing to start in %s with default secrets: %s", cfg.Env, strings.Join(insecure, ", "))
+		}
+		log.Printf("Using default secrets in dev: %s", strings.Join(insecure, ", "))
+	}
+	log.Printf("Secrets resolved from %s provider", provider.Name())
+	return store, resolved
+}
+
+// load the kms that wraps field encryption keys, generating a keyfile on first start in dev
+func setupEnvelope(cfg *config.AppConfig) (envelope.KeyManager, envelope.Envelope) {
+	path := cfg.Security.KeyFile
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) && cfg.Env == "dev" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			log.Fatalf("Failed to create keyfile directory: %v", err)
+		}
+		if err := envelope.GenerateKeyfile(path); err != nil {
+			log.Fatalf("Failed to generate dev keyfile: %v", err)
+		}
+		log.Printf("Generated dev keyfile %s", path)
+	}
+
+	kms, err := envelope.NewLocalKMS(path)
+	if err != nil {
+		log.Fatalf("Failed to load %s kms: %v", cfg.Security.KMS, err)
+	}
+	return kms, envelope.New(kms)
+}
+
+func loadRotationConfig(cfg config.SecurityConfig) rotation.Config {
+	return rotation.Config{
+		BatchSize:       cfg.RotationBatchSize,
+		BatchInterval:   cfg.RotationBatchInterval,
+		CheckInterval:   cfg.RotationCheckInterval,
+		SettleDelay:     cfg.RotationSettleDelay,
+		LegacyPlaintext: cfg.LegacyPlaintext,
+	}
+}
+
+func loadPriceConfig(cfg config.PricesConfig) prices.Config {
+	return prices.Config{
+		BaseURL:            cfg.BaseURL,
+		Timeout:            int(cfg.Timeout / time.Second),
+		RetryAttempts:      cfg.RetryAttempts,
+		CacheTTL:           cfg.CacheTTL,
+		StaleTTL:           cfg.StaleTTL,
+		StreamPollInterval: cfg.StreamPollInterval,
+		StreamThreshold:    cfg.StreamThreshold,
+	}
+}
+
+// resolve the watchlist against the asset registry, skipping pairs that aren't supported
+func loadPrewarmConfig(cfg *config.AppConfig, registry assets.Registry) prices.PrewarmConfig {
+	prewarmConfig := prices.PrewarmConfig{
+		Lead:        cfg.Prices.PrewarmLead,
+		MinInterval: cfg.Prices.PrewarmMinInterval,
+	}
+	for _, entry := range cfg.Prices.Watchlist {
+		symbol, currency, ok := strings.Cut(entry, ":")
+		asset, known := registry.Lookup(symbol)
+		if !ok || !known || currency == "" {
+			log.Printf("Skipping invalid watchlist entry %q", entry)
+			continue
+		}
+		prewarmConfig.Watchlist = append(prewarmConfig.Watchlist, prices.WatchPair{Crypto: asset.CoinGeckoID, Currency: strings.ToLower(currency)})
+	}
+	return prewarmConfig
+}
+
 // setup logging with logging file
 func setupLogging(cfg *config.AppConfig) {
-	logFile, err := os.OpenFile(cfg.LoggingPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.FileMode(0666))
+	logFile, err := os.OpenFile(cfg.Logging.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, cfg.Logging.FileMode())
 	//opens specified file for logging form config, setting it to be created/appended and read/write only with proper permissions
 	if err != nil {
 		//fallback & local logging option
@@ -82,44 +316,110 @@ func setupLogging(cfg *config.AppConfig) {
 	log.Println("Logger initialized")
 }
 
-func setupPgDatabase(cfg *config.AppConfig) platformPostgres.PostgresClient{
-	db, err := postgresInfra.NewPostgresClient(cfg.DB)
+func setupPgDatabase(cfg *config.AppConfig, password secrets.Secret) platformPostgres.PostgresClient{
+	ctx := context.Background()
+
+	// the password is read once, open connections keep working across a rotation
+	dbConfig := cfg.DB
+	dbPassword, err := password.Value(ctx)
 	if err != nil {
-		log.Fatalf("Failed to open Postgres connection: %v", err)
+		log.Fatalf("Failed to resolve Postgres password: %v", err)
 	}
-	pgClient := platformPostgres.NewPgClientWrapper(db)
+	dbConfig.Password = dbPassword
+
+	// retries with backoff internally, only giving up after db.connect_retries attempts
+	db, err := postgresInfra.NewPostgresClient(ctx, dbConfig)
+	if err != nil {
+		log.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+	return platformPostgres.NewPgClientWrapper(db)
+}
+
+func setupAssetRegistry(cfg *config.AppConfig, pgClient platformPostgres.PostgresClient) assets.Registry {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := pgClient.Ping(ctx); err != nil {
-		log.Fatalf("Postgres client ping failed: %v", err)
+	registry, err := assets.Load(ctx, pgClient, cfg.Prices.AssetRegistryPath)
+	if err != nil {
+		log.Fatalf("Failed to load asset registry: %v", err)
 	}
-
-	log.Println("Postgres connected successfully")
-	return pgClient
+	return registry
 }
 
-func setupRedisCache(cfg *config.AppConfig) platformRedis.RedisClient {
-	redisAddr := fmt.Sprintf("%s:%s", cfg.RedisHost, cfg.RedisPort)
-	rawRedisClient := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
-		Password: "",
-		DB:       0,
-	})
-	redisClient := platformRedis.NewRedisClientWrapper(rawRedisClient)
+// returns nil when redis is unreachable and memory-only fallback is enabled
+func setupRedisCache(cfg *config.AppConfig, password secrets.Secret, sentinelPassword secrets.Secret) platformRedis.RedisClient {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+
+	sentinelSecret, err := sentinelPassword.Value(ctx)
+	if err != nil {
+		log.Fatalf("Failed to resolve redis sentinel password: %v", err)
+	}
+	rawRedisClient, err := redisInfra.NewRedisClient(ctx, cfg.Redis, password, sentinelSecret)
+	if err != nil {
+		log.Fatalf("Failed to configure redis: %v", err)
+	}
+	redisClient := platformRedis.NewRedisClientWrapper(rawRedisClient)
 	if err := redisClient.Ping(ctx); err != nil {
-		log.Fatalf("Redis connection failed: %v", err)
+		if !cfg.Redis.MemoryFallback {
+			log.Fatalf("Redis connection failed: %v", err)
+		}
+		log.Printf("Redis connection failed, continuing with memory-only cache: %v", err)
+		_ = redisClient.Close()
+		return nil
 	}
-	log.Println("Redis connected successfully")
+	log.Printf("Redis connected successfully (%s mode)", cfg.Redis.Mode)
 	return redisClient
 }
 
+// elect through the configured backend. config rejects the redis backend alongside
+// redis.memory_fallback, so a redis outage at startup never leaves a replica without one
+func setupLeaderElection(cfg *config.AppConfig, redisClient platformRedis.RedisClient, pgClient platformPostgres.PostgresClient) locks.Elector {
+	// every replica has to elect through the same backend, a replica that quietly switched
+	// would lead alongside the leader the others agreed on
+	var locker locks.Locker
+	if cfg.Locks.Backend == "redis" {
+		if redisClient == nil {
+			log.Fatal("Redis unavailable for leader election, set locks.backend to postgres to elect without it")
+		}
+		locker = locks.NewRedisLocker(redisClient)
+	} else {
+		locker = locks.NewPostgresLocker(pgClient.GetDB())
+	}
+	return locks.NewElector(locker, "background-jobs", cfg.Locks.TTL, cfg.Locks.RetryInterval)
+}
+
+// layer the in-memory cache over redis, or run memory-only with a local broker when redis is unavailable
+func setupPriceCache(cfg *config.AppConfig, redisClient platformRedis.RedisClient) (prices.PricesCache, prices.PriceBroker) {
+	tieredConfig := cacheInfra.TieredCacheConfig{L1Size: cfg.Prices.L1Size, L1TTL: cfg.Prices.L1TTL}
+
+	if redisClient == nil {
+		return cacheInfra.NewMemoryPriceCache(tieredConfig), prices.NewLocalPriceBroker()
+	}
+
+	priceCache := cacheInfra.NewTieredPriceCache(cacheInfra.NewPriceCache(redisClient), tieredConfig)
+	priceCache.StartInvalidationListener(context.Background())
+	return priceCache, cacheInfra.NewPriceBroker(redisClient)
+}
+
 // startup application and configurations
-func InitApp() *App {
+func InitApp(reloader config.Reloader) *App {
 	log.Println("Initializing config...")
-	app := loadAppConfig()
+	app := loadAppConfig(reloader)
+	app.startBackgroundJobs(context.Background())
 	log.Println("App initialized")
 	return app
 }
+
+// kick off background workers that run for the life of the process
+func (a *App) startBackgroundJobs(ctx context.Context) {
+	log.Println("Starting background jobs...")
+	a.Reloader.Start(ctx)
+	a.Secrets.Start(ctx, a.Config.Secrets.RefreshInterval)
+	// running on every replica would multiply provider calls, so only the leader pre-warms
+	go a.Leader.Run(ctx, func(leaderCtx context.Context) {
+		a.Prewarmer.Start(leaderCtx)
+		// one replica rewrapping is enough, and concurrent walks would only conflict
+		a.Rotator.Start(leaderCtx)
+	})
+}
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"github.com/undersleep7x/cryo-project/internal/platform/postgresstore/pgfake"
+)
+
+func TestSetupLeaderElection(t *testing.T) {
+	t.Run("Redis Down With Defaults", func(t *testing.T) {
+		// setupRedisCache returns nil when redis is unreachable and memory_fallback is on
+		cfg := config.Defaults()
+		require.Empty(t, cfg.Validate())
+		require.True(t, cfg.Redis.MemoryFallback)
+		_, pgClient := pgfake.New()
+
+		elector := setupLeaderElection(&cfg, nil, pgClient)
+		assert.NotNil(t, elector)
+		assert.False(t, elector.IsLeader())
+	})
+}
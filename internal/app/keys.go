@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"github.com/undersleep7x/cryo-project/internal/crypto/rotation"
+)
+
+const keysUsage = "usage: cryo keys rotate|status|retire <version>"
+
+// entrypoint for the `keys` subcommand. rotate only touches the keyfile, the leader's
+// background job moves existing rows onto the new version
+func RunKeys(cfg *config.AppConfig, args []string) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+	if args[0] == "rotate" && !cfg.Security.KeyFileShared {
+		// a version only this host's keyfile has would leave every other replica unable to
+		// open what this one writes
+		return fmt.Errorf("refusing to rotate: %s is not marked as shared by every replica, set security.key_file_shared once it is", cfg.Security.KeyFile)
+	}
+	keyManager, sealer := setupEnvelope(cfg)
+	ctx := context.Background()
+
+	if args[0] == "rotate" {
+		version, err := keyManager.AddVersion(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Key version %d is now current, existing rows are rewrapped by the leader in the background", version)
+		return nil
+	}
+
+	_, appSecrets := setupSecrets(cfg)
+	pgClient := setupPgDatabase(cfg, appSecrets.dbPassword)
+	defer pgClient.Close()
+	rotator := rotation.NewRotator(rotation.NewRepository(pgClient), sealer, keyManager, loadRotationConfig(cfg.Security))
+
+	switch args[0] {
+	case "status":
+		status, err := rotator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printRotationStatus(status)
+	case "retire":
+		if len(args) < 2 {
+			return errors.New(keysUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 1 {
+			return fmt.Errorf("invalid key version %q: %s", args[1], keysUsage)
+		}
+		return rotator.Retire(ctx, version)
+	default:
+		return errors.New(keysUsage)
+	}
+	return nil
+}
+
+func printRotationStatus(status *rotation.Status) {
+	fmt.Printf("current key version %d, versions %v\n", status.CurrentVersion, status.Versions)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tCOLUMN\tSCANNED\tREWRAPPED\tSTATE\tUPDATED AT")
+	for _, progress := range status.Progress {
+		state, updatedAt := "pending", ""
+		if progress.Scanned > 0 {
+			state = "running"
+		}
+		if progress.CompletedAt != nil {
+			state = "done"
+		}
+		if !progress.UpdatedAt.IsZero() {
+			updatedAt = progress.UpdatedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", progress.Table, progress.Column, progress.Scanned, progress.Rewrapped, state, updatedAt)
+	}
+	w.Flush()
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"github.com/undersleep7x/cryo-project/internal/migrate"
+	platformPostgres "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
+	"github.com/undersleep7x/cryo-project/migrations"
+)
+
+const migrateUsage = "usage: cryo migrate up|down [steps]|status|redo"
+
+func newMigrator(pgClient platformPostgres.PostgresClient) migrate.Migrator {
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load embedded migrations: %v", err)
+	}
+	return migrate.NewMigrator(pgClient.GetDB(), loaded)
+}
+
+// apply or verify migrations on startup according to db.migrations
+func checkSchema(cfg *config.AppConfig, pgClient platformPostgres.PostgresClient) {
+	if cfg.DB.Migrations == "off" {
+		log.Println("Schema migration check disabled")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	migrator := newMigrator(pgClient)
+	if cfg.DB.Migrations == "up" {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+		log.Printf("Applied %d migrations", len(applied))
+		return
+	}
+	if err := migrator.Verify(ctx); err != nil {
+		if errors.Is(err, migrate.ErrSchemaBehind) {
+			log.Fatalf("Refusing to start: %v. Run `cryo migrate up` first", err)
+		}
+		log.Fatalf("Schema check failed: %v", err)
+	}
+	log.Println("Database schema is current")
+}
+
+// entrypoint for the `migrate` subcommand
+func RunMigrate(cfg *config.AppConfig, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	_, appSecrets := setupSecrets(cfg)
+	pgClient := setupPgDatabase(cfg, appSecrets.dbPassword)
+	defer pgClient.Close()
+	migrator := newMigrator(pgClient)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migrations", len(applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step count %q: %s", args[1], migrateUsage)
+			}
+			steps = n
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("Rolled back %d migrations", len(rolledBack))
+	case "redo":
+		return migrator.Redo(ctx)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(statuses)
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
+
+func printStatus(statuses []migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Modified {
+			state = "modified"
+		}
+		if status.Unknown {
+			state = "unknown"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	w.Flush()
+}
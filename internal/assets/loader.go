@@ -0,0 +1,86 @@
+package assets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	platformPostgres "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
+)
+
+// built in assets used when neither the assets table nor a registry file provide any
+var DefaultAssets = []Asset{
+	{Ticker: "BTC", Name: "Bitcoin", CoinGeckoID: "bitcoin", Decimals: 8, Chain: "bitcoin"},
+	{Ticker: "ETH", Name: "Ethereum", CoinGeckoID: "ethereum", Decimals: 18, Chain: "ethereum"},
+	{Ticker: "XMR", Name: "Monero", CoinGeckoID: "monero", Decimals: 12, Chain: "monero"},
+	{Ticker: "LTC", Name: "Litecoin", CoinGeckoID: "litecoin", Decimals: 8, Chain: "litecoin"},
+	{Ticker: "SOL", Name: "Solana", CoinGeckoID: "solana", Decimals: 9, Chain: "solana"},
+	{Ticker: "USDT", Name: "Tether", CoinGeckoID: "tether", Decimals: 6, Chain: "ethereum"},
+	{Ticker: "USDC", Name: "USD Coin", CoinGeckoID: "usd-coin", Decimals: 6, Chain: "ethereum"},
+}
+
+// build the registry from the assets table, falling back to a json file and then the built in defaults
+func Load(ctx context.Context, db platformPostgres.PostgresClient, path string) (Registry, error) {
+	if db != nil {
+		list, err := loadFromDB(ctx, db)
+		if err != nil {
+			log.Printf("Failed to load assets from database: %v", err)
+		} else if len(list) > 0 {
+			log.Printf("Loaded %d assets from database", len(list))
+			return NewRegistry(list)
+		}
+	}
+
+	if path != "" {
+		list, err := loadFromFile(path)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Loaded %d assets from %s", len(list), path)
+		return NewRegistry(list)
+	}
+
+	log.Printf("No asset registry configured, using %d built in assets", len(DefaultAssets))
+	return NewRegistry(DefaultAssets)
+}
+
+func loadFromFile(path string) ([]Asset, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset registry file: %w", err)
+	}
+	var list []Asset
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse asset registry file: %w", err)
+	}
+	return list, nil
+}
+
+func loadFromDB(ctx context.Context, db platformPostgres.PostgresClient) ([]Asset, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT ticker, name, coingecko_id, provider_ids, decimals, chain
+		FROM assets
+		WHERE enabled`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Asset
+	for rows.Next() {
+		var a Asset
+		var providerIDs []byte
+		if err := rows.Scan(&a.Ticker, &a.Name, &a.CoinGeckoID, &providerIDs, &a.Decimals, &a.Chain); err != nil {
+			return nil, fmt.Errorf("failed to scan asset: %w", err)
+		}
+		if len(providerIDs) > 0 {
+			if err := json.Unmarshal(providerIDs, &a.ProviderIDs); err != nil {
+				return nil, fmt.Errorf("failed to parse provider ids for %s: %w", a.Ticker, err)
+			}
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
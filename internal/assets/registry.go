@@ -0,0 +1,121 @@
+package assets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Asset struct {
+	Ticker      string            `json:"ticker"` // canonical symbol (ex. BTC)
+	Name        string            `json:"name"`
+	CoinGeckoID string            `json:"coingecko_id"`           // id used by the coingecko price api (ex. bitcoin)
+	ProviderIDs map[string]string `json:"provider_ids,omitempty"` // ids for any other price providers keyed by provider name
+	Decimals    int               `json:"decimals"`
+	Chain       string            `json:"chain"`
+}
+
+// lookup of supported assets by ticker or provider id, case-insensitive
+type Registry interface {
+	Lookup(symbol string) (Asset, bool)
+	Suggest(symbol string, limit int) []string
+	All() []Asset
+}
+
+type registryImpl struct {
+	assets []Asset
+	index  map[string]Asset // lowercased ticker and provider ids -> asset
+}
+
+func NewRegistry(list []Asset) (Registry, error) {
+	r := &registryImpl{index: make(map[string]Asset)}
+	for _, a := range list {
+		if a.Ticker == "" || a.CoinGeckoID == "" {
+			return nil, fmt.Errorf("asset %q is missing a ticker or coingecko id", a.Name)
+		}
+		a.Ticker = strings.ToUpper(a.Ticker)
+		keys := []string{a.Ticker, a.CoinGeckoID}
+		for _, id := range a.ProviderIDs {
+			keys = append(keys, id)
+		}
+		for _, key := range keys {
+			key = strings.ToLower(key)
+			if existing, ok := r.index[key]; ok && existing.Ticker != a.Ticker {
+				return nil, fmt.Errorf("asset key %q is shared by %s and %s", key, existing.Ticker, a.Ticker)
+			}
+			r.index[key] = a
+		}
+		r.assets = append(r.assets, a)
+	}
+	sort.Slice(r.assets, func(i, j int) bool { return r.assets[i].Ticker < r.assets[j].Ticker })
+	return r, nil
+}
+
+func (r *registryImpl) Lookup(symbol string) (Asset, bool) {
+	a, ok := r.index[strings.ToLower(strings.TrimSpace(symbol))]
+	return a, ok
+}
+
+// closest tickers to an unknown symbol, by prefix match first and then edit distance
+func (r *registryImpl) Suggest(symbol string, limit int) []string {
+	symbol = strings.ToLower(strings.TrimSpace(symbol))
+	type candidate struct {
+		ticker string
+		score  int
+	}
+	best := make(map[string]int)
+	for key, a := range r.index {
+		score := levenshtein(symbol, key)
+		if symbol != "" && (strings.HasPrefix(key, symbol) || strings.HasPrefix(symbol, key)) {
+			score = 0
+		}
+		if prev, ok := best[a.Ticker]; !ok || score < prev {
+			best[a.Ticker] = score
+		}
+	}
+
+	var candidates []candidate
+	for ticker, score := range best {
+		if score <= 3 {
+			candidates = append(candidates, candidate{ticker, score})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score < candidates[j].score
+		}
+		return candidates[i].ticker < candidates[j].ticker
+	})
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < limit; i++ {
+		suggestions = append(suggestions, candidates[i].ticker)
+	}
+	return suggestions
+}
+
+func (r *registryImpl) All() []Asset {
+	out := make([]Asset, len(r.assets))
+	copy(out, r.assets)
+	return out
+}
+
+func levenshtein(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
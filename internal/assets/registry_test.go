@@ -0,0 +1,35 @@
+package assets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	registry, err := NewRegistry(DefaultAssets)
+	assert.NoError(t, err)
+
+	t.Run("Lookup By Ticker And Id", func(t *testing.T) {
+		for _, symbol := range []string{"BTC", "btc", " bitcoin ", "Bitcoin"} {
+			asset, ok := registry.Lookup(symbol)
+			assert.True(t, ok, symbol)
+			assert.Equal(t, "bitcoin", asset.CoinGeckoID)
+		}
+	})
+
+	t.Run("Unknown Asset Suggestions", func(t *testing.T) {
+		_, ok := registry.Lookup("etherium")
+		assert.False(t, ok)
+		assert.Equal(t, "ETH", registry.Suggest("etherium", 3)[0])
+		assert.Empty(t, registry.Suggest("zzzzzzzzzz", 3))
+	})
+
+	t.Run("Duplicate Keys Rejected", func(t *testing.T) {
+		_, err := NewRegistry([]Asset{
+			{Ticker: "BTC", CoinGeckoID: "bitcoin"},
+			{Ticker: "WBTC", CoinGeckoID: "bitcoin"},
+		})
+		assert.Error(t, err)
+	})
+}
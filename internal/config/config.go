@@ -1,63 +1,298 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Configuration is resolved in increasing order of precedence:
+//  1. built in defaults (Defaults)
+//  2. config file (--config flag or CONFIG_FILE), yaml or toml by extension
+//  3. dotenv file (ENV_FILE, dev only), which never overrides variables already set
+//  4. process environment variables (env tags below)
+//
+// fields tagged reload:"true" are re-read on SIGHUP or config file change (see Reloader),
+// everything else needs a restart to take effect
+
+type AppConfig struct {
+	Env      string         `yaml:"env" env:"ENV"`
+	Port     int            `yaml:"port" env:"PORT"`
+	Server   ServerConfig   `yaml:"server"`
+	GRPC     GRPCConfig     `yaml:"grpc"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	DB       DBConfig       `yaml:"db"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Prices   PricesConfig   `yaml:"prices"`
+	Invoices InvoiceConfig  `yaml:"invoices"`
+	Security SecurityConfig `yaml:"security"`
+	Secrets  SecretsConfig  `yaml:"secrets"`
+	Locks    LocksConfig    `yaml:"locks"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+}
+
+// per-request deadlines, passed down to cache, sql and provider calls
+type ServerConfig struct {
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT"`
+	HistoryTimeout time.Duration `yaml:"history_timeout" env:"HISTORY_REQUEST_TIMEOUT"` // history and ohlc may page through the provider
+}
+
+// grpc api for internal consumers, served next to the http api
+type GRPCConfig struct {
+	Enabled   bool   `yaml:"enabled" env:"GRPC_ENABLED"`
+	Port      int    `yaml:"port" env:"GRPC_PORT"`
+	AuthToken string `yaml:"auth_token" env:"GRPC_AUTH_TOKEN" secret:"true"` // bearer token callers send in the authorization metadata
+}
+
+type LoggingConfig struct {
+	Path  string `yaml:"path" env:"LOGGING_PATH"`
+	Perms string `yaml:"perms" env:"LOGGING_PERMS"` // octal file mode for the log file
+}
+
 type DBConfig struct {
-	Host string
-	Port string
-	User string
-	Password string
-	Name string
+	Host             string        `yaml:"host" env:"DB_HOST"`
+	Port             int           `yaml:"port" env:"DB_PORT"`
+	User             string        `yaml:"user" env:"DB_USER"`
+	Password         string        `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name             string        `yaml:"name" env:"DB_NAME"`
+	MaxOpenConns     int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"` // 0 means unlimited
+	MaxIdleConns     int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime  time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime  time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	SSLMode          string        `yaml:"sslmode" env:"DB_SSLMODE"`         // disable, require, verify-ca or verify-full
+	SSLRootCert      string        `yaml:"sslrootcert" env:"DB_SSLROOTCERT"` // ca bundle, needed for verify-ca and verify-full
+	SSLCert          string        `yaml:"sslcert" env:"DB_SSLCERT"`         // client cert for mutual tls
+	SSLKey           string        `yaml:"sslkey" env:"DB_SSLKEY"`
+	StatementTimeout time.Duration `yaml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"` // server side cap on any single statement, 0 for none
+	ConnectTimeout   time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	ConnectRetries   int           `yaml:"connect_retries" env:"DB_CONNECT_RETRIES"` // startup attempts before giving up
+	ConnectBackoff   time.Duration `yaml:"connect_backoff" env:"DB_CONNECT_BACKOFF"` // first retry delay, doubled each attempt
+	Migrations       string        `yaml:"migrations" env:"DB_MIGRATIONS"`           // on startup: up applies pending, verify refuses to start if behind, off skips
 }
-type AppConfig struct {
-	Env       string
-	Port      string
-	RedisHost string
-	RedisPort string
-	LoggingPath string
-	LoggingPerms string
-	DB DBConfig
+
+type RedisConfig struct {
+	Mode             string        `yaml:"mode" env:"REDIS_MODE"` // standalone, sentinel or cluster
+	Host             string        `yaml:"host" env:"REDIS_HOST"`
+	Port             int           `yaml:"port" env:"REDIS_PORT"`
+	Addrs            []string      `yaml:"addrs" env:"REDIS_ADDRS"`             // sentinel or cluster seed addresses
+	MasterName       string        `yaml:"master_name" env:"REDIS_MASTER_NAME"` // sentinel only
+	Username         string        `yaml:"username" env:"REDIS_USERNAME"`       // acl user, empty for the default user
+	Password         string        `yaml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	SentinelUsername string        `yaml:"sentinel_username" env:"REDIS_SENTINEL_USERNAME"`
+	SentinelPassword string        `yaml:"sentinel_password" env:"REDIS_SENTINEL_PASSWORD" secret:"true"`
+	DB               int           `yaml:"db" env:"REDIS_DB"`
+	TLS              bool          `yaml:"tls" env:"REDIS_TLS"`
+	TLSCACert        string        `yaml:"tls_ca_cert" env:"REDIS_TLS_CA_CERT"`         // pem bundle, system roots when empty
+	TLSServerName    string        `yaml:"tls_server_name" env:"REDIS_TLS_SERVER_NAME"` // defaults to the host being dialed
+	PoolSize         int           `yaml:"pool_size" env:"REDIS_POOL_SIZE"`             // per node, 0 uses go-redis' 10 per cpu
+	DialTimeout      time.Duration `yaml:"dial_timeout" env:"REDIS_DIAL_TIMEOUT"`
+	ReadTimeout      time.Duration `yaml:"read_timeout" env:"REDIS_READ_TIMEOUT"`
+	WriteTimeout     time.Duration `yaml:"write_timeout" env:"REDIS_WRITE_TIMEOUT"`
+	MemoryFallback   bool          `yaml:"memory_fallback" env:"CACHE_MEMORY_FALLBACK"` // run with an in-memory cache when redis is unreachable
 }
 
-func LoadConfig() *AppConfig {
-	env := os.Getenv("ENV")
-	if env == ""{
-		log.Println("No application environment found; loading with local env config.")
-		env = "dev"
+type PricesConfig struct {
+	BaseURL            string        `yaml:"base_url" env:"PRICE_BASE_URL" reload:"true"`
+	Timeout            time.Duration `yaml:"timeout" env:"PRICE_TIMEOUT" reload:"true"`
+	RetryAttempts      int           `yaml:"retry_attempts" env:"PRICE_RETRY_ATTEMPTS" reload:"true"`
+	CacheTTL           time.Duration `yaml:"cache_ttl" env:"PRICE_CACHE_TTL" reload:"true"`
+	StaleTTL           time.Duration `yaml:"stale_ttl" env:"PRICE_STALE_TTL" reload:"true"` // how long last known prices are kept for provider outages
+	L1Size             int           `yaml:"l1_size" env:"PRICE_CACHE_L1_SIZE"`
+	L1TTL              time.Duration `yaml:"l1_ttl" env:"PRICE_CACHE_L1_TTL"`
+	StreamPollInterval time.Duration `yaml:"stream_poll_interval" env:"PRICE_STREAM_POLL_INTERVAL" reload:"true"`
+	StreamThreshold    float64       `yaml:"stream_threshold" env:"PRICE_STREAM_THRESHOLD" reload:"true"`
+	Watchlist          []string      `yaml:"watchlist" env:"PRICE_WATCHLIST"` // asset:currency pairs
+	PrewarmLead        time.Duration `yaml:"prewarm_lead" env:"PRICE_PREWARM_LEAD" reload:"true"`
+	PrewarmMinInterval time.Duration `yaml:"prewarm_min_interval" env:"PRICE_PREWARM_MIN_INTERVAL" reload:"true"`
+	AssetRegistryPath  string        `yaml:"asset_registry_path" env:"ASSET_REGISTRY_PATH"`
+}
+
+type InvoiceConfig struct {
+	QuoteLockWindow time.Duration `yaml:"quote_lock_window" env:"QUOTE_LOCK_WINDOW"`
+	QuoteTolerance  float64       `yaml:"quote_tolerance" env:"QUOTE_TOLERANCE"`
+}
+
+type SecurityConfig struct {
+	ReferenceKey          string        `yaml:"reference_key" env:"HMAC_REFERENCE_KEY" secret:"true"`      // master secret every reference key is derived from, can't be rotated
+	KMS                   string        `yaml:"kms" env:"KMS_PROVIDER"`                                    // holds the keys that wrap field encryption keys, only local for now
+	KeyFile               string        `yaml:"key_file" env:"KMS_KEY_FILE"`                               // local kms keyfile, generated on first start in dev
+	KeyFileShared         bool          `yaml:"key_file_shared" env:"KMS_KEY_FILE_SHARED"`                 // key_file is on storage every replica mounts, `cryo keys rotate` refuses to run without it
+	RotationBatchSize     int           `yaml:"rotation_batch_size" env:"KEY_ROTATION_BATCH_SIZE"`         // rows rewrapped per batch after a key rotation
+	RotationBatchInterval time.Duration `yaml:"rotation_batch_interval" env:"KEY_ROTATION_BATCH_INTERVAL"` // pause between batches, throttles the rotation job
+	RotationCheckInterval time.Duration `yaml:"rotation_check_interval" env:"KEY_ROTATION_CHECK_INTERVAL"` // how often the leader looks for a new key version
+	RotationSettleDelay   time.Duration `yaml:"rotation_settle_delay" env:"KEY_ROTATION_SETTLE_DELAY"`     // wait after a rotation completes before the old version can be retired
+	LegacyPlaintext       bool          `yaml:"legacy_plaintext" env:"ENCRYPTION_LEGACY_PLAINTEXT"`        // migration only, accept and seal values written before columns were encrypted. turn off once cryo_encryption_plaintext_reads_total stops rising
+}
+
+// where db/redis passwords and hmac keys are resolved from at runtime. values set above are
+// only used when the provider has no entry for a secret
+type SecretsConfig struct {
+	Provider        string        `yaml:"provider" env:"SECRETS_PROVIDER"` // env, file or vault
+	Dir             string        `yaml:"dir" env:"SECRETS_DIR"`           // file provider, one file per secret
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"SECRETS_REFRESH_INTERVAL"`
+	VaultAddr       string        `yaml:"vault_addr" env:"VAULT_ADDR"`
+	VaultToken      string        `yaml:"vault_token" env:"VAULT_TOKEN" secret:"true"`
+	VaultMount      string        `yaml:"vault_mount" env:"VAULT_MOUNT"`
+	VaultPath       string        `yaml:"vault_path" env:"VAULT_SECRET_PATH"`
+	VaultTimeout    time.Duration `yaml:"vault_timeout" env:"VAULT_TIMEOUT"`
+}
+
+// leases that keep singleton background jobs on one replica
+type LocksConfig struct {
+	Backend       string        `yaml:"backend" env:"LOCKS_BACKEND"`               // redis or postgres, redis needs redis.memory_fallback off
+	TTL           time.Duration `yaml:"ttl" env:"LOCKS_TTL"`                       // how long a crashed leader blocks the others
+	RetryInterval time.Duration `yaml:"retry_interval" env:"LOCKS_RETRY_INTERVAL"` // how often followers try to take over
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER"`            // none or otlp, none still forwards inbound traceparent headers
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"` // otlp/http collector url, http:// sends without tls
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`    // share of new traces kept, callers' sampling decisions are honored
+	ServiceName string  `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+func Defaults() AppConfig {
+	return AppConfig{
+		Env:  "dev",
+		Port: 8080,
+		Server: ServerConfig{
+			RequestTimeout: 5 * time.Second,
+			HistoryTimeout: 10 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Enabled:   true,
+			Port:      50051,
+			AuthToken: "grpc-token",
+		},
+		Logging: LoggingConfig{
+			Path:  "logs/apps.log",
+			Perms: "0666",
+		},
+		DB: DBConfig{
+			Host:             "postgres",
+			Port:             5432,
+			User:             "cryouser",
+			Password:         "cryopass",
+			Name:             "cryo",
+			MaxOpenConns:     25,
+			MaxIdleConns:     5,
+			ConnMaxLifetime:  30 * time.Minute,
+			ConnMaxIdleTime:  5 * time.Minute,
+			SSLMode:          "disable",
+			StatementTimeout: 30 * time.Second,
+			ConnectTimeout:   5 * time.Second,
+			ConnectRetries:   5,
+			ConnectBackoff:   time.Second,
+			Migrations:       "verify",
+		},
+		Redis: RedisConfig{
+			Mode:           "standalone",
+			Host:           "localhost",
+			Port:           6379,
+			DialTimeout:    5 * time.Second,
+			ReadTimeout:    3 * time.Second,
+			WriteTimeout:   3 * time.Second,
+			MemoryFallback: true,
+		},
+		Prices: PricesConfig{
+			BaseURL:            "https://api.coingecko.com/api/v3",
+			Timeout:            5 * time.Second,
+			RetryAttempts:      3,
+			CacheTTL:           30 * time.Second,
+			StaleTTL:           24 * time.Hour,
+			L1Size:             1024,
+			L1TTL:              5 * time.Second,
+			StreamPollInterval: 5 * time.Second,
+			StreamThreshold:    0.001,
+			Watchlist:          []string{"BTC:usd", "ETH:usd"},
+			PrewarmLead:        5 * time.Second,
+			PrewarmMinInterval: 2 * time.Second,
+		},
+		Invoices: InvoiceConfig{
+			QuoteLockWindow: 15 * time.Minute,
+			QuoteTolerance:  0.005,
+		},
+		Security: SecurityConfig{
+			ReferenceKey:          "hmac-key",
+			KMS:                   "local",
+			KeyFile:               "keys/cryo.keys.json",
+			RotationBatchSize:     500,
+			RotationBatchInterval: 100 * time.Millisecond,
+			RotationCheckInterval: time.Minute,
+			RotationSettleDelay:   5 * time.Minute,
+		},
+		Secrets: SecretsConfig{
+			Provider:        "env",
+			Dir:             "/run/secrets",
+			RefreshInterval: 5 * time.Minute,
+			VaultMount:      "secret",
+			VaultPath:       "cryo",
+			VaultTimeout:    5 * time.Second,
+		},
+		Locks: LocksConfig{
+			Backend:       "postgres",
+			TTL:           15 * time.Second,
+			RetryInterval: 5 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318",
+			SampleRatio: 1,
+			ServiceName: "cryo",
+		},
 	}
+}
 
+// load config from defaults, the optional file at path, and the environment, then validate it.
+// every bad field is reported in the returned error rather than stopping at the first
+func LoadConfig(path string) (*AppConfig, error) {
+	cfg := Defaults()
+	var problems []string
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		log.Printf("Loading config file %s", path)
+		problems = append(problems, applyFile(&cfg, path)...)
+	}
+
+	env := os.Getenv("ENV")
+	if env == "" {
+		env = cfg.Env
+	}
 	if env == "dev" {
-        if err := godotenv.Load("/app/.env.dev"); err != nil {
-            log.Printf("No .env.dev file found — relying on system environment: Error - %v", err)
-        }
+		envFile := getEnv("ENV_FILE", "/app/.env.dev")
+		if err := godotenv.Load(envFile); err != nil {
+			log.Printf("No %s file found — relying on system environment: Error - %v", envFile, err)
+		}
 	}
 
-	return &AppConfig{
-		Env:       env,
-		Port:      getEnv("PORT", "8080"),
-		RedisHost: getEnv("REDIS_HOST", "localhost"),
-		RedisPort: getEnv("REDIS_PORT", "6379"),
-		LoggingPath: getEnv("LOGGING_PATH", "logs/apps.log"),
-		LoggingPerms: getEnv("LOGGING_PERMS", "0666"),
-		DB: DBConfig{
-			Host: getEnv("DB_HOST", "postgres"),
-			Port: getEnv("DB_PORT", "5432"),
-			User: getEnv("DB_USER", "cryouser"),
-			Password: getEnv("DB_PASSWORD", "cryopass"),
-			Name: getEnv("DB_NAME", "cryo"),
-		},
+	problems = append(problems, applyEnv(&cfg)...)
+	problems = append(problems, cfg.Validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
 	}
+	return &cfg, nil
+}
+
+// octal log file permissions, only valid after Validate has passed
+func (l LoggingConfig) FileMode() os.FileMode {
+	perms, _ := strconv.ParseUint(l.Perms, 8, 32)
+	return os.FileMode(perms)
 }
 
+func (r RedisConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", r.Host, r.Port)
+}
 
 func getEnv(key, fallback string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
 	}
 	return fallback
-}
\ No newline at end of file
+}
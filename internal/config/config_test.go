@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("ENV", "test") // skip the dev dotenv file
+
+	t.Run("Defaults Are Valid", func(t *testing.T) {
+		cfg, err := LoadConfig("")
+		assert.NoError(t, err)
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, os.FileMode(0666), cfg.Logging.FileMode())
+	})
+
+	t.Run("Env Overrides File Overrides Defaults", func(t *testing.T) {
+		path := writeConfigFile(t, "cryo.yaml", `
+port: 9090
+redis:
+  host: cache.internal
+  db: 2
+prices:
+  timeout: 10s
+  watchlist: [BTC:eur]
+`)
+		t.Setenv("REDIS_HOST", "redis.override")
+		t.Setenv("PRICE_WATCHLIST", "BTC:usd, SOL:usd")
+
+		cfg, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, 9090, cfg.Port)
+		assert.Equal(t, "redis.override:6379", cfg.Redis.Addr())
+		assert.Equal(t, 2, cfg.Redis.DB)
+		assert.Equal(t, 10*time.Second, cfg.Prices.Timeout)
+		assert.Equal(t, []string{"BTC:usd", "SOL:usd"}, cfg.Prices.Watchlist)
+		assert.Equal(t, 5432, cfg.DB.Port) // untouched default
+	})
+
+	t.Run("Toml File", func(t *testing.T) {
+		path := writeConfigFile(t, "cryo.toml", `
+[invoices]
+quote_lock_window = "5m"
+quote_tolerance = 0.01
+`)
+		cfg, err := LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, 5*time.Minute, cfg.Invoices.QuoteLockWindow)
+		assert.Equal(t, 0.01, cfg.Invoices.QuoteTolerance)
+	})
+
+	t.Run("Every Problem Reported", func(t *testing.T) {
+		path := writeConfigFile(t, "cryo.yaml", `
+port: 70000
+redis:
+  hots: typo
+prices:
+  base_url: ftp://prices
+  timeout: soon
+  watchlist: [BTC]
+`)
+		t.Setenv("LOGGING_PERMS", "rw-rw-rw-")
+
+		cfg, err := LoadConfig(path)
+		assert.Nil(t, cfg)
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.ElementsMatch(t, []string{
+			"redis.hots: unknown setting",
+			"prices.timeout: invalid duration \"soon\"",
+			"port: must be between 1 and 65535, got 70000",
+			"logging.perms: must be an octal file mode, got \"rw-rw-rw-\"",
+			"prices.base_url: must be an http(s) url, got \"ftp://prices\"",
+			"prices.watchlist: entry \"BTC\" must be asset:currency",
+		}, validationErr.Problems)
+	})
+
+	t.Run("Sub-Second Prices Timeout", func(t *testing.T) {
+		// would truncate to no timeout at all in the provider client
+		_, err := LoadConfig(writeConfigFile(t, "cryo.yaml", "prices:\n  timeout: 500ms\n"))
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []string{"prices.timeout: must be at least 1s"}, validationErr.Problems)
+	})
+
+	t.Run("Relative Shared Keyfile", func(t *testing.T) {
+		// resolved against each process's working directory, so replicas can't agree on it
+		_, err := LoadConfig(writeConfigFile(t, "cryo.yaml", "security:\n  key_file_shared: true\n"))
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []string{"security.key_file: must be an absolute path when security.key_file_shared is set"}, validationErr.Problems)
+	})
+
+	t.Run("Redis Locks With Memory Fallback", func(t *testing.T) {
+		// a replica that fell back to memory would have no way to join the election
+		_, err := LoadConfig(writeConfigFile(t, "cryo.yaml", "locks:\n  backend: redis\n"))
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []string{"locks.backend: must be postgres while redis.memory_fallback is set, a replica started without redis couldn't elect"}, validationErr.Problems)
+
+		cfg, err := LoadConfig(writeConfigFile(t, "cryo.yaml", "locks:\n  backend: redis\nredis:\n  memory_fallback: false\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "redis", cfg.Locks.Backend)
+	})
+
+	t.Run("Unsupported File Type", func(t *testing.T) {
+		_, err := LoadConfig(writeConfigFile(t, "cryo.json", `{}`))
+		assert.ErrorContains(t, err, "unsupported extension")
+	})
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Defaults()
+	cfg.Redis.Password = ""
+
+	out, err := cfg.Dump()
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "password: '[REDACTED]'")
+	assert.Contains(t, string(out), "reference_key: '[REDACTED]'")
+	assert.NotContains(t, string(out), "cryopass")
+	assert.NotContains(t, string(out), "hmac-key")
+	assert.Equal(t, "cryopass", cfg.DB.Password) // original untouched
+	assert.Equal(t, "", cfg.Redacted().Redis.Password)
+}
+
+func TestReloader(t *testing.T) {
+	t.Setenv("ENV", "test")
+	path := writeConfigFile(t, "cryo.yaml", "port: 8080\nprices:\n  cache_ttl: 30s\n")
+	cfg, err := LoadConfig(path)
+	assert.NoError(t, err)
+	reloader := NewReloader(path, cfg)
+
+	var notified []PricesConfig
+	OnChange(reloader, func(c *AppConfig) PricesConfig { return c.Prices }, func(p PricesConfig) {
+		notified = append(notified, p)
+	})
+
+	t.Run("Reloadable Fields Applied", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("port: 9090\nprices:\n  cache_ttl: 60s\n"), 0600))
+		assert.NoError(t, reloader.Reload())
+
+		assert.Equal(t, 60*time.Second, reloader.Current().Prices.CacheTTL)
+		assert.Equal(t, 8080, reloader.Current().Port)       // needs a restart
+		assert.Equal(t, 30*time.Second, cfg.Prices.CacheTTL) // earlier snapshots are never mutated
+		assert.Len(t, notified, 1)
+		assert.Equal(t, 60*time.Second, notified[0].CacheTTL)
+	})
+
+	t.Run("Invalid Config Rejected", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("prices:\n  cache_ttl: -1s\n"), 0600))
+		assert.Error(t, reloader.Reload())
+		assert.Equal(t, 60*time.Second, reloader.Current().Prices.CacheTTL)
+		assert.Len(t, notified, 1)
+	})
+
+	t.Run("Unchanged Section Not Notified", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("port: 7070\nprices:\n  cache_ttl: 60s\n"), 0600))
+		assert.NoError(t, reloader.Reload())
+		assert.Len(t, notified, 1)
+	})
+}
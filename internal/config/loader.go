@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const redactedValue = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// decode a yaml or toml file and apply every key it sets onto cfg
+func applyFile(cfg *AppConfig, path string) []string {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("config file: %v", err)}
+	}
+
+	data := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &data)
+	case ".toml":
+		err = toml.Unmarshal(raw, &data)
+	default:
+		return []string{fmt.Sprintf("config file: unsupported extension %q, use .yaml, .yml or .toml", filepath.Ext(path))}
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("config file: %v", err)}
+	}
+
+	var problems []string
+	applyMap(reflect.ValueOf(cfg).Elem(), data, "", &problems)
+	return problems
+}
+
+// walk decoded file data alongside the struct, rejecting keys that don't map to a field
+func applyMap(v reflect.Value, data map[string]any, prefix string, problems *[]string) {
+	t := v.Type()
+	known := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := yamlName(field)
+		known[name] = true
+		raw, ok := data[name]
+		if !ok {
+			continue
+		}
+
+		fv := v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			nested, ok := raw.(map[string]any)
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("%s%s: expected a table of settings", prefix, name))
+				continue
+			}
+			applyMap(fv, nested, prefix+name+".", problems)
+			continue
+		}
+		if err := setField(fv, raw); err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s%s: %v", prefix, name, err))
+		}
+	}
+
+	var unknown []string
+	for key := range data {
+		if !known[key] {
+			unknown = append(unknown, prefix+key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		*problems = append(*problems, fmt.Sprintf("%s: unknown setting", key))
+	}
+}
+
+// override fields from their env tags when the variable is set
+func applyEnv(cfg *AppConfig) []string {
+	var problems []string
+	walkFields(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.StructField, fv reflect.Value) {
+		key := field.Tag.Get("env")
+		if key == "" {
+			return
+		}
+		val, ok := os.LookupEnv(key)
+		if !ok || val == "" {
+			return
+		}
+		if err := setField(fv, val); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s): %v", path, key, err))
+		}
+	})
+	return problems
+}
+
+// visit every leaf field with its dotted yaml path
+func walkFields(v reflect.Value, prefix string, visit func(path string, field reflect.StructField, fv reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := prefix + yamlName(field)
+		if field.Type.Kind() == reflect.Struct {
+			walkFields(v.Field(i), path+".", visit)
+			continue
+		}
+		visit(path, field, v.Field(i))
+	}
+}
+
+// parse a raw file or env value into the field's type
+func setField(fv reflect.Value, raw any) error {
+	if fv.Type() == durationType {
+		if d, ok := raw.(time.Duration); ok {
+			fv.SetInt(int64(d))
+			return nil
+		}
+		d, err := time.ParseDuration(fmt.Sprint(raw))
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", fmt.Sprint(raw))
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(fmt.Sprint(raw))
+	case reflect.Int:
+		n, err := strconv.Atoi(fmt.Sprint(raw))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", fmt.Sprint(raw))
+		}
+		fv.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(fmt.Sprint(raw))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", fmt.Sprint(raw))
+		}
+		fv.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(fmt.Sprint(raw), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", fmt.Sprint(raw))
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		switch list := raw.(type) {
+		case []any:
+			for _, item := range list {
+				items = append(items, fmt.Sprint(item))
+			}
+		default: // comma separated, as set from env
+			for _, item := range strings.Split(fmt.Sprint(raw), ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", fv.Type())
+	}
+	return nil
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// copy of the config with every secret field masked, safe to log or print
+func (c AppConfig) Redacted() AppConfig {
+	out := c
+	out.Prices.Watchlist = append([]string(nil), c.Prices.Watchlist...)
+	walkFields(reflect.ValueOf(&out).Elem(), "", func(path string, field reflect.StructField, fv reflect.Value) {
+		if field.Tag.Get("secret") == "true" && fv.String() != "" {
+			fv.SetString(redactedValue)
+		}
+	})
+	return out
+}
+
+// effective config as yaml with secrets redacted, for --print-config
+func (c AppConfig) Dump() ([]byte, error) {
+	return yaml.Marshal(c.Redacted())
+}
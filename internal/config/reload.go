@@ -0,0 +1,169 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const reloadPollInterval = 5 * time.Second // how often the config file is checked for changes
+
+// holds the live config and swaps in reloadable changes on SIGHUP or config file change
+type Reloader interface {
+	Current() *AppConfig
+	// fn runs after every applied reload with the previous and new config
+	Subscribe(fn func(old *AppConfig, new *AppConfig))
+	Reload() error
+	Start(ctx context.Context)
+}
+
+type reloaderImpl struct {
+	path    string
+	current atomic.Pointer[AppConfig]
+
+	mu          sync.Mutex // serialises reloads and guards subscribers
+	subscribers []func(old *AppConfig, new *AppConfig)
+	modTime     time.Time
+}
+
+// path is the file the initial config was loaded from, CONFIG_FILE when empty
+func NewReloader(path string, cfg *AppConfig) Reloader {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	r := &reloaderImpl{path: path}
+	r.current.Store(cfg)
+	if info, err := os.Stat(path); err == nil {
+		r.modTime = info.ModTime()
+	}
+	return r
+}
+
+// call fn with the section selected by section whenever a reload changes it
+func OnChange[T any](r Reloader, section func(*AppConfig) T, fn func(T)) {
+	r.Subscribe(func(old *AppConfig, new *AppConfig) {
+		next := section(new)
+		if !reflect.DeepEqual(section(old), next) {
+			fn(next)
+		}
+	})
+}
+
+func (r *reloaderImpl) Current() *AppConfig {
+	return r.current.Load()
+}
+
+func (r *reloaderImpl) Subscribe(fn func(old *AppConfig, new *AppConfig)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// re-read and validate the config, then atomically swap in the reloadable fields.
+// an invalid config is rejected whole and the running config is left untouched
+func (r *reloaderImpl) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded, err := LoadConfig(r.path)
+	if err != nil {
+		return err
+	}
+
+	old := r.current.Load()
+	next := *old
+	var applied, restart []string
+	walkPair(reflect.ValueOf(&next).Elem(), reflect.ValueOf(loaded).Elem(), "", func(path string, field reflect.StructField, current reflect.Value, incoming reflect.Value) {
+		if reflect.DeepEqual(current.Interface(), incoming.Interface()) {
+			return
+		}
+		if field.Tag.Get("reload") != "true" {
+			restart = append(restart, path)
+			return
+		}
+		current.Set(incoming)
+		applied = append(applied, path)
+	})
+	if len(restart) > 0 {
+		log.Printf("Config changes need a restart to take effect: %v", restart)
+	}
+	if len(applied) == 0 {
+		log.Println("Config reloaded, no reloadable settings changed")
+		return nil
+	}
+	if problems := next.Validate(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	r.current.Store(&next)
+	log.Printf("Config reloaded, applied: %v", applied)
+	for _, fn := range r.subscribers {
+		fn(old, &next)
+	}
+	return nil
+}
+
+// reload on SIGHUP and whenever the config file's modification time changes, until ctx is cancelled
+func (r *reloaderImpl) Start(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+		ticker := time.NewTicker(reloadPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Println("SIGHUP received, reloading config")
+			case <-ticker.C:
+				if !r.fileChanged() {
+					continue
+				}
+				log.Printf("Config file %s changed, reloading config", r.path)
+			}
+			if err := r.Reload(); err != nil {
+				log.Printf("Config reload rejected, keeping current config: %v", err)
+			}
+		}
+	}()
+}
+
+func (r *reloaderImpl) fileChanged() bool {
+	if r.path == "" {
+		return false
+	}
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if info.ModTime().Equal(r.modTime) {
+		return false
+	}
+	r.modTime = info.ModTime()
+	return true
+}
+
+// visit matching leaf fields of two configs with their dotted yaml path
+func walkPair(a reflect.Value, b reflect.Value, prefix string, visit func(path string, field reflect.StructField, a reflect.Value, b reflect.Value)) {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := prefix + yamlName(field)
+		if field.Type.Kind() == reflect.Struct {
+			walkPair(a.Field(i), b.Field(i), path+".", visit)
+			continue
+		}
+		visit(path, field, a.Field(i), b.Field(i))
+	}
+}
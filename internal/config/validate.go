@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// every problem found while loading and validating config
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration (%d problems):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// check every field and return all problems found, empty when the config is usable
+func (c *AppConfig) Validate() []string {
+	var problems []string
+	check := func(ok bool, field string, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: %s", field, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(c.Env != "", "env", "must not be empty")
+	check(validPort(c.Port), "port", "must be between 1 and 65535, got %d", c.Port)
+	check(c.Server.RequestTimeout > 0, "server.request_timeout", "must be positive")
+	check(c.Server.HistoryTimeout > 0, "server.history_timeout", "must be positive")
+	if c.GRPC.Enabled {
+		check(validPort(c.GRPC.Port), "grpc.port", "must be between 1 and 65535, got %d", c.GRPC.Port)
+		check(c.GRPC.Port != c.Port, "grpc.port", "must differ from port")
+		check(c.GRPC.AuthToken != "", "grpc.auth_token", "must not be empty")
+	}
+
+	check(c.Logging.Path != "", "logging.path", "must not be empty")
+	_, err := strconv.ParseUint(c.Logging.Perms, 8, 32)
+	check(err == nil, "logging.perms", "must be an octal file mode, got %q", c.Logging.Perms)
+
+	check(c.DB.Host != "", "db.host", "must not be empty")
+	check(validPort(c.DB.Port), "db.port", "must be between 1 and 65535, got %d", c.DB.Port)
+	check(c.DB.User != "", "db.user", "must not be empty")
+	check(c.DB.Name != "", "db.name", "must not be empty")
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns", "must not be negative")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns", "must not be negative")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns", "must not exceed db.max_open_conns")
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime", "must not be negative")
+	check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time", "must not be negative")
+	switch c.DB.SSLMode {
+	case "disable", "require":
+	case "verify-ca", "verify-full":
+		check(c.DB.SSLRootCert != "", "db.sslrootcert", "must be set for sslmode %s", c.DB.SSLMode)
+	default:
+		check(false, "db.sslmode", "must be disable, require, verify-ca or verify-full, got %q", c.DB.SSLMode)
+	}
+	for _, file := range [][2]string{{"db.sslrootcert", c.DB.SSLRootCert}, {"db.sslcert", c.DB.SSLCert}, {"db.sslkey", c.DB.SSLKey}} {
+		if file[1] != "" {
+			_, err := os.Stat(file[1])
+			check(err == nil, file[0], "cannot read %q: %v", file[1], err)
+		}
+	}
+	check((c.DB.SSLCert == "") == (c.DB.SSLKey == ""), "db.sslcert", "db.sslcert and db.sslkey must be set together")
+	check(c.DB.StatementTimeout >= 0, "db.statement_timeout", "must not be negative")
+	check(c.DB.ConnectTimeout >= time.Second, "db.connect_timeout", "must be at least 1s")
+	check(c.DB.ConnectRetries >= 1, "db.connect_retries", "must be at least 1")
+	check(c.DB.ConnectBackoff > 0, "db.connect_backoff", "must be positive")
+	check(c.DB.Migrations == "up" || c.DB.Migrations == "verify" || c.DB.Migrations == "off", "db.migrations", "must be up, verify or off, got %q", c.DB.Migrations)
+
+	switch c.Redis.Mode {
+	case "standalone":
+		check(c.Redis.Host != "", "redis.host", "must not be empty")
+		check(validPort(c.Redis.Port), "redis.port", "must be between 1 and 65535, got %d", c.Redis.Port)
+	case "sentinel":
+		check(c.Redis.MasterName != "", "redis.master_name", "must be set in sentinel mode")
+		check(len(c.Redis.Addrs) > 0, "redis.addrs", "must list the sentinels in sentinel mode")
+	case "cluster":
+		check(len(c.Redis.Addrs) > 0, "redis.addrs", "must list at least one node in cluster mode")
+		check(c.Redis.DB == 0, "redis.db", "must be 0 in cluster mode, got %d", c.Redis.DB)
+	default:
+		check(false, "redis.mode", "must be standalone, sentinel or cluster, got %q", c.Redis.Mode)
+	}
+	for _, addr := range c.Redis.Addrs {
+		_, port, err := net.SplitHostPort(addr)
+		portNum, _ := strconv.Atoi(port)
+		check(err == nil && validPort(portNum), "redis.addrs", "must be host:port, got %q", addr)
+	}
+	check(c.Redis.DB >= 0, "redis.db", "must not be negative")
+	if c.Redis.TLSCACert != "" {
+		check(c.Redis.TLS, "redis.tls_ca_cert", "requires redis.tls")
+		_, err := os.Stat(c.Redis.TLSCACert)
+		check(err == nil, "redis.tls_ca_cert", "cannot read %q: %v", c.Redis.TLSCACert, err)
+	}
+	check(c.Redis.PoolSize >= 0, "redis.pool_size", "must not be negative")
+	check(c.Redis.DialTimeout > 0, "redis.dial_timeout", "must be positive")
+	check(c.Redis.ReadTimeout >= 0, "redis.read_timeout", "must not be negative")
+	check(c.Redis.WriteTimeout >= 0, "redis.write_timeout", "must not be negative")
+
+	baseURL, err := url.Parse(c.Prices.BaseURL)
+	check(err == nil && (baseURL.Scheme == "http" || baseURL.Scheme == "https") && baseURL.Host != "", "prices.base_url", "must be an http(s) url, got %q", c.Prices.BaseURL)
+	check(c.Prices.Timeout >= time.Second, "prices.timeout", "must be at least 1s") // the provider client takes whole seconds
+	check(c.Prices.RetryAttempts >= 0, "prices.retry_attempts", "must not be negative")
+	check(c.Prices.CacheTTL > 0, "prices.cache_ttl", "must be positive")
+	check(c.Prices.StaleTTL >= c.Prices.CacheTTL, "prices.stale_ttl", "must be at least prices.cache_ttl")
+	check(c.Prices.L1Size > 0, "prices.l1_size", "must be positive")
+	check(c.Prices.L1TTL > 0 && c.Prices.L1TTL <= c.Prices.CacheTTL, "prices.l1_ttl", "must be positive and no longer than prices.cache_ttl")
+	check(c.Prices.StreamPollInterval > 0, "prices.stream_poll_interval", "must be positive")
+	check(c.Prices.StreamThreshold >= 0, "prices.stream_threshold", "must not be negative")
+	for _, entry := range c.Prices.Watchlist {
+		asset, currency, ok := strings.Cut(entry, ":")
+		check(ok && asset != "" && currency != "", "prices.watchlist", "entry %q must be asset:currency", entry)
+	}
+	check(c.Prices.PrewarmLead > 0 && c.Prices.PrewarmLead < c.Prices.CacheTTL, "prices.prewarm_lead", "must be positive and shorter than prices.cache_ttl")
+	check(c.Prices.PrewarmMinInterval > 0, "prices.prewarm_min_interval", "must be positive")
+
+	check(c.Invoices.QuoteLockWindow > 0, "invoices.quote_lock_window", "must be positive")
+	check(c.Invoices.QuoteTolerance >= 0 && c.Invoices.QuoteTolerance < 1, "invoices.quote_tolerance", "must be between 0 and 1, got %v", c.Invoices.QuoteTolerance)
+
+	check(c.Security.ReferenceKey != "", "security.reference_key", "must not be empty")
+	check(c.Security.KMS == "local", "security.kms", "must be local, got %q", c.Security.KMS)
+	check(c.Security.KMS != "local" || c.Security.KeyFile != "", "security.key_file", "must be set for the local kms")
+	check(!c.Security.KeyFileShared || filepath.IsAbs(c.Security.KeyFile), "security.key_file", "must be an absolute path when security.key_file_shared is set")
+	check(c.Security.RotationBatchSize > 0, "security.rotation_batch_size", "must be positive")
+	check(c.Security.RotationBatchInterval >= 0, "security.rotation_batch_interval", "must not be negative")
+	check(c.Security.RotationCheckInterval > 0, "security.rotation_check_interval", "must be positive")
+	check(c.Security.RotationSettleDelay > 0, "security.rotation_settle_delay", "must be positive")
+
+	switch c.Secrets.Provider {
+	case "env":
+	case "file":
+		check(c.Secrets.Dir != "", "secrets.dir", "must be set for the file provider")
+	case "vault":
+		vaultURL, err := url.Parse(c.Secrets.VaultAddr)
+		check(err == nil && (vaultURL.Scheme == "http" || vaultURL.Scheme == "https") && vaultURL.Host != "", "secrets.vault_addr", "must be an http(s) url for the vault provider, got %q", c.Secrets.VaultAddr)
+		check(c.Secrets.VaultToken != "", "secrets.vault_token", "must be set for the vault provider")
+		check(c.Secrets.VaultPath != "", "secrets.vault_path", "must be set for the vault provider")
+		check(c.Secrets.VaultTimeout > 0, "secrets.vault_timeout", "must be positive")
+	default:
+		check(false, "secrets.provider", "must be env, file or vault, got %q", c.Secrets.Provider)
+	}
+	check(c.Secrets.RefreshInterval >= 0, "secrets.refresh_interval", "must not be negative")
+
+	check(c.Locks.Backend == "redis" || c.Locks.Backend == "postgres", "locks.backend", "must be redis or postgres, got %q", c.Locks.Backend)
+	check(c.Locks.Backend != "redis" || !c.Redis.MemoryFallback, "locks.backend", "must be postgres while redis.memory_fallback is set, a replica started without redis couldn't elect")
+	check(c.Locks.TTL >= time.Second, "locks.ttl", "must be at least 1s")
+	check(c.Locks.RetryInterval > 0, "locks.retry_interval", "must be positive")
+
+	check(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "otlp", "tracing.exporter", "must be none or otlp, got %q", c.Tracing.Exporter)
+	if c.Tracing.Exporter == "otlp" {
+		endpoint, err := url.Parse(c.Tracing.Endpoint)
+		check(err == nil && (endpoint.Scheme == "http" || endpoint.Scheme == "https") && endpoint.Host != "", "tracing.endpoint", "must be an http(s) url for the otlp exporter, got %q", c.Tracing.Endpoint)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	check(c.Tracing.ServiceName != "", "tracing.service_name", "must not be empty")
+
+	return problems
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
@@ -0,0 +1,168 @@
+// Package envelope encrypts sensitive fields at rest with AES-256-GCM. Every value gets a
+// fresh data key, which is wrapped by a versioned key encryption key from a KMS and stored
+// alongside the ciphertext, so a key encryption key can be rotated by rewrapping data keys
+// without touching the ciphertext itself.
+package envelope
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrDecrypt   = errors.New("failed to decrypt value")
+	ErrMalformed = errors.New("malformed sealed value")
+)
+
+const (
+	keySize   = 32 // aes-256
+	nonceSize = 12
+
+	// sealed values are base64 of: format (1) | kek version (4) | wrapped key length (2) | wrapped key | nonce | ciphertext
+	formatV1   byte = 1
+	headerSize      = 1 + 4 + 2
+)
+
+type Envelope interface {
+	// encrypt plaintext under a fresh data key, aad must be given again to open it
+	Seal(ctx context.Context, plaintext []byte, aad []byte) (string, error)
+	Open(ctx context.Context, sealed string, aad []byte) ([]byte, error)
+	// move the data key under the current key encryption key. the ciphertext is carried over
+	// as is, so no aad is needed, and a value already under the current version is returned unchanged
+	Rewrap(ctx context.Context, sealed string) (string, error)
+}
+
+type envelopeImpl struct {
+	kms KMS
+}
+
+func New(kms KMS) Envelope {
+	return &envelopeImpl{kms: kms}
+}
+
+// parsed form of a sealed value
+type sealedValue struct {
+	version    int
+	wrapped    []byte
+	nonce      []byte
+	ciphertext []byte
+}
+
+func (e *envelopeImpl) Seal(ctx context.Context, plaintext []byte, aad []byte) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	version, wrapped, err := e.kms.Wrap(ctx, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	value := sealedValue{version: version, wrapped: wrapped, nonce: make([]byte, nonceSize)}
+	if _, err := rand.Read(value.nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	value.ciphertext = aead.Seal(nil, value.nonce, plaintext, dataAAD(aad))
+	return value.encode()
+}
+
+func (e *envelopeImpl) Open(ctx context.Context, sealed string, aad []byte) ([]byte, error) {
+	value, err := decode(sealed)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := e.kms.Unwrap(ctx, value.version, value.wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, value.nonce, value.ciphertext, dataAAD(aad))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func (e *envelopeImpl) Rewrap(ctx context.Context, sealed string) (string, error) {
+	value, err := decode(sealed)
+	if err != nil {
+		return "", err
+	}
+	current, err := e.kms.CurrentVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read current key version: %w", err)
+	}
+	if value.version == current {
+		return sealed, nil
+	}
+
+	dataKey, err := e.kms.Unwrap(ctx, value.version, value.wrapped)
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	if value.version, value.wrapped, err = e.kms.Wrap(ctx, dataKey); err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return value.encode()
+}
+
+// key encryption key version a sealed value was written under
+func KeyVersion(sealed string) (int, error) {
+	value, err := decode(sealed)
+	if err != nil {
+		return 0, err
+	}
+	return value.version, nil
+}
+
+// caller aad is prefixed with the format so a value can't be replayed under another layout.
+// the version is left out, the kms already refuses to unwrap a key under any other version
+func dataAAD(aad []byte) []byte {
+	return append([]byte{formatV1}, aad...)
+}
+
+func (v sealedValue) encode() (string, error) {
+	if v.version < 1 || int64(v.version) > math.MaxUint32 || len(v.wrapped) > math.MaxUint16 {
+		return "", fmt.Errorf("%w: version %d with a %d byte wrapped key", ErrMalformed, v.version, len(v.wrapped))
+	}
+	buf := make([]byte, headerSize, headerSize+len(v.wrapped)+len(v.nonce)+len(v.ciphertext))
+	buf[0] = formatV1
+	binary.BigEndian.PutUint32(buf[1:5], uint32(v.version))
+	binary.BigEndian.PutUint16(buf[5:7], uint16(len(v.wrapped)))
+	buf = append(buf, v.wrapped...)
+	buf = append(buf, v.nonce...)
+	buf = append(buf, v.ciphertext...)
+	return base64.RawStdEncoding.EncodeToString(buf), nil
+}
+
+func decode(sealed string) (sealedValue, error) {
+	buf, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(buf) < headerSize || buf[0] != formatV1 {
+		return sealedValue{}, ErrMalformed
+	}
+	version := int(binary.BigEndian.Uint32(buf[1:5]))
+	wrappedLen := int(binary.BigEndian.Uint16(buf[5:7]))
+	rest := buf[headerSize:]
+	if len(rest) < wrappedLen+nonceSize {
+		return sealedValue{}, ErrMalformed
+	}
+	return sealedValue{
+		version:    version,
+		wrapped:    rest[:wrappedLen],
+		nonce:      rest[wrappedLen : wrappedLen+nonceSize],
+		ciphertext: rest[wrappedLen+nonceSize:],
+	}, nil
+}
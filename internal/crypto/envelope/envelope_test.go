@@ -0,0 +1,324 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/internal/metrics"
+)
+
+// writes a keyfile holding a key per version, each key filled with its version number
+func newTestKeyfile(t *testing.T, current int, versions ...int) string {
+	t.Helper()
+	file := keyfile{Current: current, Keys: map[string]string{}}
+	for _, version := range versions {
+		file.Keys[strconv.Itoa(version)] = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{byte(version)}, keySize))
+	}
+	raw, err := json.Marshal(file)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, raw, 0o600))
+	return path
+}
+
+func newTestEnvelope(t *testing.T, current int, versions ...int) Envelope {
+	t.Helper()
+	kms, err := NewLocalKMS(newTestKeyfile(t, current, versions...))
+	require.NoError(t, err)
+	return New(kms)
+}
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnvelope(t, 1, 1)
+	aad := []byte("merchants.metadata\x00m1")
+
+	sealed, err := env.Seal(ctx, []byte("tier=gold"), aad)
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "tier=gold")
+
+	plaintext, err := env.Open(ctx, sealed, aad)
+	require.NoError(t, err)
+	assert.Equal(t, "tier=gold", string(plaintext))
+
+	again, err := env.Seal(ctx, []byte("tier=gold"), aad)
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again) // fresh data key and nonce every time
+
+	t.Run("Wrong AAD", func(t *testing.T) {
+		_, err := env.Open(ctx, sealed, []byte("merchants.metadata\x00m2"))
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		raw, _ := base64.RawStdEncoding.DecodeString(sealed)
+		raw[len(raw)-1] ^= 1
+		_, err := env.Open(ctx, base64.RawStdEncoding.EncodeToString(raw), aad)
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, value := range []string{"", "plaintext address", base64.RawStdEncoding.EncodeToString([]byte{2, 0, 0, 0, 1, 0, 0})} {
+			_, err := env.Open(ctx, value, aad)
+			assert.ErrorIs(t, err, ErrMalformed, value)
+		}
+	})
+
+	t.Run("Other Key", func(t *testing.T) {
+		_, err := newTestEnvelope(t, 2, 2).Open(ctx, sealed, aad)
+		assert.ErrorIs(t, err, ErrUnknownVersion)
+	})
+}
+
+func TestKeyVersions(t *testing.T) {
+	ctx := context.Background()
+	aad := []byte("wallets.address\x00w1")
+
+	old, err := newTestEnvelope(t, 1, 1).Seal(ctx, []byte("bc1qaddress"), aad)
+	require.NoError(t, err)
+
+	// version 2 becomes current, values written under version 1 still open
+	rotated := newTestEnvelope(t, 2, 1, 2)
+	plaintext, err := rotated.Open(ctx, old, aad)
+	require.NoError(t, err)
+	assert.Equal(t, "bc1qaddress", string(plaintext))
+
+	sealed, err := rotated.Seal(ctx, []byte("bc1qaddress"), aad)
+	require.NoError(t, err)
+	version, err := KeyVersion(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+	version, err = KeyVersion(old)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	t.Run("Version Header Swapped", func(t *testing.T) {
+		raw, _ := base64.RawStdEncoding.DecodeString(old)
+		raw[4] = 2 // claims version 2, the key was wrapped under 1
+		_, err := rotated.Open(ctx, base64.RawStdEncoding.EncodeToString(raw), aad)
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+}
+
+func TestLocalKMS(t *testing.T) {
+	t.Run("Generate", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys.json")
+		require.NoError(t, GenerateKeyfile(path))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		_, err = NewLocalKMS(path)
+		assert.NoError(t, err)
+		assert.Error(t, GenerateKeyfile(path)) // never overwrites existing keys
+	})
+
+	tests := map[string]string{
+		"Not JSON":          `keys`,
+		"No Current Key":    `{"current": 2, "keys": {"1": "` + base64.StdEncoding.EncodeToString(make([]byte, keySize)) + `"}}`,
+		"Short Key":         `{"current": 1, "keys": {"1": "` + base64.StdEncoding.EncodeToString(make([]byte, 16)) + `"}}`,
+		"Bad Version":       `{"current": 1, "keys": {"one": "` + base64.StdEncoding.EncodeToString(make([]byte, keySize)) + `"}}`,
+		"Key Not Base64":    `{"current": 1, "keys": {"1": "not base64!"}}`,
+		"Zero Key Versions": `{"current": 0, "keys": {}}`,
+	}
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.json")
+			require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+			_, err := NewLocalKMS(path)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("Missing File", func(t *testing.T) {
+		_, err := NewLocalKMS(filepath.Join(t.TempDir(), "missing.json"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestColumn(t *testing.T) {
+	ctx := context.Background()
+	column := NewColumn(newTestEnvelope(t, 1, 1), "merchants", "metadata")
+	metadata := "tier=gold"
+
+	value, err := column.Value(ctx, "m1", &metadata).Value()
+	require.NoError(t, err)
+	sealed, ok := value.(string)
+	require.True(t, ok)
+
+	t.Run("Round Trip", func(t *testing.T) {
+		id := "m1"
+		var opened *string
+		require.NoError(t, column.Scan(ctx, &id, &opened).Scan([]byte(sealed)))
+		require.NotNil(t, opened)
+		assert.Equal(t, metadata, *opened)
+	})
+
+	t.Run("Null", func(t *testing.T) {
+		value, err := column.Value(ctx, "m1", nil).Value()
+		require.NoError(t, err)
+		assert.Nil(t, value)
+
+		id, opened := "m1", &metadata
+		require.NoError(t, column.Scan(ctx, &id, &opened).Scan(nil))
+		assert.Nil(t, opened)
+	})
+
+	t.Run("Copied To Another Row", func(t *testing.T) {
+		id := "m2"
+		var opened *string
+		err := column.Scan(ctx, &id, &opened).Scan(sealed)
+		assert.ErrorIs(t, err, ErrDecrypt)
+		assert.Nil(t, opened)
+	})
+
+	t.Run("Copied To Another Column", func(t *testing.T) {
+		id := "m1"
+		var opened *string
+		err := NewColumn(column.envelope, "wallets", "address").Scan(ctx, &id, &opened).Scan(sealed)
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+
+	t.Run("Unsealed Rejected", func(t *testing.T) {
+		id := "m1"
+		var opened *string
+		err := column.Scan(ctx, &id, &opened).Scan([]byte("tier=silver"))
+		assert.ErrorIs(t, err, ErrMalformed)
+		assert.Nil(t, opened)
+	})
+
+	t.Run("Legacy Plaintext", func(t *testing.T) {
+		reads := metrics.EncryptionPlaintextReads.WithLabelValues("merchants", "metadata")
+		before := testutil.ToFloat64(reads)
+
+		id := "m1"
+		var opened *string
+		require.NoError(t, column.WithLegacyPlaintext(true).Scan(ctx, &id, &opened).Scan([]byte("tier=silver")))
+		require.NotNil(t, opened)
+		assert.Equal(t, "tier=silver", *opened)
+		assert.Equal(t, before+1, testutil.ToFloat64(reads))
+
+		// sealed values still have to open, the flag only covers values that aren't sealed
+		err := column.WithLegacyPlaintext(true).Scan(ctx, &id, &opened).Scan(base64.RawStdEncoding.EncodeToString([]byte{1, 0, 0, 0, 9, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}))
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrMalformed)
+		assert.Equal(t, before+1, testutil.ToFloat64(reads))
+	})
+}
+
+func TestRewrap(t *testing.T) {
+	ctx := context.Background()
+	aad := []byte("wallets.address\x00w1")
+	path := newTestKeyfile(t, 1, 1)
+	kms, err := NewLocalKMS(path)
+	require.NoError(t, err)
+	env := New(kms)
+
+	sealed, err := env.Seal(ctx, []byte("bc1qaddress"), aad)
+	require.NoError(t, err)
+	unchanged, err := env.Rewrap(ctx, sealed)
+	require.NoError(t, err)
+	assert.Equal(t, sealed, unchanged) // already current
+
+	version, err := kms.AddVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	rewrapped, err := env.Rewrap(ctx, sealed)
+	require.NoError(t, err)
+	rewrappedVersion, err := KeyVersion(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rewrappedVersion)
+
+	// the rewrapped value no longer needs version 1
+	require.NoError(t, kms.RetireVersion(ctx, 1))
+	plaintext, err := env.Open(ctx, rewrapped, aad)
+	require.NoError(t, err)
+	assert.Equal(t, "bc1qaddress", string(plaintext))
+	_, err = env.Open(ctx, sealed, aad)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestKeyManager(t *testing.T) {
+	ctx := context.Background()
+	path := newTestKeyfile(t, 1, 1)
+	kms, err := NewLocalKMS(path)
+	require.NoError(t, err)
+
+	t.Run("Retire Current", func(t *testing.T) {
+		assert.ErrorIs(t, kms.RetireVersion(ctx, 1), ErrCurrentVersion)
+		assert.ErrorIs(t, kms.RetireVersion(ctx, 7), ErrUnknownVersion)
+	})
+
+	t.Run("Other Process Rotates", func(t *testing.T) {
+		defer func(interval time.Duration) { reloadInterval = interval }(reloadInterval)
+		reloadInterval = 0
+
+		other, err := NewLocalKMS(path)
+		require.NoError(t, err)
+		version, err := other.AddVersion(ctx)
+		require.NoError(t, err)
+
+		current, err := kms.CurrentVersion(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, version, current) // new writes move over without a restart
+		versions, err := kms.Versions(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, version}, versions)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("Concurrent Rotations", func(t *testing.T) {
+		// two instances on one file stand in for two `cryo keys rotate` runs, only the file lock is shared
+		path := newTestKeyfile(t, 1, 1)
+		const rotations = 20
+		var mu sync.Mutex
+		added := map[int]bool{}
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			process, err := NewLocalKMS(path)
+			require.NoError(t, err)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < rotations; j++ {
+					version, err := process.AddVersion(ctx)
+					assert.NoError(t, err)
+					mu.Lock()
+					assert.False(t, added[version], "version %d added twice", version)
+					added[version] = true
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		file, _, err := readKeyfile(path)
+		require.NoError(t, err)
+		assert.Len(t, file.Keys, 1+2*rotations) // no rotation overwrote another's key
+		assert.Equal(t, 1+2*rotations, file.Current)
+	})
+
+	t.Run("Broken Keyfile Keeps Last Keys", func(t *testing.T) {
+		defer func(interval time.Duration) { reloadInterval = interval }(reloadInterval)
+		reloadInterval = 0
+
+		require.NoError(t, os.WriteFile(path, []byte("truncated"), 0o600))
+		_, _, err := kms.Wrap(ctx, make([]byte, keySize))
+		assert.NoError(t, err)
+	})
+}
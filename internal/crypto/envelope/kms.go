@@ -0,0 +1,325 @@
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownVersion = errors.New("unknown key encryption key version")
+	ErrCurrentVersion = errors.New("current key encryption key version can't be retired")
+)
+
+// how often a local kms checks its keyfile for versions added or retired by another process
+var reloadInterval = time.Second
+
+// longest a local kms keeps wrapping under a version after another process has replaced it
+func ReloadInterval() time.Duration {
+	return reloadInterval
+}
+
+// holds the key encryption keys, data keys never leave the process unwrapped
+type KMS interface {
+	// wrap a data key under the current key encryption key, returning the version used
+	Wrap(ctx context.Context, dataKey []byte) (version int, wrapped []byte, err error)
+	Unwrap(ctx context.Context, version int, wrapped []byte) ([]byte, error)
+	// version new data keys are wrapped under
+	CurrentVersion(ctx context.Context) (int, error)
+}
+
+// kms whose key versions are managed by cryo rather than by the provider
+type KeyManager interface {
+	KMS
+	// every version that can still unwrap, ascending
+	Versions(ctx context.Context) ([]int, error)
+	// add a fresh key and make it current, returning its version
+	AddVersion(ctx context.Context) (int, error)
+	// drop a version, anything still wrapped under it can no longer be opened
+	RetireVersion(ctx context.Context, version int) error
+}
+
+// keys are base64 encoded 32 byte aes keys by version, current is the one new data keys are wrapped under
+type keyfile struct {
+	Current int               `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+type localKMS struct {
+	path string
+
+	mu        sync.Mutex
+	current   int
+	keks      map[int]cipher.AEAD
+	loaded    os.FileInfo // keyfile the keys were read from
+	checkedAt time.Time
+}
+
+// kms backed by a json keyfile on disk, for dev and tests. production deployments should
+// plug in a kms that keeps the key encryption keys off the host. the keyfile is re-read when
+// it changes, so a version added by `cryo keys rotate` is picked up without a restart
+func NewLocalKMS(path string) (KeyManager, error) {
+	kms := &localKMS{path: path}
+	file, info, err := readKeyfile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := kms.apply(file, info); err != nil {
+		return nil, err
+	}
+	return kms, nil
+}
+
+// write a keyfile holding a single fresh version 1 key, failing if path already exists
+func GenerateKeyfile(path string) error {
+	key, err := newKey()
+	if err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(keyfile{Current: 1, Keys: map[string]string{"1": key}}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create keyfile: %w", err)
+	}
+	if _, err := f.Write(append(raw, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write keyfile: %w", err)
+	}
+	return f.Close()
+}
+
+func (k *localKMS) Wrap(ctx context.Context, dataKey []byte) (int, []byte, error) {
+	k.mu.Lock()
+	k.reload()
+	version, kek := k.current, k.keks[k.current]
+	k.mu.Unlock()
+
+	nonce := make([]byte, kek.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return version, kek.Seal(nonce, nonce, dataKey, versionAAD(version)), nil
+}
+
+func (k *localKMS) Unwrap(ctx context.Context, version int, wrapped []byte) ([]byte, error) {
+	k.mu.Lock()
+	k.reload()
+	kek, ok := k.keks[version]
+	k.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	if len(wrapped) < kek.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	dataKey, err := kek.Open(nil, wrapped[:kek.NonceSize()], wrapped[kek.NonceSize():], versionAAD(version))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return dataKey, nil
+}
+
+func (k *localKMS) CurrentVersion(ctx context.Context) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.reload()
+	return k.current, nil
+}
+
+func (k *localKMS) Versions(ctx context.Context) ([]int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.reload()
+	versions := make([]int, 0, len(k.keks))
+	for version := range k.keks {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+func (k *localKMS) AddVersion(ctx context.Context) (int, error) {
+	var added int
+	err := k.update(func(file *keyfile) error {
+		for name := range file.Keys {
+			version, _ := strconv.Atoi(name)
+			added = max(added, version)
+		}
+		added++
+		key, err := newKey()
+		if err != nil {
+			return err
+		}
+		file.Keys[strconv.Itoa(added)] = key
+		file.Current = added
+		return nil
+	})
+	return added, err
+}
+
+func (k *localKMS) RetireVersion(ctx context.Context, version int) error {
+	return k.update(func(file *keyfile) error {
+		if _, ok := file.Keys[strconv.Itoa(version)]; !ok {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+		if version == file.Current {
+			return ErrCurrentVersion
+		}
+		delete(file.Keys, strconv.Itoa(version))
+		return nil
+	})
+}
+
+// apply fn to the keyfile as it is on disk now, so changes made by other processes aren't lost.
+// k.mu only covers this process, the file lock keeps two `cryo keys` runs from both reading
+// version n and writing different keys as n+1
+func (k *localKMS) update(fn func(file *keyfile) error) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	unlock, err := lockKeyfile(k.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, _, err := readKeyfile(k.path)
+	if err != nil {
+		return err
+	}
+	if err := fn(&file); err != nil {
+		return err
+	}
+	if err := writeKeyfile(k.path, file); err != nil {
+		return err
+	}
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	return k.apply(file, info)
+}
+
+// pick up keyfile changes made by other processes, callers hold k.mu. a keyfile that fails
+// to load keeps the last good keys in service rather than failing every caller
+func (k *localKMS) reload() {
+	if time.Since(k.checkedAt) < reloadInterval {
+		return
+	}
+	k.checkedAt = time.Now()
+	// updates replace the file, in place edits change its size or mtime
+	info, err := os.Stat(k.path)
+	if err == nil && os.SameFile(info, k.loaded) && info.Size() == k.loaded.Size() && info.ModTime().Equal(k.loaded.ModTime()) {
+		return
+	}
+	file, info, err := readKeyfile(k.path)
+	if err == nil {
+		err = k.apply(file, info)
+	}
+	if err != nil {
+		log.Printf("Failed to reload keyfile, keeping version %d current: %v", k.current, err)
+	}
+}
+
+// swap in the keys from file, callers hold k.mu or own k exclusively
+func (k *localKMS) apply(file keyfile, info os.FileInfo) error {
+	keks := make(map[int]cipher.AEAD, len(file.Keys))
+	for name, encoded := range file.Keys {
+		version, err := strconv.Atoi(name)
+		if err != nil || version < 1 {
+			return fmt.Errorf("keyfile %s: version %q must be a positive integer", k.path, name)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return fmt.Errorf("keyfile %s: version %d must be a base64 %d byte key", k.path, version, keySize)
+		}
+		if keks[version], err = newGCM(key); err != nil {
+			return err
+		}
+	}
+	if _, ok := keks[file.Current]; !ok {
+		return fmt.Errorf("keyfile %s: current version %d has no key", k.path, file.Current)
+	}
+	k.current, k.keks, k.loaded = file.Current, keks, info
+	return nil
+}
+
+func readKeyfile(path string) (keyfile, os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return keyfile{}, nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return keyfile{}, nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	var file keyfile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return keyfile{}, nil, fmt.Errorf("failed to parse keyfile %s: %w", path, err)
+	}
+	if file.Keys == nil {
+		file.Keys = map[string]string{}
+	}
+	return file, info, nil
+}
+
+// replace the keyfile through a rename so readers never see it half written
+func writeKeyfile(path string, file keyfile) error {
+	raw, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write keyfile: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(raw, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write keyfile: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write keyfile: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace keyfile: %w", err)
+	}
+	return nil
+}
+
+func newKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// a wrapped key only unwraps under the version it claims
+func versionAAD(version int) []byte {
+	return []byte("cryo/kek/" + strconv.Itoa(version))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
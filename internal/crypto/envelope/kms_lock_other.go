@@ -0,0 +1,11 @@
+//go:build !unix
+
+package envelope
+
+import "errors"
+
+// without flock two processes updating the keyfile could both add the same version, so
+// updates are refused rather than risk losing a key
+func lockKeyfile(path string) (unlock func(), err error) {
+	return nil, errors.New("keyfile updates need flock, only supported on unix")
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package envelope
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// hold an exclusive lock on path+".lock" until unlock is called. the keyfile itself can't be
+// locked since updates replace it, and a lock on the replaced file guards nothing
+func lockKeyfile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open keyfile lock: %w", err)
+	}
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock keyfile: %w", err)
+	}
+	// closing the descriptor releases the lock
+	return func() { f.Close() }, nil
+}
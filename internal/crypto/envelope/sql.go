@@ -0,0 +1,106 @@
+package envelope
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/undersleep7x/cryo-project/internal/metrics"
+)
+
+// an encrypted column, every value is sealed with its table, column and row id as associated
+// data so a ciphertext copied onto another row or column fails to open
+type Column struct {
+	envelope        Envelope
+	table           string
+	column          string
+	legacyPlaintext bool
+}
+
+func NewColumn(envelope Envelope, table string, column string) Column {
+	return Column{envelope: envelope, table: table, column: column}
+}
+
+// a copy that reads a value which isn't sealed at all as plaintext written before the column
+// was encrypted. only for the migration: anyone who can write to the db could plant such a
+// value, so every fallback is logged and counted, and it's turned off again once the rotation
+// job has sealed the legacy rows
+func (c Column) WithLegacyPlaintext(allow bool) Column {
+	c.legacyPlaintext = allow
+	return c
+}
+
+// the separator keeps ("ab", "c") and ("a", "bc") apart
+func (c Column) AAD(id string) []byte {
+	return []byte(c.table + "." + c.column + "\x00" + id)
+}
+
+// query argument that seals plaintext for the row id, nil plaintext is stored as null
+func (c Column) Value(ctx context.Context, id string, plaintext *string) driver.Valuer {
+	return columnValue{ctx: ctx, column: c, id: id, plaintext: plaintext}
+}
+
+// scan destination that opens the column into dest, null scans to nil. id is read when the
+// column is scanned, so it must come earlier in the same row's scan destinations. a value that
+// isn't sealed at all fails with ErrMalformed unless WithLegacyPlaintext is set. plaintext that
+// happens to parse as a sealed value isn't guessed at either way, it fails to open like a
+// damaged value and the rotation job reports it as failed
+func (c Column) Scan(ctx context.Context, id *string, dest **string) sql.Scanner {
+	return columnScanner{ctx: ctx, column: c, id: id, dest: dest}
+}
+
+type columnValue struct {
+	ctx       context.Context
+	column    Column
+	id        string
+	plaintext *string
+}
+
+func (v columnValue) Value() (driver.Value, error) {
+	if v.plaintext == nil {
+		return nil, nil
+	}
+	sealed, err := v.column.envelope.Seal(v.ctx, []byte(*v.plaintext), v.column.AAD(v.id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s.%s: %w", v.column.table, v.column.column, err)
+	}
+	return sealed, nil
+}
+
+type columnScanner struct {
+	ctx    context.Context
+	column Column
+	id     *string
+	dest   **string
+}
+
+func (s columnScanner) Scan(src any) error {
+	var sealed string
+	switch value := src.(type) {
+	case nil:
+		*s.dest = nil
+		return nil
+	case string:
+		sealed = value
+	case []byte:
+		sealed = string(value)
+	default:
+		return fmt.Errorf("cannot scan %T into encrypted %s.%s", src, s.column.table, s.column.column)
+	}
+
+	plaintext, err := s.column.envelope.Open(s.ctx, sealed, s.column.AAD(*s.id))
+	if errors.Is(err, ErrMalformed) && s.column.legacyPlaintext {
+		log.Printf("Read unsealed %s.%s for %s as legacy plaintext", s.column.table, s.column.column, *s.id)
+		metrics.EncryptionPlaintextReads.WithLabelValues(s.column.table, s.column.column).Inc()
+		plaintext, err = []byte(sealed), nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s.%s for %s: %w", s.column.table, s.column.column, *s.id, err)
+	}
+	opened := string(plaintext)
+	*s.dest = &opened
+	return nil
+}
@@ -0,0 +1,103 @@
+// Package keys derives purpose scoped hmac keys from one master secret with HKDF. Every
+// reference stored in the database can be recomputed from the id it stands for, so records
+// are found without storing plaintext ids, while a leaked derived key only exposes the refs
+// of one purpose and owner.
+//
+// The master secret (security.reference_key) can't be rotated. Every stored ref is derived from
+// it and there is no key version to try older masters with, so a new master orphans every
+// user, merchant and wallet ref already in the database. It is pinned for the life of the
+// process so a rotation picked up by the secret store can't break refs mid run.
+package keys
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+	utils "github.com/undersleep7x/cryo-project/internal/utils"
+	"golang.org/x/crypto/hkdf"
+)
+
+// what a derived key is used for, part of the HKDF info so keys never cross purposes
+type Purpose string
+
+const (
+	PurposeAccount        Purpose = "account"         // users.account_number, unscoped so an account number alone finds its user
+	PurposeMerchantOwner  Purpose = "merchant_owner"  // merchants.account_ref, scoped to the owning account
+	PurposeWalletOwner    Purpose = "wallet_owner"    // wallets.owner_ref, scoped to the owning user or merchant
+	PurposeRefundMerchant Purpose = "refund_merchant" // refunds.merchant_hash, scoped to the merchant
+	PurposeRecipientRef   Purpose = "recipient_ref"   // transaction recipient refs, scoped to the merchant
+)
+
+const keySize = 32
+
+// versioned so a future derivation scheme can't produce the same keys
+var salt = []byte("cryo/refs/v1")
+
+var ErrNoMasterKey = errors.New("master key is empty")
+
+type Deriver interface {
+	// key for purpose scoped to one owner (merchant id, account hash), empty owner for unscoped keys
+	Key(ctx context.Context, purpose Purpose, owner string) ([]byte, error)
+	// hmac of value under Key(purpose, owner)
+	Ref(ctx context.Context, purpose Purpose, owner string, value string, truncate utils.Truncation) (string, error)
+}
+
+type hkdfDeriver struct {
+	secret secrets.Secret
+
+	mu     sync.Mutex
+	master []byte // pinned on the first successful read
+}
+
+// master is read once and kept, later values from the secret store are ignored
+func NewDeriver(master secrets.Secret) Deriver {
+	return &hkdfDeriver{secret: master}
+}
+
+func (d *hkdfDeriver) Key(ctx context.Context, purpose Purpose, owner string) ([]byte, error) {
+	if purpose == "" {
+		return nil, errors.New("key purpose must be set")
+	}
+	master, err := d.masterKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the separator keeps ("ab", "c") and ("a", "bc") apart
+	info := []byte(string(purpose) + "\x00" + owner)
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, salt, info), key); err != nil {
+		return nil, fmt.Errorf("failed to derive %s key: %w", purpose, err)
+	}
+	return key, nil
+}
+
+func (d *hkdfDeriver) masterKey(ctx context.Context) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.master != nil {
+		return d.master, nil
+	}
+	master, err := d.secret.Value(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load master key: %w", err)
+	}
+	if master == "" {
+		return nil, ErrNoMasterKey
+	}
+	d.master = []byte(master)
+	return d.master, nil
+}
+
+func (d *hkdfDeriver) Ref(ctx context.Context, purpose Purpose, owner string, value string, truncate utils.Truncation) (string, error) {
+	key, err := d.Key(ctx, purpose, owner)
+	if err != nil {
+		return "", err
+	}
+	return utils.GenerateRef(key, value, truncate), nil
+}
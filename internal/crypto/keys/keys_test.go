@@ -0,0 +1,83 @@
+package keys
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+	utils "github.com/undersleep7x/cryo-project/internal/utils"
+)
+
+// secret whose value changes like a rotation picked up by the secret store
+type rotatingSecret struct {
+	mu    sync.Mutex
+	value string
+}
+
+func (s *rotatingSecret) set(value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value = value
+}
+
+func (s *rotatingSecret) Value(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value, nil
+}
+
+func TestDeriver(t *testing.T) {
+	ctx := context.Background()
+	deriver := NewDeriver(secrets.Static("master"))
+
+	key, err := deriver.Key(ctx, PurposeWalletOwner, "merchant-1")
+	require.NoError(t, err)
+	assert.Len(t, key, keySize)
+
+	t.Run("Deterministic", func(t *testing.T) {
+		again, err := NewDeriver(secrets.Static("master")).Key(ctx, PurposeWalletOwner, "merchant-1")
+		require.NoError(t, err)
+		assert.Equal(t, key, again)
+	})
+
+	t.Run("Scoped", func(t *testing.T) {
+		otherPurpose, _ := deriver.Key(ctx, PurposeRecipientRef, "merchant-1")
+		otherOwner, _ := deriver.Key(ctx, PurposeWalletOwner, "merchant-2")
+		otherMaster, _ := NewDeriver(secrets.Static("rotated")).Key(ctx, PurposeWalletOwner, "merchant-1")
+		assert.NotEqual(t, key, otherPurpose)
+		assert.NotEqual(t, key, otherOwner)
+		assert.NotEqual(t, key, otherMaster)
+
+		// purpose and owner can't be shifted into each other
+		shifted, _ := deriver.Key(ctx, Purpose("wallet_owne"), "rmerchant-1")
+		assert.NotEqual(t, key, shifted)
+	})
+
+	t.Run("Ref", func(t *testing.T) {
+		ref, err := deriver.Ref(ctx, PurposeWalletOwner, "merchant-1", "value", utils.TruncateHard)
+		require.NoError(t, err)
+		assert.Equal(t, utils.GenerateRef(key, "value", utils.TruncateHard), ref)
+		assert.Len(t, ref, 32)
+	})
+
+	t.Run("Master Pinned", func(t *testing.T) {
+		master := &rotatingSecret{value: "master"}
+		pinned := NewDeriver(master)
+		before, err := pinned.Key(ctx, PurposeWalletOwner, "merchant-1")
+		require.NoError(t, err)
+
+		// refs stored under the old master would no longer be found
+		master.set("rotated")
+		after, err := pinned.Key(ctx, PurposeWalletOwner, "merchant-1")
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("Empty Master", func(t *testing.T) {
+		_, err := NewDeriver(secrets.Static("")).Key(ctx, PurposeAccount, "")
+		assert.ErrorIs(t, err, ErrNoMasterKey)
+	})
+}
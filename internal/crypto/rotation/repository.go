@@ -0,0 +1,142 @@
+package rotation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	platformPostgres "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
+)
+
+// encrypted column the rotation job walks, every table is keyed by a uuid id
+type Target struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// every column written through envelope.Column. names are interpolated into sql, so targets
+// must only ever come from code. nothing writes transactions.destination_encrypted yet, it's
+// walked so any rows already in it are sealed and whatever writes it later must go through
+// envelope.Column
+var Targets = []Target{
+	{Table: "transactions", Column: "destination_encrypted"},
+	{Table: "merchants", Column: "metadata"},
+	{Table: "wallets", Column: "address"},
+}
+
+// start of every walk, sorts before any uuid
+const firstID = "00000000-0000-0000-0000-000000000000"
+
+type Row struct {
+	ID     string
+	Sealed string
+}
+
+// how far one target is through a rotation onto Version
+type Progress struct {
+	Target
+	Version       int        `json:"key_version"`
+	LastID        string     `json:"last_id"`
+	Scanned       int64      `json:"scanned"`
+	Rewrapped     int64      `json:"rewrapped"`
+	PassRewrapped int64      `json:"pass_rewrapped"` // rewrapped since the walk last started over
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type Repository interface {
+	// up to limit non null values of target after afterID, in id order
+	Batch(ctx context.Context, target Target, afterID string, limit int) ([]Row, error)
+	// swap old for new, false when the row was written since it was read
+	Replace(ctx context.Context, target Target, id string, old string, new string) (bool, error)
+
+	// nil when the rotation onto version hasn't touched target yet
+	FindProgress(ctx context.Context, version int, target Target) (*Progress, error)
+	SaveProgress(ctx context.Context, progress Progress) error
+}
+
+type repository struct {
+	db platformPostgres.Querier
+}
+
+func NewRepository(db platformPostgres.Querier) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Batch(ctx context.Context, target Target, afterID string, limit int) ([]Row, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, `+target.Column+`
+		FROM `+target.Table+`
+		WHERE id > $1 AND `+target.Column+` IS NOT NULL
+		ORDER BY id
+		LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s.%s: %w", target.Table, target.Column, err)
+	}
+	defer rows.Close()
+
+	var batch []Row
+	for rows.Next() {
+		var row Row
+		if err := rows.Scan(&row.ID, &row.Sealed); err != nil {
+			return nil, fmt.Errorf("failed to scan %s.%s: %w", target.Table, target.Column, err)
+		}
+		batch = append(batch, row)
+	}
+	return batch, rows.Err()
+}
+
+func (r *repository) Replace(ctx context.Context, target Target, id string, old string, new string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE `+target.Table+`
+		SET `+target.Column+` = $3
+		WHERE id = $1 AND `+target.Column+` = $2`,
+		id, old, new)
+	if err != nil {
+		return false, fmt.Errorf("failed to update %s.%s: %w", target.Table, target.Column, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (r *repository) FindProgress(ctx context.Context, version int, target Target) (*Progress, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT key_version, table_name, column_name, last_id, scanned, rewrapped, pass_rewrapped, completed_at, updated_at
+		FROM key_rotation_progress
+		WHERE key_version = $1 AND table_name = $2 AND column_name = $3`,
+		version, target.Table, target.Column)
+
+	var p Progress
+	err := row.Scan(&p.Version, &p.Table, &p.Column, &p.LastID, &p.Scanned, &p.Rewrapped, &p.PassRewrapped, &p.CompletedAt, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rotation progress: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *repository) SaveProgress(ctx context.Context, p Progress) error {
+	var completedAt *time.Time
+	if p.CompletedAt != nil {
+		utc := p.CompletedAt.UTC()
+		completedAt = &utc
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO key_rotation_progress (key_version, table_name, column_name, last_id, scanned, rewrapped, pass_rewrapped, completed_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (key_version, table_name, column_name) DO UPDATE
+		SET last_id = EXCLUDED.last_id, scanned = EXCLUDED.scanned, rewrapped = EXCLUDED.rewrapped,
+			pass_rewrapped = EXCLUDED.pass_rewrapped, completed_at = EXCLUDED.completed_at, updated_at = EXCLUDED.updated_at`,
+		p.Version, p.Table, p.Column, p.LastID, p.Scanned, p.Rewrapped, p.PassRewrapped, completedAt, p.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save rotation progress: %w", err)
+	}
+	return nil
+}
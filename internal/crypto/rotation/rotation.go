@@ -0,0 +1,314 @@
+// Package rotation moves encrypted columns onto the current key encryption key version.
+// `cryo keys rotate` makes a new version current in the shared keyfile, which every replica
+// picks up for new writes within envelope.ReloadInterval, and the leader's background job then rewraps the data key of every existing value,
+// leaving the ciphertext untouched. Progress is saved after every batch so the job resumes
+// where it left off across restarts and leader changes. With Config.LegacyPlaintext set,
+// plaintext written before a column was encrypted is sealed along the way, so the first run
+// after deploying seals every legacy value. An old version is only retired once every replica has had time to pick up the new
+// one and a scan finds nothing left under it.
+package rotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/undersleep7x/cryo-project/internal/crypto/envelope"
+	"github.com/undersleep7x/cryo-project/internal/metrics"
+)
+
+var (
+	ErrVersionInUse    = errors.New("key version still in use")
+	ErrRotationPending = errors.New("rotation onto the current key version hasn't settled")
+)
+
+type Config struct {
+	BatchSize     int           // rows read and rewrapped per batch
+	BatchInterval time.Duration // pause between batches so the job doesn't crowd out live traffic
+	CheckInterval time.Duration // how often the leader looks for a new current version
+	SettleDelay   time.Duration // wait after a rotation completes before an old version can be retired
+	// seal values written before the columns were encrypted. off, they're skipped as failed,
+	// since an unsealed value may as well have been planted by anyone with db access
+	LegacyPlaintext bool
+}
+
+type Status struct {
+	CurrentVersion int        `json:"current_version"`
+	Versions       []int      `json:"versions"`
+	Progress       []Progress `json:"progress"` // the rotation onto the current version, per target
+}
+
+type Rotator interface {
+	// rotate in the background until ctx is cancelled, only the leader should run this
+	Start(ctx context.Context)
+	// move every target onto the current version, resuming from saved progress
+	Run(ctx context.Context) error
+	Status(ctx context.Context) (*Status, error)
+	// count the values still under version across every target
+	Verify(ctx context.Context, version int) (int64, error)
+	// drop version from the kms, refusing until the rotation onto the current version has
+	// settled and while any value is still under it
+	Retire(ctx context.Context, version int) error
+}
+
+type rotatorImpl struct {
+	repo    Repository
+	sealer  envelope.Envelope
+	keys    envelope.KeyManager
+	targets []Target
+	config  Config
+}
+
+func NewRotator(repo Repository, sealer envelope.Envelope, keys envelope.KeyManager, cfg Config) Rotator {
+	return &rotatorImpl{repo: repo, sealer: sealer, keys: keys, targets: Targets, config: cfg}
+}
+
+func (r *rotatorImpl) Start(ctx context.Context) {
+	go func() {
+		for {
+			if err := r.Run(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Key rotation failed, retrying in %s: %v", r.config.CheckInterval, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.config.CheckInterval):
+			}
+		}
+	}()
+}
+
+func (r *rotatorImpl) Run(ctx context.Context) error {
+	version, err := r.keys.CurrentVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read current key version: %w", err)
+	}
+	for _, target := range r.targets {
+		if err := r.rotate(ctx, version, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk target in id order until a full pass finds nothing left to rewrap
+func (r *rotatorImpl) rotate(ctx context.Context, version int, target Target) error {
+	progress, err := r.progress(ctx, version, target)
+	if err != nil {
+		return err
+	}
+	if progress.CompletedAt != nil {
+		return nil
+	}
+	if progress.Scanned == 0 {
+		log.Printf("Rotating %s.%s onto key version %d", target.Table, target.Column, version)
+	}
+
+	for {
+		rows, err := r.repo.Batch(ctx, target, progress.LastID, r.config.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			// rows written by a replica that hadn't seen the new version yet, or changed under
+			// us, are caught by starting over. a pass that moves nothing verifies the target
+			if progress.PassRewrapped > 0 {
+				progress.LastID, progress.PassRewrapped = firstID, 0
+				if err := r.save(ctx, progress); err != nil {
+					return err
+				}
+				continue
+			}
+			now := time.Now().UTC()
+			progress.CompletedAt = &now
+			log.Printf("Rotated %s.%s onto key version %d: %d rewrapped of %d scanned", target.Table, target.Column, version, progress.Rewrapped, progress.Scanned)
+			return r.save(ctx, progress)
+		}
+
+		for _, row := range rows {
+			moved, err := r.rewrap(ctx, version, target, row)
+			if err != nil {
+				return err
+			}
+			if moved {
+				progress.Rewrapped++
+				progress.PassRewrapped++
+			}
+		}
+		progress.Scanned += int64(len(rows))
+		progress.LastID = rows[len(rows)-1].ID
+		if err := r.save(ctx, progress); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.config.BatchInterval):
+		}
+	}
+}
+
+// true when the row was moved or needs looking at again on the next pass
+func (r *rotatorImpl) rewrap(ctx context.Context, version int, target Target, row Row) (bool, error) {
+	rowVersion, err := envelope.KeyVersion(row.Sealed)
+	if errors.Is(err, envelope.ErrMalformed) {
+		if !r.config.LegacyPlaintext {
+			log.Printf("Skipping unsealed %s.%s for %s, security.legacy_plaintext is off", target.Table, target.Column, row.ID)
+			metrics.KeyRotationRows.WithLabelValues(target.Table, "failed").Inc()
+			return false, nil
+		}
+		return r.seal(ctx, target, row)
+	}
+	if err != nil {
+		return false, err
+	}
+	if rowVersion == version {
+		metrics.KeyRotationRows.WithLabelValues(target.Table, "current").Inc()
+		return false, nil
+	}
+
+	rewrapped, err := r.sealer.Rewrap(ctx, row.Sealed)
+	if errors.Is(err, envelope.ErrUnknownVersion) || errors.Is(err, envelope.ErrDecrypt) {
+		// the key is gone or the value is damaged, retrying won't help
+		log.Printf("Skipping %s.%s for %s: %v", target.Table, target.Column, row.ID, err)
+		metrics.KeyRotationRows.WithLabelValues(target.Table, "failed").Inc()
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return r.replace(ctx, target, row, rewrapped, "rewrapped")
+}
+
+// plaintext written before the column was encrypted, sealed under the current version the
+// same way envelope.Column would have written it
+func (r *rotatorImpl) seal(ctx context.Context, target Target, row Row) (bool, error) {
+	column := envelope.NewColumn(r.sealer, target.Table, target.Column)
+	sealed, err := r.sealer.Seal(ctx, []byte(row.Sealed), column.AAD(row.ID))
+	if err != nil {
+		return false, fmt.Errorf("failed to seal %s.%s for %s: %w", target.Table, target.Column, row.ID, err)
+	}
+	return r.replace(ctx, target, row, sealed, "sealed")
+}
+
+func (r *rotatorImpl) replace(ctx context.Context, target Target, row Row, value string, outcome string) (bool, error) {
+	replaced, err := r.repo.Replace(ctx, target, row.ID, row.Sealed, value)
+	if err != nil {
+		return false, err
+	}
+	if !replaced {
+		metrics.KeyRotationRows.WithLabelValues(target.Table, "conflict").Inc()
+		return true, nil
+	}
+	metrics.KeyRotationRows.WithLabelValues(target.Table, outcome).Inc()
+	return true, nil
+}
+
+func (r *rotatorImpl) Status(ctx context.Context) (*Status, error) {
+	version, err := r.keys.CurrentVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current key version: %w", err)
+	}
+	versions, err := r.keys.Versions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key versions: %w", err)
+	}
+
+	status := &Status{CurrentVersion: version, Versions: versions}
+	for _, target := range r.targets {
+		progress, err := r.progress(ctx, version, target)
+		if err != nil {
+			return nil, err
+		}
+		status.Progress = append(status.Progress, *progress)
+	}
+	return status, nil
+}
+
+func (r *rotatorImpl) Verify(ctx context.Context, version int) (int64, error) {
+	var remaining int64
+	for _, target := range r.targets {
+		afterID := firstID
+		for {
+			rows, err := r.repo.Batch(ctx, target, afterID, r.config.BatchSize)
+			if err != nil {
+				return 0, err
+			}
+			if len(rows) == 0 {
+				break
+			}
+			for _, row := range rows {
+				if rowVersion, err := envelope.KeyVersion(row.Sealed); err == nil && rowVersion == version {
+					remaining++
+				}
+			}
+			afterID = rows[len(rows)-1].ID
+		}
+	}
+	return remaining, nil
+}
+
+func (r *rotatorImpl) Retire(ctx context.Context, version int) error {
+	current, err := r.keys.CurrentVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read current key version: %w", err)
+	}
+	if version == current {
+		return envelope.ErrCurrentVersion
+	}
+	if err := r.settled(ctx, current); err != nil {
+		return err
+	}
+	remaining, err := r.Verify(ctx, version)
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return fmt.Errorf("%w: %d values under version %d", ErrVersionInUse, remaining, version)
+	}
+	if err := r.keys.RetireVersion(ctx, version); err != nil {
+		return err
+	}
+	log.Printf("Retired key version %d", version)
+	return nil
+}
+
+// every target finished rotating onto current long enough ago that no replica can still be
+// wrapping under an older version, otherwise a value written after the verifying scan would
+// be lost with the retired key
+func (r *rotatorImpl) settled(ctx context.Context, current int) error {
+	settle := max(r.config.SettleDelay, 2*envelope.ReloadInterval())
+	for _, target := range r.targets {
+		progress, err := r.progress(ctx, current, target)
+		if err != nil {
+			return err
+		}
+		if progress.CompletedAt == nil {
+			return fmt.Errorf("%w: %s.%s is still rotating onto version %d", ErrRotationPending, target.Table, target.Column, current)
+		}
+		if since := time.Since(*progress.CompletedAt); since < settle {
+			return fmt.Errorf("%w: %s.%s finished %s ago, retire after %s", ErrRotationPending, target.Table, target.Column, since.Round(time.Second), settle)
+		}
+	}
+	return nil
+}
+
+// saved progress for target, or a fresh walk when the rotation hasn't reached it yet
+func (r *rotatorImpl) progress(ctx context.Context, version int, target Target) (*Progress, error) {
+	progress, err := r.repo.FindProgress(ctx, version, target)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		progress = &Progress{Target: target, Version: version, LastID: firstID}
+	}
+	return progress, nil
+}
+
+func (r *rotatorImpl) save(ctx context.Context, progress *Progress) error {
+	progress.UpdatedAt = time.Now().UTC()
+	return r.repo.SaveProgress(ctx, *progress)
+}
@@ -0,0 +1,327 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/internal/crypto/envelope"
+)
+
+type progressKey struct {
+	version int
+	target  Target
+}
+
+// in-memory tables of sealed values keyed by row id
+type mockRepository struct {
+	mu        sync.Mutex
+	values    map[Target]map[string]string
+	progress  map[progressKey]Progress
+	failAfter int // Batch calls allowed before failing, 0 never fails
+	batches   int
+	onReplace func(target Target, id string) // runs before each replace, used to simulate concurrent writes
+}
+
+func newMockRepository() *mockRepository {
+	return &mockRepository{values: make(map[Target]map[string]string), progress: make(map[progressKey]Progress)}
+}
+
+func (m *mockRepository) put(target Target, id string, sealed string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values[target] == nil {
+		m.values[target] = make(map[string]string)
+	}
+	m.values[target][id] = sealed
+}
+
+func (m *mockRepository) get(target Target, id string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[target][id]
+}
+
+func (m *mockRepository) Batch(ctx context.Context, target Target, afterID string, limit int) ([]Row, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches++
+	if m.failAfter > 0 && m.batches > m.failAfter {
+		return nil, errors.New("connection reset")
+	}
+	var rows []Row
+	for id, sealed := range m.values[target] {
+		if id > afterID {
+			rows = append(rows, Row{ID: id, Sealed: sealed})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+func (m *mockRepository) Replace(ctx context.Context, target Target, id string, old string, new string) (bool, error) {
+	if m.onReplace != nil {
+		m.onReplace(target, id)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values[target][id] != old {
+		return false, nil
+	}
+	m.values[target][id] = new
+	return true, nil
+}
+
+func (m *mockRepository) FindProgress(ctx context.Context, version int, target Target) (*Progress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	progress, ok := m.progress[progressKey{version, target}]
+	if !ok {
+		return nil, nil
+	}
+	return &progress, nil
+}
+
+func (m *mockRepository) SaveProgress(ctx context.Context, progress Progress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progress[progressKey{progress.Version, progress.Target}] = progress
+	return nil
+}
+
+type testRotation struct {
+	rotator Rotator
+	repo    *mockRepository
+	keys    envelope.KeyManager
+	sealer  envelope.Envelope
+	keyfile string
+	ids     map[Target][]string
+}
+
+// seeds rows values under each target, all sealed under key version 1
+func newTestRotation(t *testing.T, rows int) *testRotation {
+	t.Helper()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, envelope.GenerateKeyfile(path))
+	keys, err := envelope.NewLocalKMS(path)
+	require.NoError(t, err)
+	sealer := envelope.New(keys)
+
+	tr := &testRotation{repo: newMockRepository(), keys: keys, sealer: sealer, keyfile: path, ids: make(map[Target][]string)}
+	for _, target := range Targets {
+		column := envelope.NewColumn(sealer, target.Table, target.Column)
+		for i := 0; i < rows; i++ {
+			id, plaintext := uuid.NewString(), "secret-"+target.Table
+			value, err := column.Value(ctx, id, &plaintext).Value()
+			require.NoError(t, err)
+			tr.repo.put(target, id, value.(string))
+			tr.ids[target] = append(tr.ids[target], id)
+		}
+	}
+	tr.rotator = NewRotator(tr.repo, sealer, keys, Config{BatchSize: 2, SettleDelay: time.Minute})
+	return tr
+}
+
+// backdate every completed rotation onto version past the settle delay
+func (tr *testRotation) settle(version int) {
+	tr.repo.mu.Lock()
+	defer tr.repo.mu.Unlock()
+	for key, progress := range tr.repo.progress {
+		if key.version == version && progress.CompletedAt != nil {
+			completedAt := progress.CompletedAt.Add(-time.Hour)
+			progress.CompletedAt = &completedAt
+			tr.repo.progress[key] = progress
+		}
+	}
+}
+
+// every value opens under its row id and sits on version
+func (tr *testRotation) assertAllOn(t *testing.T, version int) {
+	t.Helper()
+	for target, ids := range tr.ids {
+		column := envelope.NewColumn(tr.sealer, target.Table, target.Column)
+		for _, id := range ids {
+			sealed := tr.repo.get(target, id)
+			rowVersion, err := envelope.KeyVersion(sealed)
+			require.NoError(t, err)
+			assert.Equal(t, version, rowVersion, "%s.%s %s", target.Table, target.Column, id)
+
+			var opened *string
+			rowID := id
+			require.NoError(t, column.Scan(context.Background(), &rowID, &opened).Scan(sealed))
+			assert.Equal(t, "secret-"+target.Table, *opened)
+		}
+	}
+}
+
+func TestRotation(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRotation(t, 5)
+
+	require.NoError(t, tr.rotator.Run(ctx))
+	for _, progress := range tr.repo.progress {
+		assert.Zero(t, progress.Rewrapped) // nothing to move before a rotation
+	}
+
+	version, err := tr.keys.AddVersion(ctx)
+	require.NoError(t, err)
+	assert.ErrorIs(t, tr.rotator.Retire(ctx, 1), ErrRotationPending)
+
+	require.NoError(t, tr.rotator.Run(ctx))
+	tr.assertAllOn(t, version)
+
+	status, err := tr.rotator.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, version, status.CurrentVersion)
+	assert.Equal(t, []int{1, version}, status.Versions)
+	require.Len(t, status.Progress, len(Targets))
+	for _, progress := range status.Progress {
+		assert.NotNil(t, progress.CompletedAt)
+		assert.EqualValues(t, 5, progress.Rewrapped)
+		assert.EqualValues(t, 10, progress.Scanned) // the verifying pass reads every row again
+	}
+
+	remaining, err := tr.rotator.Verify(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, remaining)
+	assert.ErrorIs(t, tr.rotator.Retire(ctx, version), envelope.ErrCurrentVersion)
+	// replicas that haven't reloaded the keyfile yet could still be writing under version 1
+	assert.ErrorIs(t, tr.rotator.Retire(ctx, 1), ErrRotationPending)
+	tr.settle(version)
+	require.NoError(t, tr.rotator.Retire(ctx, 1))
+	versions, err := tr.keys.Versions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{version}, versions)
+	tr.assertAllOn(t, version)
+}
+
+func TestRotationResumes(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRotation(t, 5)
+	_, err := tr.keys.AddVersion(ctx)
+	require.NoError(t, err)
+
+	tr.repo.failAfter = 2
+	assert.Error(t, tr.rotator.Run(ctx))
+	first := Targets[0]
+	progress, err := tr.repo.FindProgress(ctx, 2, first)
+	require.NoError(t, err)
+	require.NotNil(t, progress)
+	assert.EqualValues(t, 4, progress.Scanned)
+	assert.Nil(t, progress.CompletedAt)
+
+	// picks up after the last saved batch rather than starting over
+	tr.repo.failAfter = 0
+	require.NoError(t, tr.rotator.Run(ctx))
+	progress, err = tr.repo.FindProgress(ctx, 2, first)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, progress.Rewrapped)
+	assert.EqualValues(t, 10, progress.Scanned)
+	tr.assertAllOn(t, 2)
+}
+
+func TestRotationConcurrentWrite(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRotation(t, 3)
+	// a replica that hasn't seen version 2 yet rewrites a row while the job is reading it
+	raw, err := os.ReadFile(tr.keyfile)
+	require.NoError(t, err)
+	stalePath := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(stalePath, raw, 0o600))
+	staleKeys, err := envelope.NewLocalKMS(stalePath)
+	require.NoError(t, err)
+	_, err = tr.keys.AddVersion(ctx)
+	require.NoError(t, err)
+
+	stale := envelope.NewColumn(envelope.New(staleKeys), "merchants", "metadata")
+	target := Target{Table: "merchants", Column: "metadata"}
+	rewritten := tr.ids[target][0]
+	tr.repo.onReplace = func(replaced Target, id string) {
+		if replaced == target && id == rewritten {
+			tr.repo.onReplace = nil
+			plaintext := "secret-merchants"
+			value, err := stale.Value(ctx, id, &plaintext).Value()
+			require.NoError(t, err)
+			tr.repo.put(target, id, value.(string))
+		}
+	}
+
+	require.NoError(t, tr.rotator.Run(ctx))
+	tr.assertAllOn(t, 2) // the extra pass catches the stale write
+}
+
+func TestRotationSealsPlaintext(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRotation(t, 2)
+	tr.rotator = NewRotator(tr.repo, tr.sealer, tr.keys, Config{BatchSize: 2, SettleDelay: time.Minute, LegacyPlaintext: true})
+	// rows written before the columns were encrypted, sealed by the first run on version 1
+	for _, target := range Targets {
+		id := uuid.NewString()
+		tr.repo.put(target, id, "secret-"+target.Table)
+		tr.ids[target] = append(tr.ids[target], id)
+	}
+
+	require.NoError(t, tr.rotator.Run(ctx))
+	tr.assertAllOn(t, 1)
+	for _, target := range Targets {
+		progress, err := tr.repo.FindProgress(ctx, 1, target)
+		require.NoError(t, err)
+		assert.NotNil(t, progress.CompletedAt)
+		assert.EqualValues(t, 1, progress.Rewrapped)
+	}
+
+	_, err := tr.keys.AddVersion(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tr.rotator.Run(ctx))
+	tr.assertAllOn(t, 2)
+	tr.settle(2)
+	require.NoError(t, tr.rotator.Retire(ctx, 1))
+}
+
+func TestRotationSkipsPlaintextWithoutLegacyFlag(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRotation(t, 2)
+	target := Target{Table: "wallets", Column: "address"}
+	id := uuid.NewString()
+	tr.repo.put(target, id, "planted-address")
+
+	require.NoError(t, tr.rotator.Run(ctx))
+	assert.Equal(t, "planted-address", tr.repo.get(target, id)) // left unsealed, never made authentic
+	tr.assertAllOn(t, 1)
+}
+
+func TestRetireVersionInUse(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRotation(t, 2)
+	raw, err := os.ReadFile(tr.keyfile)
+	require.NoError(t, err)
+	stalePath := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(stalePath, raw, 0o600))
+	staleKeys, err := envelope.NewLocalKMS(stalePath)
+	require.NoError(t, err)
+	_, err = tr.keys.AddVersion(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tr.rotator.Run(ctx))
+	tr.settle(2)
+
+	// written under version 1 after the rotation finished, by a replica that never reloaded
+	target := Target{Table: "wallets", Column: "address"}
+	id, plaintext := uuid.NewString(), "secret-wallets"
+	value, err := envelope.NewColumn(envelope.New(staleKeys), target.Table, target.Column).Value(ctx, id, &plaintext).Value()
+	require.NoError(t, err)
+	tr.repo.put(target, id, value.(string))
+
+	assert.ErrorIs(t, tr.rotator.Retire(ctx, 1), ErrVersionInUse)
+}
@@ -0,0 +1,24 @@
+package cache
+
+import (
+	"context"
+
+	platformRedis "github.com/undersleep7x/cryo-project/internal/platform/redisstore"
+)
+
+// redis pub/sub fan-out for price updates so every replica sees changes polled by any other
+type PriceBroker struct {
+	Redis platformRedis.RedisClient
+}
+
+func NewPriceBroker(client platformRedis.RedisClient) *PriceBroker {
+	return &PriceBroker{Redis: client}
+}
+
+func (b *PriceBroker) Publish(ctx context.Context, channel string, payload string) error {
+	return b.Redis.Publish(ctx, channel, payload)
+}
+
+func (b *PriceBroker) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	return b.Redis.Subscribe(ctx, channel)
+}
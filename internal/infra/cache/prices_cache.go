@@ -3,9 +3,16 @@ package cache
 import (
 	"context"
 	"time"
+
 	platformRedis "github.com/undersleep7x/cryo-project/internal/platform/redisstore"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const tracerName = "github.com/undersleep7x/cryo-project/internal/infra/cache"
+
 type PriceCache struct {
 	Redis platformRedis.RedisClient
 }
@@ -15,9 +22,40 @@ func NewPriceCache (client platformRedis.RedisClient) *PriceCache {
 }
 
 func (c *PriceCache) GetCachedPrices(ctx context.Context, cacheKey string) (string, error) {
-	return c.Redis.Get(ctx, cacheKey)
+	ctx, span := startCacheSpan(ctx, "GET", cacheKey)
+	value, err := c.Redis.Get(ctx, cacheKey)
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+	endCacheSpan(span, err)
+	return value, err
 }
 
 func (c *PriceCache) CachePrices(ctx context.Context, cacheKey string, value interface{}, ttl time.Duration) error {
-	return c.Redis.Set(ctx, cacheKey, value, ttl)
-}
\ No newline at end of file
+	ctx, span := startCacheSpan(ctx, "SET", cacheKey)
+	err := c.Redis.Set(ctx, cacheKey, value, ttl)
+	endCacheSpan(span, err)
+	return err
+}
+
+// remaining time to live, 0 for keys without an expiry
+func (c *PriceCache) TTL(ctx context.Context, cacheKey string) (time.Duration, error) {
+	ctx, span := startCacheSpan(ctx, "PTTL", cacheKey)
+	ttl, err := c.Redis.PTTL(ctx, cacheKey)
+	endCacheSpan(span, err)
+	return ttl, err
+}
+
+func startCacheSpan(ctx context.Context, operation string, cacheKey string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "redis "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "redis"), attribute.String("cache.key", cacheKey)),
+	)
+}
+
+// a miss is a normal outcome, not a span error
+func endCacheSpan(span trace.Span, err error) {
+	if err != nil && err != ErrCacheMiss {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
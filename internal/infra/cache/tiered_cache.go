@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	redis "github.com/redis/go-redis/v9"
+)
+
+// returned on a miss in every tier, matches redis so callers handle both the same way
+var ErrCacheMiss = redis.Nil
+
+const (
+	invalidationChannel = "prices:invalidate"
+
+	maxInvalidationSubscribeBackoff = 30 * time.Second
+)
+
+// first retry delay after the invalidation subscription fails or drops, doubled up to maxInvalidationSubscribeBackoff
+var invalidationSubscribeBackoff = time.Second
+
+type TieredCacheConfig struct {
+	L1Size int           // max entries held in memory
+	L1TTL  time.Duration // upper bound on how long an entry is served from memory before rechecking redis
+}
+
+// in-process lru layered over the redis backed PriceCache. writes are published on redis
+// so other replicas drop their in-memory copy, and with no redis it runs memory-only
+type TieredPriceCache struct {
+	l1         *lruCache
+	l2         *PriceCache // nil in memory-only mode
+	config     TieredCacheConfig
+	instanceID string // tags invalidations so a replica ignores its own
+}
+
+type invalidation struct {
+	Key    string `json:"key"`
+	Origin string `json:"origin"`
+}
+
+func NewTieredPriceCache(l2 *PriceCache, cfg TieredCacheConfig) *TieredPriceCache {
+	if cfg.L1Size <= 0 {
+		cfg.L1Size = 1024
+	}
+	if cfg.L1TTL <= 0 {
+		cfg.L1TTL = 5 * time.Second
+	}
+	return &TieredPriceCache{l1: newLRUCache(cfg.L1Size), l2: l2, config: cfg, instanceID: uuid.NewString()}
+}
+
+// memory-only cache for running without redis
+func NewMemoryPriceCache(cfg TieredCacheConfig) *TieredPriceCache {
+	return NewTieredPriceCache(nil, cfg)
+}
+
+func (c *TieredPriceCache) MemoryOnly() bool {
+	return c.l2 == nil
+}
+
+func (c *TieredPriceCache) GetCachedPrices(ctx context.Context, cacheKey string) (string, error) {
+	if value, ok := c.l1.Get(cacheKey); ok {
+		return value, nil
+	}
+	if c.l2 == nil {
+		return "", ErrCacheMiss
+	}
+
+	value, err := c.l2.GetCachedPrices(ctx, cacheKey)
+	if err != nil {
+		return "", err
+	}
+	// never kept in memory past the redis expiry, a failed lookup just skips memory this time
+	ttl, err := c.l2.TTL(ctx, cacheKey)
+	if err != nil {
+		return value, nil
+	}
+	l1TTL := c.config.L1TTL
+	if ttl > 0 {
+		l1TTL = min(l1TTL, ttl)
+	}
+	c.l1.Set(cacheKey, value, l1TTL)
+	return value, nil
+}
+
+func (c *TieredPriceCache) CachePrices(ctx context.Context, cacheKey string, value interface{}, ttl time.Duration) error {
+	stored := toCacheString(value)
+	if c.l2 == nil { // memory is the only tier, so honor the full ttl
+		c.l1.Set(cacheKey, stored, ttl)
+		return nil
+	}
+
+	c.l1.Set(cacheKey, stored, min(ttl, c.config.L1TTL))
+	if err := c.l2.CachePrices(ctx, cacheKey, stored, ttl); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(invalidation{Key: cacheKey, Origin: c.instanceID})
+	if err := c.l2.Redis.Publish(ctx, invalidationChannel, string(payload)); err != nil {
+		log.Printf("Failed to publish cache invalidation for %s: %v", cacheKey, err)
+	}
+	return nil
+}
+
+// listen for writes from other replicas and evict the matching in-memory entries until ctx is
+// done. a failed or dropped subscription is retried with backoff, and memory is flushed every
+// time it's (re)established since invalidations published in between were missed
+func (c *TieredPriceCache) StartInvalidationListener(ctx context.Context) {
+	if c.l2 == nil {
+		return
+	}
+	go c.listen(ctx)
+}
+
+func (c *TieredPriceCache) listen(ctx context.Context) {
+	backoff := invalidationSubscribeBackoff
+	for {
+		msgs, err := c.l2.Redis.Subscribe(ctx, invalidationChannel)
+		if err != nil {
+			log.Printf("Failed to subscribe to cache invalidations, retrying in %s: %v", backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxInvalidationSubscribeBackoff)
+			continue
+		}
+		backoff = invalidationSubscribeBackoff
+		c.l1.Clear()
+
+		c.evict(msgs)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Cache invalidation subscription ended, resubscribing")
+	}
+}
+
+// drop the in-memory entries other replicas wrote until the subscription ends
+func (c *TieredPriceCache) evict(msgs <-chan string) {
+	for msg := range msgs {
+		var inv invalidation
+		if err := json.Unmarshal([]byte(msg), &inv); err != nil {
+			log.Printf("Failed to parse cache invalidation: %v", err)
+			continue
+		}
+		if inv.Origin != c.instanceID {
+			c.l1.Delete(inv.Key)
+		}
+	}
+}
+
+func toCacheString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/undersleep7x/cryo-project/internal/platform/redisstore/redisfake"
+)
+
+func TestTieredPriceCache(t *testing.T) {
+	ctx := context.Background()
+	cfg := TieredCacheConfig{L1Size: 2, L1TTL: time.Minute}
+
+	t.Run("L1 Hit Skips Redis", func(t *testing.T) {
+		redisClient := redisfake.New()
+		c := NewTieredPriceCache(NewPriceCache(redisClient), cfg)
+
+		assert.NoError(t, c.CachePrices(ctx, "prices:bitcoin:usd", []byte(`{"price":1}`), 30*time.Second))
+		value, err := c.GetCachedPrices(ctx, "prices:bitcoin:usd")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"price":1}`, value)
+		assert.Equal(t, 0, redisClient.Count("Get"))
+	})
+
+	t.Run("L1 Miss Falls Through To Redis", func(t *testing.T) {
+		redisClient := redisfake.New()
+		_ = redisClient.Set(ctx, "prices:bitcoin:usd", `{"price":2}`, 0)
+		c := NewTieredPriceCache(NewPriceCache(redisClient), cfg)
+
+		value, err := c.GetCachedPrices(ctx, "prices:bitcoin:usd")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"price":2}`, value)
+		_, _ = c.GetCachedPrices(ctx, "prices:bitcoin:usd")
+		assert.Equal(t, 1, redisClient.Count("Get"))
+
+		_, err = c.GetCachedPrices(ctx, "prices:ethereum:usd")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+
+	t.Run("LRU Evicts Oldest", func(t *testing.T) {
+		c := NewMemoryPriceCache(cfg)
+		assert.NoError(t, c.CachePrices(ctx, "a", "1", time.Minute))
+		assert.NoError(t, c.CachePrices(ctx, "b", "2", time.Minute))
+		_, _ = c.GetCachedPrices(ctx, "a") // a becomes most recently used
+		assert.NoError(t, c.CachePrices(ctx, "c", "3", time.Minute))
+
+		_, err := c.GetCachedPrices(ctx, "b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		value, err := c.GetCachedPrices(ctx, "a")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", value)
+	})
+
+	t.Run("Memory Only Expiry", func(t *testing.T) {
+		c := NewMemoryPriceCache(cfg)
+		now := time.Now()
+		c.l1.now = func() time.Time { return now }
+		assert.True(t, c.MemoryOnly())
+
+		assert.NoError(t, c.CachePrices(ctx, "prices:bitcoin:usd", "1", 30*time.Second))
+		c.l1.now = func() time.Time { return now.Add(31 * time.Second) }
+		_, err := c.GetCachedPrices(ctx, "prices:bitcoin:usd")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+
+	t.Run("Invalidation Across Replicas", func(t *testing.T) {
+		redisClient := redisfake.New()
+		replicaA := NewTieredPriceCache(NewPriceCache(redisClient), cfg)
+		replicaB := NewTieredPriceCache(NewPriceCache(redisClient), cfg)
+		listenCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		replicaB.StartInvalidationListener(listenCtx)
+
+		// replica b caches the old value in memory
+		_ = redisClient.Set(ctx, "prices:bitcoin:usd", "old", 0)
+		_, _ = replicaB.GetCachedPrices(ctx, "prices:bitcoin:usd")
+
+		assert.NoError(t, replicaA.CachePrices(ctx, "prices:bitcoin:usd", "new", 30*time.Second))
+		assert.Eventually(t, func() bool {
+			value, _ := replicaB.GetCachedPrices(ctx, "prices:bitcoin:usd")
+			return value == "new"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Resubscribes After A Dropped Connection", func(t *testing.T) {
+		defer func(backoff time.Duration) { invalidationSubscribeBackoff = backoff }(invalidationSubscribeBackoff)
+		invalidationSubscribeBackoff = 100 * time.Millisecond
+
+		redisClient := redisfake.New()
+		replicaA := NewTieredPriceCache(NewPriceCache(redisClient), cfg)
+		replicaB := NewTieredPriceCache(NewPriceCache(redisClient), cfg)
+		listenCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		replicaB.StartInvalidationListener(listenCtx)
+		assert.Eventually(t, func() bool { return redisClient.Count("Subscribe") == 1 }, time.Second, time.Millisecond)
+
+		_ = redisClient.Set(ctx, "prices:bitcoin:usd", "old", 0)
+		_, _ = replicaB.GetCachedPrices(ctx, "prices:bitcoin:usd")
+
+		// the first resubscribe fails, and the write lands while replica b isn't listening
+		redisClient.Fail("Subscribe", errors.New("connection refused"), 1)
+		redisClient.Disconnect()
+		assert.Eventually(t, func() bool { return redisClient.Count("Subscribe") == 2 }, time.Second, time.Millisecond)
+		assert.NoError(t, replicaA.CachePrices(ctx, "prices:bitcoin:usd", "missed", 30*time.Second))
+
+		// memory is flushed once the subscription is back, so the missed write is picked up
+		assert.Eventually(t, func() bool {
+			value, _ := replicaB.GetCachedPrices(ctx, "prices:bitcoin:usd")
+			return value == "missed"
+		}, time.Second, 10*time.Millisecond)
+
+		// and later writes are delivered again
+		assert.NoError(t, replicaA.CachePrices(ctx, "prices:bitcoin:usd", "new", 30*time.Second))
+		assert.Eventually(t, func() bool {
+			value, _ := replicaB.GetCachedPrices(ctx, "prices:bitcoin:usd")
+			return value == "new"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("L1 Never Outlives Redis", func(t *testing.T) {
+		redisClient := redisfake.New()
+		now := time.Now()
+		redisClient.SetClock(func() time.Time { return now })
+		_ = redisClient.Set(ctx, "prices:bitcoin:usd", "1", 2*time.Second)
+		c := NewTieredPriceCache(NewPriceCache(redisClient), cfg)
+		c.l1.now = func() time.Time { return now }
+
+		value, err := c.GetCachedPrices(ctx, "prices:bitcoin:usd")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", value)
+
+		// well inside the one minute L1TTL, but redis has expired the key
+		later := now.Add(3 * time.Second)
+		redisClient.SetClock(func() time.Time { return later })
+		c.l1.now = func() time.Time { return later }
+		_, err = c.GetCachedPrices(ctx, "prices:bitcoin:usd")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+		assert.Equal(t, 2, redisClient.Count("Get"))
+	})
+}
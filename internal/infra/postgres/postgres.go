@@ -1,29 +1,90 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"github.com/undersleep7x/cryo-project/internal/config"
+	"strings"
+	"time"
+
 	_ "github.com/lib/pq" // postgres driver
+	"github.com/undersleep7x/cryo-project/internal/config"
 )
 
-// NewPostgresClient sets up a raw *sql.DB instance
-func NewPostgresClient(cfg config.DBConfig) (*sql.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name,
-	)
+const maxConnectBackoff = 30 * time.Second
 
-	db, err := sql.Open("postgres", dsn)
+// NewPostgresClient sets up a pooled *sql.DB, retrying the first connection with backoff
+// so the app survives postgres starting slower than it does
+func NewPostgresClient(ctx context.Context, cfg config.DBConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", buildDSN(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open postgres connection: %w", err)
 	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	backoff := cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			break
+		}
+		if attempt >= cfg.ConnectRetries {
+			db.Close()
+			return nil, fmt.Errorf("failed to ping postgres after %d attempts: %w", attempt, err)
+		}
+		log.Printf("Postgres not ready (attempt %d/%d), retrying in %s: %v", attempt, cfg.ConnectRetries, backoff, err)
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
 	}
 
 	log.Println("Postgres connected successfully")
 	return db, nil
 }
+
+// key=value dsn for lib/pq. statement_timeout is passed through as a session setting,
+// so postgres cancels any single statement that runs longer
+func buildDSN(cfg config.DBConfig) string {
+	params := [][2]string{
+		{"host", cfg.Host},
+		{"port", fmt.Sprint(cfg.Port)},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Name},
+		{"sslmode", cfg.SSLMode},
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+	}
+	if cfg.ConnectTimeout > 0 {
+		params = append(params, [2]string{"connect_timeout", fmt.Sprint(int(cfg.ConnectTimeout / time.Second))})
+	}
+	if cfg.StatementTimeout > 0 {
+		params = append(params, [2]string{"statement_timeout", fmt.Sprint(cfg.StatementTimeout.Milliseconds())})
+	}
+
+	var parts []string
+	for _, param := range params {
+		if param[1] == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", param[0], quoteDSNValue(param[1])))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quote values so passwords with spaces or quotes survive the key=value format
+func quoteDSNValue(val string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(val)
+	return "'" + escaped + "'"
+}
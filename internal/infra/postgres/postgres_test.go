@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/undersleep7x/cryo-project/internal/config"
+)
+
+func TestBuildDSN(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		dsn := buildDSN(config.Defaults().DB)
+		assert.Equal(t, "host='postgres' port='5432' user='cryouser' password='cryopass' dbname='cryo' sslmode='disable' connect_timeout='5' statement_timeout='30000'", dsn)
+	})
+
+	t.Run("TLS And Quoting", func(t *testing.T) {
+		cfg := config.Defaults().DB
+		cfg.Password = `it's a \secret`
+		cfg.SSLMode = "verify-full"
+		cfg.SSLRootCert = "/etc/ssl/ca.pem"
+		cfg.StatementTimeout = 0
+		cfg.ConnectTimeout = 2 * time.Second
+
+		dsn := buildDSN(cfg)
+		assert.Contains(t, dsn, `password='it\'s a \\secret'`)
+		assert.Contains(t, dsn, "sslmode='verify-full' sslrootcert='/etc/ssl/ca.pem'")
+		assert.Contains(t, dsn, "connect_timeout='2'")
+		assert.NotContains(t, dsn, "statement_timeout")
+		assert.NotContains(t, dsn, "sslcert")
+	})
+}
@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+)
+
+// NewRedisClient builds a standalone, sentinel or cluster client from config. connections
+// are dialed lazily, so nothing here talks to redis
+func NewRedisClient(ctx context.Context, cfg config.RedisConfig, password secrets.Secret, sentinelPassword string) (goredis.UniversalClient, error) {
+	tlsConfig, err := buildTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// read per connection so a rotated password is picked up without a restart
+	credentials := func(ctx context.Context) (string, string, error) {
+		redisPassword, err := password.Value(ctx)
+		return cfg.Username, redisPassword, err
+	}
+
+	switch cfg.Mode {
+	case "cluster":
+		return goredis.NewClusterClient(&goredis.ClusterOptions{
+			Addrs:                      cfg.Addrs,
+			CredentialsProviderContext: credentials,
+			TLSConfig:                  tlsConfig,
+			PoolSize:                   cfg.PoolSize,
+			DialTimeout:                cfg.DialTimeout,
+			ReadTimeout:                cfg.ReadTimeout,
+			WriteTimeout:               cfg.WriteTimeout,
+		}), nil
+	case "sentinel":
+		// failover clients have no credentials provider, so the password is resolved once
+		redisPassword, err := password.Value(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve redis password: %w", err)
+		}
+		return goredis.NewFailoverClient(&goredis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.Addrs,
+			SentinelUsername: cfg.SentinelUsername,
+			SentinelPassword: sentinelPassword,
+			Username:         cfg.Username,
+			Password:         redisPassword,
+			DB:               cfg.DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         cfg.PoolSize,
+			DialTimeout:      cfg.DialTimeout,
+			ReadTimeout:      cfg.ReadTimeout,
+			WriteTimeout:     cfg.WriteTimeout,
+		}), nil
+	default:
+		return goredis.NewClient(&goredis.Options{
+			Addr:                       cfg.Addr(),
+			DB:                         cfg.DB,
+			CredentialsProviderContext: credentials,
+			TLSConfig:                  tlsConfig,
+			PoolSize:                   cfg.PoolSize,
+			DialTimeout:                cfg.DialTimeout,
+			ReadTimeout:                cfg.ReadTimeout,
+			WriteTimeout:               cfg.WriteTimeout,
+		}), nil
+	}
+}
+
+// nil when tls is off. server name is left empty unless configured so each node is
+// verified against the host it was dialed on
+func buildTLSConfig(cfg config.RedisConfig) (*tls.Config, error) {
+	if !cfg.TLS {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: cfg.TLSServerName}
+	if cfg.TLSCACert != "" {
+		pem, err := os.ReadFile(cfg.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis ca cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+)
+
+func TestNewRedisClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Client Per Mode", func(t *testing.T) {
+		cfg := config.Defaults().Redis
+		client, err := NewRedisClient(ctx, cfg, secrets.Static("pass"), "")
+		assert.NoError(t, err)
+		assert.IsType(t, &goredis.Client{}, client)
+		assert.Equal(t, "localhost:6379", client.(*goredis.Client).Options().Addr)
+		_ = client.Close()
+
+		cfg.Mode = "cluster"
+		cfg.Addrs = []string{"node-1:6379", "node-2:6379"}
+		client, err = NewRedisClient(ctx, cfg, secrets.Static("pass"), "")
+		assert.NoError(t, err)
+		assert.IsType(t, &goredis.ClusterClient{}, client)
+		_ = client.Close()
+
+		cfg.Mode = "sentinel"
+		cfg.MasterName = "mymaster"
+		client, err = NewRedisClient(ctx, cfg, secrets.Static("pass"), "sentinel-pass")
+		assert.NoError(t, err)
+		assert.Equal(t, "pass", client.(*goredis.Client).Options().Password)
+		_ = client.Close()
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		cfg := config.Defaults().Redis
+		tlsConfig, err := buildTLSConfig(cfg)
+		assert.NoError(t, err)
+		assert.Nil(t, tlsConfig)
+
+		cfg.TLS = true
+		cfg.TLSServerName = "redis.internal"
+		tlsConfig, err = buildTLSConfig(cfg)
+		assert.NoError(t, err)
+		assert.Equal(t, "redis.internal", tlsConfig.ServerName)
+		assert.Nil(t, tlsConfig.RootCAs)
+
+		cfg.TLSCACert = filepath.Join(t.TempDir(), "ca.pem")
+		assert.NoError(t, os.WriteFile(cfg.TLSCACert, []byte("not a certificate"), 0o600))
+		_, err = buildTLSConfig(cfg)
+		assert.ErrorContains(t, err, "no certificates found")
+	})
+}
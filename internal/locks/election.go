@@ -0,0 +1,94 @@
+package locks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+type Elector interface {
+	// campaign for leadership until ctx is done. lead is called with a context that is
+	// cancelled when leadership is lost, so it should start its workers on that context
+	Run(ctx context.Context, lead func(ctx context.Context))
+	IsLeader() bool
+	// fencing token of the current term, 0 when not leading
+	Token() int64
+}
+
+type electorImpl struct {
+	locker        Locker
+	name          string
+	ttl           time.Duration
+	retryInterval time.Duration
+	token         atomic.Int64
+}
+
+func NewElector(locker Locker, name string, ttl time.Duration, retryInterval time.Duration) Elector {
+	return &electorImpl{locker: locker, name: name, ttl: ttl, retryInterval: retryInterval}
+}
+
+func (e *electorImpl) IsLeader() bool { return e.token.Load() != 0 }
+
+func (e *electorImpl) Token() int64 { return e.token.Load() }
+
+func (e *electorImpl) Run(ctx context.Context, lead func(ctx context.Context)) {
+	for {
+		lease, err := e.locker.TryAcquire(ctx, e.name, e.ttl)
+		switch {
+		case err == nil:
+			e.lead(ctx, lease, lead)
+		case !errors.Is(err, ErrNotAcquired) && ctx.Err() == nil:
+			log.Printf("Leader election for %s failed: %v", e.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.retryInterval):
+		}
+	}
+}
+
+// hold the lease, renewing at a third of its ttl so a slow renewal still lands before expiry
+func (e *electorImpl) lead(ctx context.Context, lease Lease, lead func(ctx context.Context)) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	e.token.Store(lease.Token())
+	defer e.token.Store(0)
+	log.Printf("Became leader for %s (token %d)", e.name, lease.Token())
+	go lead(leaderCtx)
+
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			e.release(lease)
+			return
+		case <-ticker.C:
+			renewCtx, renewCancel := context.WithTimeout(ctx, e.ttl/3)
+			err := lease.Renew(renewCtx)
+			renewCancel()
+			if err != nil {
+				// stop leading first, the lease may already belong to someone else
+				cancel()
+				log.Printf("Lost leadership for %s: %v", e.name, err)
+				if !errors.Is(err, ErrLockLost) {
+					e.release(lease)
+				}
+				return
+			}
+		}
+	}
+}
+
+// background context so the next leader doesn't wait out the ttl after a shutdown
+func (e *electorImpl) release(lease Lease) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl/3)
+	defer cancel()
+	if err := lease.Release(ctx); err != nil && !errors.Is(err, ErrLockLost) {
+		log.Printf("Failed to release leadership for %s: %v", e.name, err)
+	}
+}
@@ -0,0 +1,28 @@
+// Package locks provides leases that keep work on a single replica, backed by redis or
+// postgres advisory locks, and a leader election loop built on them.
+package locks
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotAcquired = errors.New("lock held by another owner")
+	ErrLockLost    = errors.New("lock no longer held")
+)
+
+// a held lock. the fencing token increases every time the lock changes hands, so writes
+// tagged with it can be rejected when they come from a leader that has since been replaced
+type Lease interface {
+	Token() int64
+	// extend the lease by its ttl, ErrLockLost if it expired or was taken over
+	Renew(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+type Locker interface {
+	// take the lock without waiting, ErrNotAcquired when someone else holds it
+	TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lease, error)
+}
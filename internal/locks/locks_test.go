@@ -0,0 +1,176 @@
+package locks
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/undersleep7x/cryo-project/internal/platform/postgresstore/pgfake"
+	"github.com/undersleep7x/cryo-project/internal/platform/redisstore/redisfake"
+)
+
+// fake redis that runs the lease scripts with the same semantics as the lua
+func newLockRedis() *redisfake.Redis {
+	ctx := context.Background()
+	fake := redisfake.New()
+	fake.HandleScript(acquireScript, func(f *redisfake.Redis, keys []string, args []any) (any, error) {
+		ok, err := f.SetNX(ctx, keys[0], args[0], time.Duration(args[1].(int64))*time.Millisecond)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return f.Incr(ctx, keys[1])
+	})
+	fake.HandleScript(renewScript, func(f *redisfake.Redis, keys []string, args []any) (any, error) {
+		if owner, _ := f.Value(keys[0]); owner != args[0] {
+			return int64(0), nil
+		}
+		_, err := f.Expire(ctx, keys[0], time.Duration(args[1].(int64))*time.Millisecond)
+		return int64(1), err
+	})
+	fake.HandleScript(releaseScript, func(f *redisfake.Redis, keys []string, args []any) (any, error) {
+		if owner, _ := f.Value(keys[0]); owner != args[0] {
+			return int64(0), nil
+		}
+		return f.Del(ctx, keys[0])
+	})
+	return fake
+}
+
+func TestRedisLocker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Exclusive With Increasing Tokens", func(t *testing.T) {
+		locker := NewRedisLocker(newLockRedis())
+		first, err := locker.TryAcquire(ctx, "jobs", time.Minute)
+		assert.NoError(t, err)
+		_, err = locker.TryAcquire(ctx, "jobs", time.Minute)
+		assert.ErrorIs(t, err, ErrNotAcquired)
+
+		assert.NoError(t, first.Renew(ctx))
+		assert.NoError(t, first.Release(ctx))
+		second, err := locker.TryAcquire(ctx, "jobs", time.Minute)
+		assert.NoError(t, err)
+		assert.Greater(t, second.Token(), first.Token())
+	})
+
+	t.Run("Expired Lease Is Lost", func(t *testing.T) {
+		fake := newLockRedis()
+		now := time.Now()
+		fake.SetClock(func() time.Time { return now })
+		locker := NewRedisLocker(fake)
+
+		stale, err := locker.TryAcquire(ctx, "jobs", 10*time.Second)
+		assert.NoError(t, err)
+		now = now.Add(11 * time.Second)
+		current, err := locker.TryAcquire(ctx, "jobs", 10*time.Second)
+		assert.NoError(t, err)
+
+		// the old holder can neither extend nor release the new holder's lease
+		assert.ErrorIs(t, stale.Renew(ctx), ErrLockLost)
+		assert.ErrorIs(t, stale.Release(ctx), ErrLockLost)
+		assert.NoError(t, current.Renew(ctx))
+	})
+
+	t.Run("Redis Errors Surface", func(t *testing.T) {
+		fake := newLockRedis()
+		fake.Fail("Eval", errors.New("connection refused"), 1)
+		_, err := NewRedisLocker(fake).TryAcquire(ctx, "jobs", time.Minute)
+		assert.ErrorContains(t, err, "connection refused")
+		assert.NotErrorIs(t, err, ErrNotAcquired)
+	})
+}
+
+func TestPostgresLocker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Acquire And Release", func(t *testing.T) {
+		fake, client := pgfake.New()
+		fake.SetRows("pg_try_advisory_lock", pgfake.Rows{Columns: []string{"locked"}, Values: [][]driver.Value{{true}}})
+		fake.SetRows("nextval", pgfake.Rows{Columns: []string{"nextval"}, Values: [][]driver.Value{{int64(7)}}})
+		fake.SetRows("pg_advisory_unlock", pgfake.Rows{Columns: []string{"unlocked"}, Values: [][]driver.Value{{true}}})
+
+		lease, err := NewPostgresLocker(client.GetDB()).TryAcquire(ctx, "jobs", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), lease.Token())
+		assert.NoError(t, lease.Renew(ctx))
+		assert.NoError(t, lease.Release(ctx))
+		assert.Equal(t, []string{
+			"SELECT pg_try_advisory_lock($1)",
+			"SELECT nextval('lock_fencing_tokens')",
+			"SELECT pg_advisory_unlock($1)",
+		}, fake.Statements())
+	})
+
+	t.Run("Failed Unlock Discards Connection", func(t *testing.T) {
+		fake, client := pgfake.New()
+		fake.SetRows("pg_try_advisory_lock", pgfake.Rows{Columns: []string{"locked"}, Values: [][]driver.Value{{true}}})
+		fake.SetRows("nextval", pgfake.Rows{Columns: []string{"nextval"}, Values: [][]driver.Value{{int64(7)}}})
+		fake.Fail("pg_advisory_unlock", errors.New("connection reset"), 1)
+
+		lease, err := NewPostgresLocker(client.GetDB()).TryAcquire(ctx, "jobs", time.Minute)
+		assert.NoError(t, err)
+		assert.ErrorContains(t, lease.Release(ctx), "connection reset")
+		// a pooled connection would go on holding the lock
+		assert.Zero(t, client.GetDB().Stats().OpenConnections)
+	})
+
+	t.Run("Failed Renew Frees The Lock", func(t *testing.T) {
+		fake, client := pgfake.New()
+		fake.SetRows("pg_try_advisory_lock", pgfake.Rows{Columns: []string{"locked"}, Values: [][]driver.Value{{true}}})
+		fake.SetRows("nextval", pgfake.Rows{Columns: []string{"nextval"}, Values: [][]driver.Value{{int64(7)}}})
+		fake.Fail("PING", context.DeadlineExceeded, 1)
+		locker := NewPostgresLocker(client.GetDB())
+
+		lease, err := locker.TryAcquire(ctx, "jobs", time.Minute)
+		assert.NoError(t, err)
+		assert.ErrorIs(t, lease.Renew(ctx), ErrLockLost)
+		// postgres drops a session's advisory locks when its connection closes
+		assert.Zero(t, client.GetDB().Stats().OpenConnections)
+
+		next, err := locker.TryAcquire(ctx, "jobs", time.Minute)
+		assert.NoError(t, err)
+		assert.NoError(t, next.Renew(ctx))
+	})
+
+	t.Run("Held Elsewhere", func(t *testing.T) {
+		fake, client := pgfake.New()
+		fake.SetRows("pg_try_advisory_lock", pgfake.Rows{Columns: []string{"locked"}, Values: [][]driver.Value{{false}}})
+		_, err := NewPostgresLocker(client.GetDB()).TryAcquire(ctx, "jobs", time.Minute)
+		assert.ErrorIs(t, err, ErrNotAcquired)
+	})
+}
+
+func TestElector(t *testing.T) {
+	locker := NewRedisLocker(newLockRedis())
+	first := NewElector(locker, "jobs", 30*time.Millisecond, 5*time.Millisecond)
+	second := NewElector(locker, "jobs", 30*time.Millisecond, 5*time.Millisecond)
+
+	var firstLeading, secondLeading atomic.Bool
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+	run := func(e Elector, ctx context.Context, leading *atomic.Bool) {
+		e.Run(ctx, func(leaderCtx context.Context) {
+			leading.Store(true)
+			<-leaderCtx.Done()
+			leading.Store(false)
+		})
+	}
+	go run(first, firstCtx, &firstLeading)
+	assert.Eventually(t, firstLeading.Load, time.Second, time.Millisecond)
+	go run(second, secondCtx, &secondLeading)
+
+	// renewals keep the first leader in place past several ttls
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, first.IsLeader())
+	assert.False(t, second.IsLeader())
+
+	stopFirst()
+	assert.Eventually(t, secondLeading.Load, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return !firstLeading.Load() }, time.Second, time.Millisecond)
+	assert.Greater(t, second.Token(), int64(0))
+}
@@ -0,0 +1,92 @@
+package locks
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+type postgresLocker struct {
+	db *sql.DB
+}
+
+// leases are session advisory locks held on a dedicated connection. they don't expire, the
+// lock is dropped when the connection closes, so ttl is ignored and renewal checks the
+// connection is still alive
+func NewPostgresLocker(db *sql.DB) Locker {
+	return &postgresLocker{db: db}
+}
+
+func (l *postgresLocker) TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lease, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	key := advisoryKey(name)
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, ErrNotAcquired
+	}
+
+	lease := &postgresLease{conn: conn, key: key}
+	if err := conn.QueryRowContext(ctx, "SELECT nextval('lock_fencing_tokens')").Scan(&lease.token); err != nil {
+		_ = lease.Release(ctx)
+		return nil, fmt.Errorf("failed to issue fencing token for %s: %w", name, err)
+	}
+	return lease, nil
+}
+
+// advisory locks are keyed by bigint, so names are hashed into that space
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("cryo:lock:" + name))
+	return int64(h.Sum64())
+}
+
+type postgresLease struct {
+	conn  *sql.Conn
+	key   int64
+	token int64
+}
+
+func (l *postgresLease) Token() int64 { return l.token }
+
+// a ping that only timed out leaves the session, and the lock with it, alive. the connection
+// is closed before reporting the lease lost so the lock is freed for the next leader
+func (l *postgresLease) Renew(ctx context.Context) error {
+	if err := l.conn.PingContext(ctx); err != nil {
+		l.discard()
+		return fmt.Errorf("%w: %v", ErrLockLost, err)
+	}
+	return nil
+}
+
+// a connection whose unlock failed may still hold the lock, so it is closed rather than
+// returned to the pool where it would keep the lock for as long as it stays idle
+func (l *postgresLease) Release(ctx context.Context) error {
+	var unlocked bool
+	if err := l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.key).Scan(&unlocked); err != nil {
+		l.discard()
+		return err
+	}
+	l.conn.Close()
+	if !unlocked {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// database/sql closes the driver connection instead of pooling it once it reports ErrBadConn
+func (l *postgresLease) discard() {
+	_ = l.conn.Raw(func(any) error { return driver.ErrBadConn })
+	l.conn.Close()
+}
@@ -0,0 +1,84 @@
+package locks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	platformRedis "github.com/undersleep7x/cryo-project/internal/platform/redisstore"
+)
+
+// keys share a hash tag so both land on the same cluster slot
+const (
+	acquireScript = `if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return false`
+	renewScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`
+	releaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
+)
+
+type redisLocker struct {
+	client platformRedis.RedisClient
+}
+
+// leases are SET NX PX keys owned by a random id, expiring on their own if the holder dies
+func NewRedisLocker(client platformRedis.RedisClient) Locker {
+	return &redisLocker{client: client}
+}
+
+func (l *redisLocker) TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lease, error) {
+	lease := &redisLease{
+		client: l.client,
+		key:    "locks:{" + name + "}",
+		owner:  uuid.NewString(),
+		ttl:    ttl,
+	}
+	result, err := l.client.Eval(ctx, acquireScript, []string{lease.key, lease.key + ":fence"}, lease.owner, ttl.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	token, ok := result.(int64)
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+	lease.token = token
+	return lease, nil
+}
+
+type redisLease struct {
+	client platformRedis.RedisClient
+	key    string
+	owner  string
+	ttl    time.Duration
+	token  int64
+}
+
+func (l *redisLease) Token() int64 { return l.token }
+
+func (l *redisLease) Renew(ctx context.Context) error {
+	return l.ifOwner(ctx, renewScript, l.ttl.Milliseconds())
+}
+
+func (l *redisLease) Release(ctx context.Context) error {
+	return l.ifOwner(ctx, releaseScript)
+}
+
+// run a compare-and-act script, only touching the key while it still holds our owner id
+func (l *redisLease) ifOwner(ctx context.Context, script string, args ...any) error {
+	result, err := l.client.Eval(ctx, script, []string{l.key}, append([]any{l.owner}, args...)...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.(int64); n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
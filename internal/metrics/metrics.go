@@ -0,0 +1,129 @@
+// Package metrics holds the prometheus collectors exported on /metrics. label values are
+// fixed sets (routes, statuses, outcomes) so series counts stay bounded
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cryo"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls by full method name and status code.",
+	}, []string{"method", "code"})
+
+	// streams are observed when they end, so their latency is the stream lifetime
+	GRPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC call latency by full method name and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	// hit, miss, error (redis failed) or stale (served the last known price after a provider failure)
+	PriceCacheResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "price_cache_results_total",
+		Help:      "Price cache lookups by result.",
+	}, []string{"result"})
+
+	UpstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Calls to price providers by endpoint and outcome.",
+	}, []string{"provider", "endpoint", "outcome"})
+
+	UpstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Price provider call latency by endpoint.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"provider", "endpoint"})
+
+	// created, confirmed or expired
+	Invoices = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invoices_total",
+		Help:      "Invoice lifecycle events.",
+	}, []string{"event"})
+
+	// sent or failed
+	Payments = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_total",
+		Help:      "Payments by outcome.",
+	}, []string{"outcome"})
+
+	// rewrapped, sealed (plaintext from before encryption), current (already on the target version),
+	// conflict (written mid rotation) or failed
+	KeyRotationRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "key_rotation_rows_total",
+		Help:      "Encrypted values visited by the key rotation job by table and outcome.",
+	}, []string{"table", "outcome"})
+
+	// only while security.legacy_plaintext is on, stops rising once the rotation job has sealed
+	// every legacy row
+	EncryptionPlaintextReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "encryption_plaintext_reads_total",
+		Help:      "Unsealed values read as legacy plaintext from encrypted columns by table and column.",
+	}, []string{"table", "column"})
+)
+
+func init() {
+	// start every known series at zero so rates work before the first event
+	for _, result := range []string{"hit", "miss", "error", "stale"} {
+		PriceCacheResults.WithLabelValues(result)
+	}
+	for _, event := range []string{"created", "confirmed", "expired"} {
+		Invoices.WithLabelValues(event)
+	}
+	for _, outcome := range []string{"sent", "failed"} {
+		Payments.WithLabelValues(outcome)
+	}
+}
+
+// export the pool statistics of db under the given name
+func RegisterDBStats(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// "ok" for 2xx/3xx responses, otherwise the status code or "error" when no response came back
+func Outcome(statusCode int, err error) string {
+	switch {
+	case err != nil:
+		return "error"
+	case statusCode >= 400:
+		return strconv.Itoa(statusCode)
+	default:
+		return "ok"
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/price/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/metrics", gin.WrapH(Handler()))
+
+	for _, path := range []string{"/price/bitcoin", "/price/ethereum", "/no/such/route"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// raw paths collapse into their route template
+	assert.Equal(t, 2.0, testutil.ToFloat64(HTTPRequests.WithLabelValues("/price/:id", "GET", "204")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(HTTPRequests.WithLabelValues("unmatched", "GET", "404")))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	assert.True(t, strings.Contains(body, `cryo_http_request_duration_seconds_count{method="GET",route="/price/:id",status="204"} 2`), body)
+	assert.Contains(t, body, `cryo_invoices_total{event="expired"} 0`)
+}
+
+func TestOutcome(t *testing.T) {
+	assert.Equal(t, "ok", Outcome(http.StatusOK, nil))
+	assert.Equal(t, "429", Outcome(http.StatusTooManyRequests, nil))
+	assert.Equal(t, "error", Outcome(0, errors.New("timeout")))
+}
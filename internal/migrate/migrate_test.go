@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/undersleep7x/cryo-project/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Embedded Migrations", func(t *testing.T) {
+		loaded, err := Load(migrations.FS)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, loaded)
+		for i, migration := range loaded {
+			assert.Equal(t, i+1, migration.Version, "migrations must be numbered without gaps")
+			assert.NotEmpty(t, migration.Checksum)
+		}
+	})
+
+	t.Run("Ordered By Version", func(t *testing.T) {
+		loaded, err := Load(fstest.MapFS{
+			"0010_later.up.sql":     {Data: []byte("SELECT 10")},
+			"0010_later.down.sql":   {Data: []byte("SELECT -10")},
+			"0002_earlier.up.sql":   {Data: []byte("SELECT 2")},
+			"0002_earlier.down.sql": {Data: []byte("SELECT -2")},
+			"README.md":             {Data: []byte("ignored")},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, loaded[0].Version)
+		assert.Equal(t, "earlier", loaded[0].Name)
+		assert.Equal(t, "SELECT -10", loaded[1].Down)
+	})
+
+	t.Run("Missing Down Rejected", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{"0001_init.up.sql": {Data: []byte("SELECT 1")}})
+		assert.ErrorContains(t, err, "needs both an up and a down file")
+	})
+}
+
+func TestPlan(t *testing.T) {
+	shipped := []Migration{
+		{Version: 1, Name: "init", Checksum: "a"},
+		{Version: 2, Name: "prices", Checksum: "b"},
+	}
+	appliedAt := time.Now()
+
+	t.Run("Pending Migrations Behind", func(t *testing.T) {
+		statuses := plan(shipped, map[int]appliedMigration{1: {name: "init", checksum: "a", appliedAt: appliedAt}})
+		assert.True(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+		assert.True(t, errors.Is(verify(statuses), ErrSchemaBehind))
+	})
+
+	t.Run("Current Schema", func(t *testing.T) {
+		statuses := plan(shipped, map[int]appliedMigration{
+			1: {name: "init", checksum: "a", appliedAt: appliedAt},
+			2: {name: "prices", checksum: "b", appliedAt: appliedAt},
+		})
+		assert.NoError(t, verify(statuses))
+	})
+
+	t.Run("Edited Migration Detected", func(t *testing.T) {
+		statuses := plan(shipped, map[int]appliedMigration{1: {name: "init", checksum: "changed", appliedAt: appliedAt}})
+		assert.True(t, statuses[0].Modified)
+		assert.True(t, errors.Is(verify(statuses), ErrChecksumMismatch))
+	})
+
+	t.Run("Unknown Applied Migration", func(t *testing.T) {
+		statuses := plan(shipped, map[int]appliedMigration{
+			1: {name: "init", checksum: "a", appliedAt: appliedAt},
+			2: {name: "prices", checksum: "b", appliedAt: appliedAt},
+			3: {name: "from_newer_release", checksum: "c", appliedAt: appliedAt},
+		})
+		assert.Len(t, statuses, 3)
+		assert.True(t, statuses[2].Unknown)
+		assert.NoError(t, verify(statuses)) // an older binary can still run against a newer schema
+	})
+}
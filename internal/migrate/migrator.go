@@ -0,0 +1,308 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+var (
+	ErrSchemaBehind      = errors.New("database schema is behind")
+	ErrChecksumMismatch  = errors.New("applied migration differs from the shipped file")
+	ErrNothingToRollback = errors.New("no applied migrations to roll back")
+)
+
+// arbitrary key shared by every replica so only one runs migrations at a time
+const advisoryLockKey int64 = 0x6372796f // "cryo"
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+)`
+
+// state of one migration against the database
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Modified  bool // applied checksum doesn't match the shipped file
+	Unknown   bool // applied in the database but not shipped in this binary
+}
+
+type Migrator interface {
+	Up(ctx context.Context) ([]Migration, error)
+	Down(ctx context.Context, steps int) ([]Migration, error)
+	Redo(ctx context.Context) error
+	Status(ctx context.Context) ([]Status, error)
+	// ErrSchemaBehind if any shipped migration is pending, ErrChecksumMismatch if one was edited after applying
+	Verify(ctx context.Context) error
+}
+
+type migratorImpl struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) Migrator {
+	return &migratorImpl{db: db, migrations: migrations}
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// apply every pending migration in order, each in its own transaction
+func (m *migratorImpl) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.statusOn(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkModified(statuses); err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			if status.Applied {
+				continue
+			}
+			migration := m.migrations[indexOf(m.migrations, status.Version)]
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// roll back the most recently applied steps migrations
+func (m *migratorImpl) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.statusOn(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(statuses) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			status := statuses[i]
+			if !status.Applied {
+				continue
+			}
+			if status.Unknown {
+				return fmt.Errorf("cannot roll back migration %d (%s): not shipped in this binary", status.Version, status.Name)
+			}
+			migration := m.migrations[indexOf(m.migrations, status.Version)]
+			if err := m.rollback(ctx, conn, migration); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		if len(rolledBack) == 0 {
+			return ErrNothingToRollback
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// roll back and re-apply the latest migration, for iterating on a migration in development
+func (m *migratorImpl) Redo(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.statusOn(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(statuses) - 1; i >= 0; i-- {
+			if !statuses[i].Applied {
+				continue
+			}
+			if statuses[i].Unknown {
+				return fmt.Errorf("cannot redo migration %d (%s): not shipped in this binary", statuses[i].Version, statuses[i].Name)
+			}
+			migration := m.migrations[indexOf(m.migrations, statuses[i].Version)]
+			if err := m.rollback(ctx, conn, migration); err != nil {
+				return err
+			}
+			return m.apply(ctx, conn, migration)
+		}
+		return ErrNothingToRollback
+	})
+}
+
+func (m *migratorImpl) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+	return m.statusOn(ctx, conn)
+}
+
+func (m *migratorImpl) Verify(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return verify(statuses)
+}
+
+// hold a session advisory lock on a dedicated connection for the duration of fn
+func (m *migratorImpl) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// background context so the lock is released even if ctx was cancelled mid migration
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *migratorImpl) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	log.Printf("Applying migration %04d_%s", migration.Version, migration.Name)
+	return inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, migration.Checksum)
+		return err
+	})
+}
+
+func (m *migratorImpl) rollback(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	log.Printf("Rolling back migration %04d_%s", migration.Version, migration.Name)
+	return inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return fmt.Errorf("rollback of %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		return err
+	})
+}
+
+func (m *migratorImpl) statusOn(ctx context.Context, conn *sql.Conn) ([]Status, error) {
+	applied := make(map[int]appliedMigration)
+
+	// nothing has been applied yet on a fresh database
+	var exists bool
+	if err := conn.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check for schema_migrations: %w", err)
+	}
+	if !exists {
+		return plan(m.migrations, applied), nil
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var row appliedMigration
+		if err := rows.Scan(&version, &row.name, &row.checksum, &row.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return plan(m.migrations, applied), nil
+}
+
+// merge shipped migrations with what the database has applied, ordered by version
+func plan(migrations []Migration, applied map[int]appliedMigration) []Status {
+	statuses := make([]Status, 0, len(migrations))
+	shipped := make(map[int]bool, len(migrations))
+	for _, migration := range migrations {
+		shipped[migration.Version] = true
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = row.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	for version, row := range applied {
+		if !shipped[version] {
+			appliedAt := row.appliedAt
+			statuses = append(statuses, Status{Version: version, Name: row.name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+func verify(statuses []Status) error {
+	if err := checkModified(statuses); err != nil {
+		return err
+	}
+	var pending []string
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, fmt.Sprintf("%04d_%s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migrations %v", ErrSchemaBehind, len(pending), pending)
+	}
+	return nil
+}
+
+func checkModified(statuses []Status) error {
+	for _, status := range statuses {
+		if status.Modified {
+			return fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, status.Version, status.Name)
+		}
+	}
+	return nil
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func indexOf(migrations []Migration, version int) int {
+	for i, migration := range migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
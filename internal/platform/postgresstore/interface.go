@@ -5,8 +5,30 @@ import (
 	"database/sql"
 )
 
+// query surface shared by *sql.DB and *sql.Tx
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// what repositories take, satisfied by both the client and an open transaction so the
+// same repository can run standalone or as one step of a larger unit of work
+type Querier interface {
+	DBTX
+	// run fn in a transaction, or in a savepoint when already inside one
+	WithTx(ctx context.Context, fn func(tx Tx) error) error
+}
+
+type Tx interface {
+	Querier
+}
+
 type PostgresClient interface {
+	Querier
     Ping(ctx context.Context) error
 	GetDB() *sql.DB
+	Stats() sql.DBStats
     Close() error
-}
\ No newline at end of file
+}
@@ -0,0 +1,205 @@
+// Package pgfake is an in-memory database/sql driver for testing code built on
+// postgresstore without a running Postgres. It records every statement, transaction
+// boundary and savepoint, and can be told to fail or return rows for matching queries.
+package pgfake
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	platformPostgres "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
+)
+
+const driverName = "pgfake"
+
+var (
+	registerOnce sync.Once
+	nextID       atomic.Int64
+	fakesMu      sync.Mutex
+	fakes        = make(map[string]*DB)
+)
+
+// canned result for queries containing a substring
+type Rows struct {
+	Columns []string
+	Values  [][]driver.Value
+}
+
+type failure struct {
+	match string
+	err   error
+	times int // remaining failures, -1 for always
+}
+
+type DB struct {
+	mu         sync.Mutex
+	statements []string
+	failures   []*failure
+	rows       map[string]Rows
+}
+
+// new fake and a client backed by it
+func New() (*DB, platformPostgres.PostgresClient) {
+	registerOnce.Do(func() { sql.Register(driverName, fakeDriver{}) })
+
+	fake := &DB{rows: make(map[string]Rows)}
+	name := fmt.Sprintf("fake-%d", nextID.Add(1))
+	fakesMu.Lock()
+	fakes[name] = fake
+	fakesMu.Unlock()
+
+	db, _ := sql.Open(driverName, name) // never fails, connections are opened lazily
+	return fake, platformPostgres.NewPgClientWrapper(db)
+}
+
+// every statement seen, including BEGIN, COMMIT, ROLLBACK and savepoint commands
+func (f *DB) Statements() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.statements...)
+}
+
+// fail the next times statements containing match with err, times < 0 fails forever
+func (f *DB) Fail(match string, err error, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, &failure{match: match, err: err, times: times})
+}
+
+// return rows for queries containing match
+func (f *DB) SetRows(match string, rows Rows) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rows[match] = rows
+}
+
+func (f *DB) record(stmt string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, stmt)
+	return f.failure(stmt)
+}
+
+// error for the first failure matching stmt, callers hold f.mu
+func (f *DB) failure(stmt string) error {
+	for _, fail := range f.failures {
+		if fail.times != 0 && strings.Contains(stmt, fail.match) {
+			if fail.times > 0 {
+				fail.times--
+			}
+			return fail.err
+		}
+	}
+	return nil
+}
+
+func (f *DB) rowsFor(query string) Rows {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for match, rows := range f.rows {
+		if strings.Contains(query, match) {
+			return rows
+		}
+	}
+	return Rows{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakesMu.Lock()
+	defer fakesMu.Unlock()
+	fake, ok := fakes[name]
+	if !ok {
+		return nil, fmt.Errorf("pgfake: unknown database %q", name)
+	}
+	return &conn{db: fake}, nil
+}
+
+type conn struct {
+	db *DB
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+// pings aren't statements, fail them with Fail("PING", ...)
+func (c *conn) Ping(ctx context.Context) error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return c.db.failure("PING")
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.db.record("BEGIN"); err != nil {
+		return nil, err
+	}
+	return &tx{db: c.db}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.db.record(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.db.record(query); err != nil {
+		return nil, err
+	}
+	return &rows{result: c.db.rowsFor(query)}, nil
+}
+
+type tx struct {
+	db *DB
+}
+
+func (t *tx) Commit() error   { return t.db.record("COMMIT") }
+func (t *tx) Rollback() error { return t.db.record("ROLLBACK") }
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, nil)
+}
+
+type rows struct {
+	result Rows
+	next   int
+}
+
+func (r *rows) Columns() []string { return r.result.Columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.Values) {
+		return io.EOF
+	}
+	copy(dest, r.result.Values[r.next])
+	r.next++
+	return nil
+}
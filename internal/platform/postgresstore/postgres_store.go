@@ -18,10 +18,30 @@ func (p *pgClientImpl) Ping(ctx context.Context) error {
 	return p.db.PingContext(ctx)
 }
 
+func (p *pgClientImpl) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return traceExec(ctx, p.db, query, args)
+}
+
+func (p *pgClientImpl) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return traceQuery(ctx, p.db, query, args)
+}
+
+func (p *pgClientImpl) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return traceQueryRow(ctx, p.db, query, args)
+}
+
+func (p *pgClientImpl) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.PrepareContext(ctx, query)
+}
+
 func (p *pgClientImpl) GetDB() *sql.DB {
 	return p.db
 }
 
+func (p *pgClientImpl) Stats() sql.DBStats {
+	return p.db.Stats()
+}
+
 func (p *pgClientImpl) Close() error {
 	return p.db.Close()
 }
@@ -0,0 +1,65 @@
+package postgresstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
+
+// client span per statement, named by its leading keyword so names stay low cardinality.
+// queries use placeholders, so the text carries no values
+func startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	return otel.Tracer(tracerName).Start(ctx, "postgres "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation), semconv.DBQueryText(query)),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// the statement methods *sql.DB and *sql.Tx have in common
+type sqlRunner interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func traceExec(ctx context.Context, db sqlRunner, query string, args []any) (sql.Result, error) {
+	ctx, span := startSpan(ctx, query)
+	result, err := db.ExecContext(ctx, query, args...)
+	endSpan(span, err)
+	return result, err
+}
+
+// the span covers running the query, not iterating the rows
+func traceQuery(ctx context.Context, db sqlRunner, query string, args []any) (*sql.Rows, error) {
+	ctx, span := startSpan(ctx, query)
+	rows, err := db.QueryContext(ctx, query, args...)
+	endSpan(span, err)
+	return rows, err
+}
+
+func traceQueryRow(ctx context.Context, db sqlRunner, query string, args []any) *sql.Row {
+	ctx, span := startSpan(ctx, query)
+	row := db.QueryRowContext(ctx, query, args...)
+	endSpan(span, row.Err())
+	return row
+}
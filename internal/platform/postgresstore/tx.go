@@ -0,0 +1,107 @@
+package postgresstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	maxTxAttempts  = 3
+	txRetryBackoff = 20 * time.Millisecond
+)
+
+// run fn in a transaction, committing when it returns nil and rolling back otherwise.
+// serialization failures and deadlocks re-run fn from the start, so fn must not have side
+// effects outside the transaction
+func (p *pgClientImpl) WithTx(ctx context.Context, fn func(tx Tx) error) (err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "postgres transaction", trace.WithAttributes(semconv.DBSystemPostgreSQL))
+	defer func() { endSpan(span, err) }()
+
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = p.runTx(ctx, fn)
+		if err == nil || !isRetryable(err) || attempt == maxTxAttempts {
+			return err
+		}
+		log.Printf("Transaction conflict (attempt %d/%d), retrying: %v", attempt, maxTxAttempts, err)
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * txRetryBackoff):
+		}
+	}
+	return err
+}
+
+func (p *pgClientImpl) runTx(ctx context.Context, fn func(tx Tx) error) error {
+	sqlTx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(&txImpl{Tx: sqlTx, span: trace.SpanFromContext(ctx)}); err != nil {
+		if rbErr := sqlTx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Failed to roll back transaction: %v", rbErr)
+		}
+		return err
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+type txImpl struct {
+	*sql.Tx
+	depth int        // savepoint nesting, 0 for the outer transaction
+	span  trace.Span // transaction span, fn only gets the caller's ctx so statements are parented here
+}
+
+func (t *txImpl) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return traceExec(trace.ContextWithSpan(ctx, t.span), t.Tx, query, args)
+}
+
+func (t *txImpl) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return traceQuery(trace.ContextWithSpan(ctx, t.span), t.Tx, query, args)
+}
+
+func (t *txImpl) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return traceQueryRow(trace.ContextWithSpan(ctx, t.span), t.Tx, query, args)
+}
+
+// nested units of work get a savepoint, so a failing step undoes only its own writes
+// and the caller decides whether the outer transaction carries on
+func (t *txImpl) WithTx(ctx context.Context, fn func(tx Tx) error) error {
+	savepoint := fmt.Sprintf("sp_%d", t.depth+1)
+	if _, err := t.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	if err := fn(&txImpl{Tx: t.Tx, depth: t.depth + 1, span: t.span}); err != nil {
+		if _, rbErr := t.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			log.Printf("Failed to roll back to savepoint %s: %v", savepoint, rbErr)
+		}
+		return err
+	}
+	if _, err := t.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// serialization_failure and deadlock_detected are safe to retry from the top
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
@@ -0,0 +1,144 @@
+package postgresstore_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	platformPostgres "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
+	"github.com/undersleep7x/cryo-project/internal/platform/postgresstore/pgfake"
+	"github.com/undersleep7x/cryo-project/internal/tracing/tracingtest"
+)
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Commit On Success", func(t *testing.T) {
+		fake, client := pgfake.New()
+		err := client.WithTx(ctx, func(tx platformPostgres.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO invoices VALUES (1)")
+			return err
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"BEGIN", "INSERT INTO invoices VALUES (1)", "COMMIT"}, fake.Statements())
+	})
+
+	t.Run("Rollback On Error", func(t *testing.T) {
+		fake, client := pgfake.New()
+		boom := errors.New("boom")
+		err := client.WithTx(ctx, func(tx platformPostgres.Tx) error { return boom })
+		assert.True(t, errors.Is(err, boom))
+		assert.Equal(t, []string{"BEGIN", "ROLLBACK"}, fake.Statements())
+	})
+
+	t.Run("Nested Savepoints", func(t *testing.T) {
+		fake, client := pgfake.New()
+		err := client.WithTx(ctx, func(tx platformPostgres.Tx) error {
+			_, _ = tx.ExecContext(ctx, "INSERT INTO invoices VALUES (1)")
+			// failed inner step is undone without aborting the outer transaction
+			innerErr := tx.WithTx(ctx, func(inner platformPostgres.Tx) error {
+				_, _ = inner.ExecContext(ctx, "INSERT INTO webhook_outbox VALUES (1)")
+				return errors.New("outbox full")
+			})
+			assert.Error(t, innerErr)
+			return tx.WithTx(ctx, func(inner platformPostgres.Tx) error {
+				_, err := inner.ExecContext(ctx, "INSERT INTO invoice_status VALUES (1)")
+				return err
+			})
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"BEGIN",
+			"INSERT INTO invoices VALUES (1)",
+			"SAVEPOINT sp_1",
+			"INSERT INTO webhook_outbox VALUES (1)",
+			"ROLLBACK TO SAVEPOINT sp_1",
+			"SAVEPOINT sp_1",
+			"INSERT INTO invoice_status VALUES (1)",
+			"RELEASE SAVEPOINT sp_1",
+			"COMMIT",
+		}, fake.Statements())
+	})
+
+	t.Run("Retry Serialization Failure", func(t *testing.T) {
+		fake, client := pgfake.New()
+		fake.Fail("UPDATE balances", &pq.Error{Code: "40001"}, 1)
+		attempts := 0
+		err := client.WithTx(ctx, func(tx platformPostgres.Tx) error {
+			attempts++
+			_, err := tx.ExecContext(ctx, "UPDATE balances SET amount = 1")
+			return err
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("Other Errors Not Retried", func(t *testing.T) {
+		fake, client := pgfake.New()
+		fake.Fail("UPDATE balances", &pq.Error{Code: "23505"}, -1)
+		attempts := 0
+		err := client.WithTx(ctx, func(tx platformPostgres.Tx) error {
+			attempts++
+			_, err := tx.ExecContext(ctx, "UPDATE balances SET amount = 1")
+			return err
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestWithTxTracing(t *testing.T) {
+	spans := tracingtest.New(t)
+	ctx := context.Background()
+	_, client := pgfake.New()
+
+	err := client.WithTx(ctx, func(tx platformPostgres.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO invoices VALUES (1)")
+		return err
+	})
+	assert.NoError(t, err)
+
+	recorded := spans.GetSpans()
+	assert.Equal(t, []string{"postgres INSERT", "postgres transaction"}, tracingtest.SpanNames(spans))
+	assert.Equal(t, recorded[1].SpanContext.SpanID(), recorded[0].Parent.SpanID()) // statements nest under the transaction
+}
+
+// runs against a real database when CRYO_TEST_POSTGRES_DSN is set, see `make test-integration`
+func TestWithTxPostgres(t *testing.T) {
+	dsn := os.Getenv("CRYO_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("CRYO_TEST_POSTGRES_DSN not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	assert.NoError(t, err)
+	client := platformPostgres.NewPgClientWrapper(db)
+	defer client.Close()
+	ctx := context.Background()
+
+	_, err = client.ExecContext(ctx, "CREATE TEMP TABLE uow_test (id INT PRIMARY KEY)")
+	assert.NoError(t, err)
+	db.SetMaxOpenConns(1) // temp tables are per connection
+
+	err = client.WithTx(ctx, func(tx platformPostgres.Tx) error {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO uow_test VALUES (1)"); err != nil {
+			return err
+		}
+		_ = tx.WithTx(ctx, func(inner platformPostgres.Tx) error {
+			if _, err := inner.ExecContext(ctx, "INSERT INTO uow_test VALUES (2)"); err != nil {
+				return err
+			}
+			_, err := inner.ExecContext(ctx, "INSERT INTO uow_test VALUES (1)") // duplicate key
+			return err
+		})
+		return nil
+	})
+	assert.NoError(t, err)
+
+	var count int
+	assert.NoError(t, client.QueryRowContext(ctx, "SELECT COUNT(*) FROM uow_test").Scan(&count))
+	assert.Equal(t, 1, count) // savepoint rolled back row 2, outer insert committed
+}
@@ -5,8 +5,33 @@ import (
 	"time"
 )
 
+// redis surface the app builds on: caching, pub/sub, and the primitives (SetNX, Incr,
+// Expire, Eval) that locks, rate limits and idempotency keys need
 type RedisClient interface {
 	Get(ctx context.Context, key string) (string, error)
+	// values for the keys that exist, missing keys are left out
+	MGet(ctx context.Context, keys ...string) (map[string]string, error)
 	Set(ctx context.Context, key string, value any, expiration time.Duration) error
+	// set only if the key doesn't exist, reporting whether it was set
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error)
+	Del(ctx context.Context, keys ...string) (int64, error)
+	Incr(ctx context.Context, key string) (int64, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) (bool, error)
+	// remaining time to live, 0 for keys without an expiry, redis.Nil for missing keys
+	PTTL(ctx context.Context, key string) (time.Duration, error)
+	// queue commands on p and send them in one round trip when fn returns
+	Pipelined(ctx context.Context, fn func(p Pipeline) error) error
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
 	Ping(ctx context.Context) error
-}
\ No newline at end of file
+	Publish(ctx context.Context, channel string, message any) error
+	Subscribe(ctx context.Context, channel string) (<-chan string, error) // channel closes when ctx is done
+	Close() error
+}
+
+// write commands that can be batched, results are only known once the pipeline runs
+type Pipeline interface {
+	Set(key string, value any, expiration time.Duration)
+	Del(keys ...string)
+	Incr(key string)
+	Expire(key string, expiration time.Duration)
+}
@@ -2,25 +2,155 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	redis "github.com/redis/go-redis/v9"
 )
 
 type clientWrapper struct {
-	Client *redis.Client
+	Client redis.UniversalClient
 }
 
-func NewRedisClientWrapper(client *redis.Client) RedisClient {
+// wraps a standalone, sentinel (failover) or cluster client
+func NewRedisClientWrapper(client redis.UniversalClient) RedisClient {
 	return &clientWrapper{Client: client}
 }
 
 func (r *clientWrapper) Get(ctx context.Context, key string) (string, error) {
 	return r.Client.Get(ctx, key).Result()
 }
+
+func (r *clientWrapper) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	values, err := r.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]string, len(values))
+	for i, value := range values {
+		if s, ok := value.(string); ok { // nil for missing keys
+			found[keys[i]] = s
+		}
+	}
+	return found, nil
+}
+
 func (r *clientWrapper) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	return r.Client.Set(ctx, key, value, expiration).Err()
 }
+
+func (r *clientWrapper) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	return r.Client.SetNX(ctx, key, value, expiration).Result()
+}
+
+func (r *clientWrapper) Del(ctx context.Context, keys ...string) (int64, error) {
+	return r.Client.Del(ctx, keys...).Result()
+}
+
+func (r *clientWrapper) Incr(ctx context.Context, key string) (int64, error) {
+	return r.Client.Incr(ctx, key).Result()
+}
+
+func (r *clientWrapper) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	return r.Client.Expire(ctx, key, expiration).Result()
+}
+
+func (r *clientWrapper) PTTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.Client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	switch ttl {
+	case -2: // missing
+		return 0, redis.Nil
+	case -1: // no expiry
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r *clientWrapper) Pipelined(ctx context.Context, fn func(p Pipeline) error) error {
+	var fnErr error
+	_, err := r.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		fnErr = fn(&pipelineWrapper{ctx: ctx, p: p})
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+// scripts are sent by sha first and only loaded when redis doesn't have them cached
+func (r *clientWrapper) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	result, err := redis.NewScript(script).Run(ctx, r.Client, keys, args...).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return result, err
+}
+
 func (r *clientWrapper) Ping(ctx context.Context) error {
 	return r.Client.Ping(ctx).Err()
 }
+
+func (r *clientWrapper) Publish(ctx context.Context, channel string, message any) error {
+	return r.Client.Publish(ctx, channel, message).Err()
+}
+
+// subscribe to a channel and forward payloads until ctx is cancelled
+func (r *clientWrapper) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	pubsub := r.Client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil { // wait for subscription confirmation
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+		msgs := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case out <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (r *clientWrapper) Close() error {
+	return r.Client.Close()
+}
+
+type pipelineWrapper struct {
+	ctx context.Context
+	p   redis.Pipeliner
+}
+
+func (w *pipelineWrapper) Set(key string, value any, expiration time.Duration) {
+	w.p.Set(w.ctx, key, value, expiration)
+}
+
+func (w *pipelineWrapper) Del(keys ...string) {
+	w.p.Del(w.ctx, keys...)
+}
+
+func (w *pipelineWrapper) Incr(key string) {
+	w.p.Incr(w.ctx, key)
+}
+
+func (w *pipelineWrapper) Expire(key string, expiration time.Duration) {
+	w.p.Expire(w.ctx, key, expiration)
+}
@@ -0,0 +1,391 @@
+// Package redisfake is an in-memory RedisClient for testing code built on redisstore
+// without a running redis. It keeps values with expiries against an adjustable clock,
+// delivers pub/sub messages, counts commands and can be told to fail them.
+package redisfake
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+	platformRedis "github.com/undersleep7x/cryo-project/internal/platform/redisstore"
+)
+
+// stands in for a lua script passed to Eval, keys and args are passed through unchanged
+type ScriptFunc func(f *Redis, keys []string, args []any) (any, error)
+
+type entry struct {
+	value     string
+	expiresAt time.Time // zero for no expiry
+}
+
+type failure struct {
+	cmd   string
+	err   error
+	times int // remaining failures, -1 for always
+}
+
+type Redis struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	values   map[string]entry
+	channels map[string][]chan string
+	calls    map[string]int
+	failures []*failure
+	scripts  map[string]ScriptFunc
+}
+
+var _ platformRedis.RedisClient = (*Redis)(nil)
+
+func New() *Redis {
+	return &Redis{
+		now:      time.Now,
+		values:   make(map[string]entry),
+		channels: make(map[string][]chan string),
+		calls:    make(map[string]int),
+		scripts:  make(map[string]ScriptFunc),
+	}
+}
+
+// replace the clock used for expiries
+func (f *Redis) SetClock(now func() time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// fail the next times calls to cmd (e.g. "Get") with err, times < 0 fails forever
+func (f *Redis) Fail(cmd string, err error, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, &failure{cmd: cmd, err: err, times: times})
+}
+
+// run fn whenever Eval is called with script
+func (f *Redis) HandleScript(script string, fn ScriptFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripts[script] = fn
+}
+
+// number of times cmd was called, failed calls included
+func (f *Redis) Count(cmd string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[cmd]
+}
+
+// stored value without counting as a Get
+func (f *Redis) Value(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.lookup(key)
+	return e.value, ok
+}
+
+// remaining time to live, 0 for keys without an expiry, false for missing keys
+func (f *Redis) TTL(key string) (time.Duration, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, ok := f.lookup(key)
+	if !ok || e.expiresAt.IsZero() {
+		return 0, ok
+	}
+	return e.expiresAt.Sub(f.now()), true
+}
+
+func (f *Redis) Get(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Get"); err != nil {
+		return "", err
+	}
+	e, ok := f.lookup(key)
+	if !ok {
+		return "", redis.Nil
+	}
+	return e.value, nil
+}
+
+func (f *Redis) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("MGet"); err != nil {
+		return nil, err
+	}
+	found := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if e, ok := f.lookup(key); ok {
+			found[key] = e.value
+		}
+	}
+	return found, nil
+}
+
+func (f *Redis) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Set"); err != nil {
+		return err
+	}
+	f.set(key, value, expiration)
+	return nil
+}
+
+func (f *Redis) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("SetNX"); err != nil {
+		return false, err
+	}
+	if _, ok := f.lookup(key); ok {
+		return false, nil
+	}
+	f.set(key, value, expiration)
+	return true, nil
+}
+
+func (f *Redis) Del(ctx context.Context, keys ...string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Del"); err != nil {
+		return 0, err
+	}
+	return f.del(keys...), nil
+}
+
+func (f *Redis) Incr(ctx context.Context, key string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Incr"); err != nil {
+		return 0, err
+	}
+	return f.incr(key)
+}
+
+func (f *Redis) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Expire"); err != nil {
+		return false, err
+	}
+	return f.expire(key, expiration), nil
+}
+
+// queued commands are applied together once fn returns without error
+func (f *Redis) PTTL(ctx context.Context, key string) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("PTTL"); err != nil {
+		return 0, err
+	}
+	e, ok := f.lookup(key)
+	if !ok {
+		return 0, redis.Nil
+	}
+	if e.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return e.expiresAt.Sub(f.now()), nil
+}
+
+func (f *Redis) Pipelined(ctx context.Context, fn func(p platformRedis.Pipeline) error) error {
+	p := &pipeline{}
+	if err := fn(p); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Pipelined"); err != nil {
+		return err
+	}
+	for _, cmd := range p.cmds {
+		if err := cmd(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Redis) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	f.mu.Lock()
+	if err := f.call("Eval"); err != nil {
+		f.mu.Unlock()
+		return nil, err
+	}
+	fn, ok := f.scripts[script]
+	f.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("redisfake: no handler for script %q", script)
+	}
+	return fn(f, keys, args)
+}
+
+func (f *Redis) Ping(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.call("Ping")
+}
+
+// messages are dropped for subscribers that fall more than a buffer behind, as redis does
+// for slow clients
+func (f *Redis) Publish(ctx context.Context, channel string, message any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Publish"); err != nil {
+		return err
+	}
+	for _, ch := range f.channels[channel] {
+		select {
+		case ch <- toString(message):
+		default:
+		}
+	}
+	return nil
+}
+
+func (f *Redis) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Subscribe"); err != nil {
+		return nil, err
+	}
+	ch := make(chan string, 64)
+	f.channels[channel] = append(f.channels[channel], ch)
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		subs := f.channels[channel]
+		for i, sub := range subs {
+			if sub == ch { // already closed by Disconnect otherwise
+				f.channels[channel] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// end every subscription as a dropped connection would, subscribers have to subscribe again
+func (f *Redis) Disconnect() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for channel, subs := range f.channels {
+		for _, ch := range subs {
+			close(ch)
+		}
+		delete(f.channels, channel)
+	}
+}
+
+func (f *Redis) Close() error { return nil }
+
+// helpers below expect f.mu to be held
+
+func (f *Redis) call(cmd string) error {
+	f.calls[cmd]++
+	for _, fail := range f.failures {
+		if fail.times != 0 && fail.cmd == cmd {
+			if fail.times > 0 {
+				fail.times--
+			}
+			return fail.err
+		}
+	}
+	return nil
+}
+
+func (f *Redis) lookup(key string) (entry, bool) {
+	e, ok := f.values[key]
+	if ok && !e.expiresAt.IsZero() && !f.now().Before(e.expiresAt) {
+		delete(f.values, key)
+		return entry{}, false
+	}
+	return e, ok
+}
+
+func (f *Redis) set(key string, value any, expiration time.Duration) {
+	e := entry{value: toString(value)}
+	if expiration > 0 {
+		e.expiresAt = f.now().Add(expiration)
+	}
+	f.values[key] = e
+}
+
+func (f *Redis) del(keys ...string) int64 {
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := f.lookup(key); ok {
+			delete(f.values, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// keeps the existing expiry, like INCR
+func (f *Redis) incr(key string) (int64, error) {
+	e, _ := f.lookup(key)
+	n := int64(0)
+	if e.value != "" {
+		var err error
+		if n, err = strconv.ParseInt(e.value, 10, 64); err != nil {
+			return 0, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+	}
+	n++
+	e.value = strconv.FormatInt(n, 10)
+	f.values[key] = e
+	return n, nil
+}
+
+func (f *Redis) expire(key string, expiration time.Duration) bool {
+	e, ok := f.lookup(key)
+	if !ok {
+		return false
+	}
+	if expiration <= 0 {
+		delete(f.values, key)
+		return true
+	}
+	e.expiresAt = f.now().Add(expiration)
+	f.values[key] = e
+	return true
+}
+
+type pipeline struct {
+	cmds []func(f *Redis) error
+}
+
+func (p *pipeline) Set(key string, value any, expiration time.Duration) {
+	p.cmds = append(p.cmds, func(f *Redis) error { f.set(key, value, expiration); return nil })
+}
+
+func (p *pipeline) Del(keys ...string) {
+	p.cmds = append(p.cmds, func(f *Redis) error { f.del(keys...); return nil })
+}
+
+func (p *pipeline) Incr(key string) {
+	p.cmds = append(p.cmds, func(f *Redis) error { _, err := f.incr(key); return err })
+}
+
+func (p *pipeline) Expire(key string, expiration time.Duration) {
+	p.cmds = append(p.cmds, func(f *Redis) error { f.expire(key, expiration); return nil })
+}
+
+// same formatting go-redis applies to command arguments for the common types
+func toString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -1,18 +1,22 @@
 package prices
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"log"
 	"strings"
 	"time"
 
 	resty "github.com/go-resty/resty/v2"
+	"github.com/undersleep7x/cryo-project/internal/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // fetch prices from coingecko api
-var FetchPrices = func(cryptos []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+var FetchPrices = func(ctx context.Context, cryptos []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
 	// setup resty client for api call
-	client := resty.New()
+	client := newTracedClient()
 	timeout := time.Duration(timeoutVal) * time.Second
 	client.SetTimeout(timeout)
 
@@ -20,6 +24,37 @@ var FetchPrices = func(cryptos []string, currency string, baseURL string, timeou
 	apiQuery := strings.Join(cryptos, ",")
 	url := fmt.Sprintf("%s/simple/price?ids=%s&vs_currencies=%s", baseURL, apiQuery, currency)
 	log.Printf("Making API call to %s", url)
-	resp, err := client.R().Get(url) // make call to api and return resp
+	start := time.Now()
+	resp, err := client.R().SetContext(ctx).Get(url) // make call to api and return resp
+	observeUpstream("simple_price", start, resp, err)
 	return resp, err
 }
+
+// fetch historical price points for a single crypto between two timestamps from coingecko api
+var FetchPriceRange = func(ctx context.Context, crypto string, currency string, from time.Time, to time.Time, baseURL string, timeoutVal int) (*resty.Response, error) {
+	client := newTracedClient()
+	timeout := time.Duration(timeoutVal) * time.Second
+	client.SetTimeout(timeout)
+
+	url := fmt.Sprintf("%s/coins/%s/market_chart/range?vs_currency=%s&from=%d&to=%d", baseURL, crypto, currency, from.Unix(), to.Unix())
+	log.Printf("Making API call to %s", url)
+	start := time.Now()
+	resp, err := client.R().SetContext(ctx).Get(url)
+	observeUpstream("market_chart_range", start, resp, err)
+	return resp, err
+}
+
+// record latency and outcome of a coingecko call
+func observeUpstream(endpoint string, start time.Time, resp *resty.Response, err error) {
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode()
+	}
+	metrics.UpstreamRequests.WithLabelValues("coingecko", endpoint, metrics.Outcome(statusCode, err)).Inc()
+	metrics.UpstreamDuration.WithLabelValues("coingecko", endpoint).Observe(time.Since(start).Seconds())
+}
+
+// resty client whose requests carry the caller's traceparent and get their own client span
+func newTracedClient() *resty.Client {
+	return resty.New().SetTransport(otelhttp.NewTransport(http.DefaultTransport))
+}
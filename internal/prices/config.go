@@ -1,7 +1,57 @@
 package prices
 
+import (
+	"sync/atomic"
+	"time"
+)
+
 type Config struct {
-	BaseURL       string
-	Timeout       int
-	RetryAttempts int
-}
\ No newline at end of file
+	BaseURL            string
+	Timeout            int
+	RetryAttempts      int
+	CacheTTL           time.Duration // how long fetched prices are served from cache, priceCacheTTL when unset
+	StaleTTL           time.Duration // how long last known prices are kept for provider outages, staleCacheTTL when unset
+	StreamPollInterval time.Duration // how often streamed pairs are refreshed
+	StreamThreshold    float64       // fractional price move required before pushing an update (0.001 = 0.1%)
+}
+
+func (c Config) cacheTTL() time.Duration {
+	if c.CacheTTL <= 0 {
+		return priceCacheTTL
+	}
+	return c.CacheTTL
+}
+
+func (c Config) staleTTL() time.Duration {
+	if c.StaleTTL <= 0 {
+		return staleCacheTTL
+	}
+	return c.StaleTTL
+}
+
+func (c Config) streamPollInterval() time.Duration {
+	if c.StreamPollInterval <= 0 {
+		return defaultStreamPollInterval
+	}
+	return c.StreamPollInterval
+}
+
+// live price settings shared by every price component. a config reload swaps the whole
+// Config at once, so a request that loads it once sees a consistent snapshot
+type Settings struct {
+	current atomic.Pointer[Config]
+}
+
+func NewSettings(cfg Config) *Settings {
+	s := &Settings{}
+	s.current.Store(&cfg)
+	return s
+}
+
+func (s *Settings) Load() Config {
+	return *s.current.Load()
+}
+
+func (s *Settings) Store(cfg Config) {
+	s.current.Store(&cfg)
+}
@@ -0,0 +1,132 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	cryov1 "github.com/undersleep7x/cryo-project/api/proto/cryo/v1"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var protoPriceStatus = map[PriceStatus]cryov1.PriceStatus{
+	StatusOK:            cryov1.PriceStatus_PRICE_STATUS_OK,
+	StatusNotFound:      cryov1.PriceStatus_PRICE_STATUS_NOT_FOUND,
+	StatusUpstreamError: cryov1.PriceStatus_PRICE_STATUS_UPSTREAM_ERROR,
+	StatusStale:         cryov1.PriceStatus_PRICE_STATUS_STALE,
+}
+
+// grpc counterpart of PriceHandler and PriceStreamHandler, sharing their service and streamer
+type PriceGRPCServer struct {
+	cryov1.UnimplementedPriceServiceServer
+	service  FetchCryptoPriceService
+	streamer *PriceStreamer
+	registry assets.Registry
+}
+
+func NewPriceGRPCServer(service FetchCryptoPriceService, streamer *PriceStreamer, registry assets.Registry) *PriceGRPCServer {
+	return &PriceGRPCServer{service: service, streamer: streamer, registry: registry}
+}
+
+func (s *PriceGRPCServer) FetchPrices(ctx context.Context, req *cryov1.FetchPricesRequest) (*cryov1.FetchPricesResponse, error) {
+	if len(req.GetCryptos()) == 0 || req.GetCurrency() == "" {
+		return nil, status.Error(codes.InvalidArgument, "cryptos and currency must be set")
+	}
+	ids, err := s.resolve(req.GetCryptos())
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.service.FetchCryptoPrice(ctx, ids, normalizeCurrency(req.GetCurrency()))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, status.Error(codes.DeadlineExceeded, "request timed out")
+		}
+		log.Printf("Internal Server Error in grpc call: %v", err)
+		return nil, status.Error(codes.Internal, "failed to fetch prices")
+	}
+
+	// keyed by what the client asked for, as in the http response
+	resp := &cryov1.FetchPricesResponse{Currency: result.Currency, Prices: make(map[string]*cryov1.AssetPrice, len(ids))}
+	for i, crypto := range req.GetCryptos() {
+		price, ok := result.Prices[ids[i]]
+		if !ok {
+			price = AssetPrice{Crypto: ids[i], Status: StatusUpstreamError, Error: "no result returned"}
+		}
+		resp.Prices[strings.TrimSpace(crypto)] = assetPriceToProto(price)
+	}
+	return resp, nil
+}
+
+func (s *PriceGRPCServer) StreamPrices(req *cryov1.StreamPricesRequest, stream cryov1.PriceService_StreamPricesServer) error {
+	if len(req.GetCryptos()) == 0 || req.GetCurrency() == "" {
+		return status.Error(codes.InvalidArgument, "cryptos and currency must be set")
+	}
+	if len(req.GetCryptos()) > maxStreamPairs {
+		return status.Error(codes.InvalidArgument, "too many pairs requested")
+	}
+	ids, err := s.resolve(req.GetCryptos())
+	if err != nil {
+		return err
+	}
+
+	updates := make(chan PriceUpdate, subscriberBufferSize)
+	subs := newSubscriptionSet(s.streamer, updates)
+	defer subs.closeAll()
+	currency := normalizeCurrency(req.GetCurrency())
+	for _, id := range ids {
+		subs.add(id, currency)
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case update := <-updates:
+			if err := stream.Send(&cryov1.PriceUpdate{
+				Crypto:    update.Crypto,
+				Currency:  update.Currency,
+				Price:     update.Price,
+				Previous:  update.Previous,
+				ChangedAt: timestamppb.New(update.ChangedAt),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// map requested symbols onto coingecko ids, failing with the unsupported ones listed
+func (s *PriceGRPCServer) resolve(symbols []string) ([]string, error) {
+	ids := make([]string, len(symbols))
+	var unknown []string
+	for i, symbol := range symbols {
+		asset, ok := s.registry.Lookup(symbol)
+		if !ok {
+			unknown = append(unknown, strings.TrimSpace(symbol))
+			continue
+		}
+		ids[i] = asset.CoinGeckoID
+	}
+	if len(unknown) > 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported assets requested: %s", strings.Join(unknown, ", "))
+	}
+	return ids, nil
+}
+
+func assetPriceToProto(price AssetPrice) *cryov1.AssetPrice {
+	out := &cryov1.AssetPrice{
+		Crypto: price.Crypto,
+		Status: protoPriceStatus[price.Status],
+		Price:  price.Price,
+		Source: price.Source,
+		Error:  price.Error,
+	}
+	if price.FetchedAt != nil {
+		out.FetchedAt = timestamppb.New(*price.FetchedAt)
+	}
+	return out
+}
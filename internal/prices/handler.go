@@ -1,19 +1,24 @@
 package prices
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/undersleep7x/cryo-project/internal/assets"
 )
 
 // setup interface for price fetching
 type PriceHandler struct {
 	service FetchCryptoPriceService
+	registry assets.Registry
 }
-func NewPriceHandler (service FetchCryptoPriceService) *PriceHandler {
-	return &PriceHandler{service: service}
+func NewPriceHandler (service FetchCryptoPriceService, registry assets.Registry) *PriceHandler {
+	return &PriceHandler{service: service, registry: registry}
 }
 
 
@@ -34,13 +39,203 @@ func (f *PriceHandler) FetchPrices (c *gin.Context) {
 	}
 
 	cryptoList := strings.Split(cryptos, ",") // csv -> array of cryptos
-	prices, err := f.service.FetchCryptoPrice(cryptoList, currency) // call service to fetch pricing
+	ids, ok := resolveAssets(c, f.registry, cryptoList) // tickers/ids -> provider ids, 400 on unknown assets
+	if !ok {
+		return
+	}
+	prices, err := f.service.FetchCryptoPrice(c.Request.Context(), ids, normalizeCurrency(currency)) // call service to fetch pricing
 	if err != nil {   //return error if service error is thrown
 		log.Printf("Internal Server Error when calling FetchCryptoPrice: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prices"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"prices": prices}) // return prices json
+	// key the response by what the client asked for rather than the provider id, splitting out failures
+	found := gin.H{}
+	failed := gin.H{}
+	allNotFound := true
+	for i, crypto := range cryptoList {
+		price, ok := prices.Prices[ids[i]]
+		if !ok {
+			price = AssetPrice{Crypto: ids[i], Status: StatusUpstreamError, Error: "no result returned"}
+		}
+		if price.Usable() {
+			found[strings.TrimSpace(crypto)] = price
+			continue
+		}
+		if price.Status != StatusNotFound {
+			allNotFound = false
+		}
+		failed[strings.TrimSpace(crypto)] = price
+	}
+
+	// 200 when every asset resolved, 207 for partial success, and an error status when nothing did
+	status := http.StatusOK
+	switch {
+	case len(found) == 0 && allNotFound:
+		status = http.StatusNotFound
+	case len(found) == 0:
+		status = http.StatusBadGateway
+	case len(failed) > 0:
+		status = http.StatusMultiStatus
+	}
+
+	body := gin.H{"currency": prices.Currency, "prices": found}
+	if len(failed) > 0 {
+		body["errors"] = failed
+	}
+	c.JSON(status, body) // return prices json
 
-}
\ No newline at end of file
+}
+
+// map requested symbols onto coingecko ids, writing a 400 with suggestions when any are unsupported
+func resolveAssets(c *gin.Context, registry assets.Registry, symbols []string) ([]string, bool) {
+	ids := make([]string, len(symbols))
+	unknown := gin.H{}
+	for i, symbol := range symbols {
+		asset, ok := registry.Lookup(symbol)
+		if !ok {
+			unknown[strings.TrimSpace(symbol)] = registry.Suggest(symbol, 3)
+			continue
+		}
+		ids[i] = asset.CoinGeckoID
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported asset requested", "suggestions": unknown})
+		return nil, false
+	}
+	return ids, true
+}
+
+// coingecko keys quote currencies in lowercase, so normalize before building cache keys or api paths
+func normalizeCurrency(currency string) string {
+	return strings.ToLower(strings.TrimSpace(currency))
+}
+
+// setup interface for historical price lookups
+type PriceHistoryHandler struct {
+	service PriceHistoryService
+	registry assets.Registry
+}
+func NewPriceHistoryHandler (service PriceHistoryService, registry assets.Registry) *PriceHistoryHandler {
+	return &PriceHistoryHandler{service: service, registry: registry}
+}
+
+// handle /price/history route call and return the price closest to the requested time
+func (f *PriceHistoryHandler) FetchHistoricalPrice (c *gin.Context) {
+	crypto := c.Query("crypto")
+	currency := c.Query("currency")
+
+	if crypto == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'crypto' query parameter"})
+		return
+	}
+	if currency == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'currency' query parameter"})
+		return
+	}
+	at, err := parseTimeParam(c.Query("at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing 'at' query parameter"})
+		return
+	}
+
+	ids, ok := resolveAssets(c, f.registry, []string{crypto})
+	if !ok {
+		return
+	}
+
+	price, err := f.service.FetchHistoricalPrice(c.Request.Context(), ids[0], normalizeCurrency(currency), at)
+	if err != nil {
+		writeHistoryError(c, "FetchHistoricalPrice", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"price": price})
+}
+
+// handle /price/ohlc route call and return candles for the requested window
+func (f *PriceHistoryHandler) FetchOHLC (c *gin.Context) {
+	crypto := c.Query("crypto")
+	currency := c.Query("currency")
+	interval := c.Query("interval")
+
+	if crypto == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'crypto' query parameter"})
+		return
+	}
+	if currency == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'currency' query parameter"})
+		return
+	}
+	if interval == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'interval' query parameter"})
+		return
+	}
+	from, err := parseTimeParam(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing 'from' query parameter"})
+		return
+	}
+	to, err := parseTimeParam(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing 'to' query parameter"})
+		return
+	}
+
+	ids, ok := resolveAssets(c, f.registry, []string{crypto})
+	if !ok {
+		return
+	}
+
+	candles, err := f.service.FetchOHLC(c.Request.Context(), ids[0], normalizeCurrency(currency), interval, from, to)
+	if err != nil {
+		writeHistoryError(c, "FetchOHLC", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"candles": candles})
+}
+
+// accepts RFC3339 timestamps or unix seconds
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("empty timestamp")
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// map service errors onto client or server errors
+func writeHistoryError(c *gin.Context, op string, err error) {
+	switch {
+	case c.Request.Context().Err() != nil: // route deadline hit or client went away mid request
+		log.Printf("%s abandoned: %v", op, err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+	case errors.Is(err, ErrInvalidInterval), errors.Is(err, ErrInvalidRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrPriceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No historical price found"})
+	case errors.Is(err, ErrUpstream):
+		log.Printf("Upstream error when calling %s: %v", op, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch price history"})
+	default:
+		log.Printf("Internal Server Error when calling %s: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+	}
+}
+
+// setup interface for watchlist pre-warm status
+type PrewarmHandler struct {
+	prewarmer *Prewarmer
+}
+func NewPrewarmHandler (prewarmer *Prewarmer) *PrewarmHandler {
+	return &PrewarmHandler{prewarmer: prewarmer}
+}
+
+// handle /price/watchlist route call and return last refresh and failure counts per watched pair
+func (f *PrewarmHandler) FetchStatus (c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"watchlist": f.prewarmer.Status()})
+}
@@ -1,31 +1,41 @@
 package prices
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/undersleep7x/cryo-project/internal/assets"
 )
 
 type mockPriceHandler struct {
 	FetchCryptoPriceService
 }
 
-func (m *mockPriceHandler) FetchCryptoPrice(cryptoList []string, currency string) (map[string]float64, error) {
+func (m *mockPriceHandler) FetchCryptoPrice(ctx context.Context, cryptoList []string, currency string) (*PriceResult, error) {
 	if currency == "se" {
 		return nil, errors.New("No currency provided")
 	}
-	return map[string]float64{"bitcoin": 45000.000, "ethereum": 3200.75}, nil
+	result := &PriceResult{Currency: currency, Prices: map[string]AssetPrice{
+		"bitcoin":  {Crypto: "bitcoin", Status: StatusOK, Price: 45000.000, Source: SourceCache},
+		"ethereum": {Crypto: "ethereum", Status: StatusOK, Price: 3200.75, Source: SourceCoinGecko},
+		"monero":   {Crypto: "monero", Status: StatusNotFound, Error: "no price for monero"},
+		"litecoin": {Crypto: "litecoin", Status: StatusUpstreamError, Error: "provider returned status 429"},
+	}}
+	return result, nil
 }
 
 func TestFetchPrices(t *testing.T) {
 	router := gin.Default()
 	mockService := &mockPriceHandler{}
-	PriceHandler := NewPriceHandler(mockService)
+	registry, _ := assets.NewRegistry(assets.DefaultAssets)
+	PriceHandler := NewPriceHandler(mockService, registry)
 	router.GET("/price", PriceHandler.FetchPrices)
 
 	t.Run("Missing crypto", func(t *testing.T) {
@@ -72,8 +82,69 @@ func TestFetchPrices(t *testing.T) {
 		}
 	
 		assert.NotNil(t, response["prices"])
-		assert.Equal(t, 45000.00, response["prices"].(map[string]any)["bitcoin"])
-		assert.Equal(t, 3200.75, response["prices"].(map[string]any)["ethereum"])
+		assert.Nil(t, response["errors"])
+		assert.Equal(t, 45000.00, response["prices"].(map[string]any)["bitcoin"].(map[string]any)["price"])
+		assert.Equal(t, 3200.75, response["prices"].(map[string]any)["ethereum"].(map[string]any)["price"])
+		assert.Equal(t, "ok", response["prices"].(map[string]any)["ethereum"].(map[string]any)["status"])
+	})
+
+	t.Run("Partial Success", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price?crypto=bitcoin,monero&currency=usd", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+		var response map[string]any
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %v", err)
+		}
+
+		assert.Equal(t, 45000.00, response["prices"].(map[string]any)["bitcoin"].(map[string]any)["price"])
+		assert.Equal(t, "not_found", response["errors"].(map[string]any)["monero"].(map[string]any)["status"])
+		assert.Nil(t, response["errors"].(map[string]any)["monero"].(map[string]any)["price"])
+	})
+
+	t.Run("All Upstream Errors", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price?crypto=litecoin&currency=usd", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+
+	t.Run("Ticker Normalized", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price?crypto=BTC&currency=USD", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]any
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %v", err)
+		}
+
+		assert.Equal(t, 45000.00, response["prices"].(map[string]any)["BTC"].(map[string]any)["price"])
+	})
+
+	t.Run("Unknown Asset", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price?crypto=bitcoinn&currency=usd", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response map[string]any
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %v", err)
+		}
+
+		assert.Equal(t, "Unsupported asset requested", response["error"])
+		assert.Contains(t, response["suggestions"].(map[string]any)["bitcoinn"], "BTC")
 	})
 
 	t.Run("ServiceError", func(t *testing.T) {
@@ -92,4 +163,83 @@ func TestFetchPrices(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 		assert.Equal(t, "Failed to fetch prices", response["error"])
 	})
-}
\ No newline at end of file
+}
+type mockPriceHistoryService struct {
+	PriceHistoryService
+}
+
+func (m *mockPriceHistoryService) FetchHistoricalPrice(ctx context.Context, crypto string, currency string, at time.Time) (*HistoricalPrice, error) {
+	if at.Before(time.Unix(0, 0).Add(time.Hour)) {
+		return nil, ErrPriceNotFound
+	}
+	return &HistoricalPrice{Crypto: crypto, Currency: currency, Price: 45000.00, PricedAt: at, Source: "coingecko"}, nil
+}
+
+func (m *mockPriceHistoryService) FetchOHLC(ctx context.Context, crypto string, currency string, interval string, from time.Time, to time.Time) ([]OHLCCandle, error) {
+	if interval == "3m" {
+		return nil, ErrInvalidInterval
+	}
+	return []OHLCCandle{{Crypto: crypto, Currency: currency, Interval: interval, OpenTime: from, Open: 1, High: 2, Low: 1, Close: 2}}, nil
+}
+
+func TestFetchHistory(t *testing.T) {
+	router := gin.Default()
+	registry, _ := assets.NewRegistry(assets.DefaultAssets)
+	historyHandler := NewPriceHistoryHandler(&mockPriceHistoryService{}, registry)
+	router.GET("/price/history", historyHandler.FetchHistoricalPrice)
+	router.GET("/price/ohlc", historyHandler.FetchOHLC)
+
+	t.Run("Missing At", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price/history?crypto=bitcoin&currency=usd", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("History Success", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price/history?crypto=bitcoin&currency=usd&at=2025-01-01T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]any
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %v", err)
+		}
+		assert.Equal(t, 45000.00, response["price"].(map[string]any)["price"])
+	})
+
+	t.Run("History Not Found", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price/history?crypto=bitcoin&currency=usd&at=60", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("OHLC Invalid Interval", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price/ohlc?crypto=bitcoin&currency=usd&interval=3m&from=1735689600&to=1735700000", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("OHLC Success", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/price/ohlc?crypto=bitcoin&currency=usd&interval=1h&from=1735689600&to=1735700000", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]any
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %v", err)
+		}
+		assert.Len(t, response["candles"], 1)
+	})
+}
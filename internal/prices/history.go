@@ -0,0 +1,288 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	historySource = "coingecko"
+	// coingecko spaces points by the span of the request rather than its age, so the two hour
+	// window a nearest-match lookup fetches comes back hourly. the tolerance is still widened to
+	// a day for old timestamps, where stored history may only hold daily points
+	historyRecentTolerance = time.Hour
+	historyDailyTolerance  = 24 * time.Hour
+	historyDailyCutoff     = 90 * 24 * time.Hour
+	maxCandlesPerRequest   = 500
+
+	// market_chart/range returns 5 minute points for ranges up to a day and hourly points up to
+	// 90 days, but only keeps 5 minute points for the last day
+	historyFiveMinuteSpan = 24 * time.Hour
+	historyHourlySpan     = 90 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidInterval = errors.New("unsupported candle interval")
+	ErrInvalidRange    = errors.New("invalid time range")
+	ErrPriceNotFound   = errors.New("no historical price found")
+	ErrUpstream        = errors.New("price provider request failed")
+)
+
+// supported candle intervals and their bucket widths
+var candleIntervals = map[string]time.Duration{
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"4h": 4 * time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+type HistoricalPrice struct {
+	Crypto   string    `json:"crypto"`
+	Currency string    `json:"currency"`
+	Price    float64   `json:"price"`
+	PricedAt time.Time `json:"priced_at"`
+	Source   string    `json:"source"`
+}
+
+type OHLCCandle struct {
+	Crypto   string    `json:"crypto"`
+	Currency string    `json:"currency"`
+	Interval string    `json:"interval"`
+	OpenTime time.Time `json:"open_time"`
+	Open     float64   `json:"open"`
+	High     float64   `json:"high"`
+	Low      float64   `json:"low"`
+	Close    float64   `json:"close"`
+	Source   string    `json:"source"`
+}
+
+type PriceHistoryService interface {
+	FetchHistoricalPrice(ctx context.Context, crypto string, currency string, at time.Time) (*HistoricalPrice, error)
+	FetchOHLC(ctx context.Context, crypto string, currency string, interval string, from time.Time, to time.Time) ([]OHLCCandle, error)
+}
+
+type priceHistoryServiceImpl struct {
+	repo     PriceHistoryRepository
+	settings *Settings
+	now      func() time.Time
+}
+
+func NewPriceHistoryService(repo PriceHistoryRepository, settings *Settings) PriceHistoryService {
+	return &priceHistoryServiceImpl{repo: repo, settings: settings, now: time.Now}
+}
+
+// returns the price closest to the requested time, checking stored history before calling the provider
+func (s *priceHistoryServiceImpl) FetchHistoricalPrice(ctx context.Context, crypto string, currency string, at time.Time) (*HistoricalPrice, error) {
+	now := s.now().UTC()
+	at = at.UTC()
+	if at.After(now) {
+		return nil, fmt.Errorf("%w: timestamp is in the future", ErrInvalidRange)
+	}
+
+	tolerance := historyRecentTolerance
+	if now.Sub(at) > historyDailyCutoff {
+		tolerance = historyDailyTolerance
+	}
+
+	stored, err := s.repo.FindNearestPrice(ctx, crypto, currency, at, tolerance)
+	if err != nil {
+		log.Printf("Price history lookup failed for %s, fetching with API: %v", crypto, err)
+	} else if stored != nil {
+		log.Printf("Successfully retrieved stored historical price for %s", crypto)
+		return stored, nil
+	}
+
+	to := at.Add(tolerance)
+	if to.After(now) {
+		to = now
+	}
+	points, err := s.fetchPricePoints(ctx, crypto, currency, at.Add(-tolerance), to)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SavePricePoints(ctx, points); err != nil {
+		log.Printf("Failed to persist price history for %s: %v", crypto, err)
+	}
+
+	nearest := nearestPricePoint(points, at)
+	if nearest == nil || absDuration(nearest.PricedAt.Sub(at)) > tolerance {
+		return nil, ErrPriceNotFound
+	}
+	return nearest, nil
+}
+
+// returns candles for the requested window, only calling the provider when stored candles don't cover it
+func (s *priceHistoryServiceImpl) FetchOHLC(ctx context.Context, crypto string, currency string, interval string, from time.Time, to time.Time) ([]OHLCCandle, error) {
+	width, ok := candleIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidInterval, interval)
+	}
+
+	now := s.now().UTC()
+	from = from.UTC().Truncate(width)
+	to = to.UTC()
+	if to.After(now) {
+		to = now
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: 'from' must be before 'to'", ErrInvalidRange)
+	}
+	buckets := int(math.Ceil(float64(to.Sub(from)) / float64(width)))
+	if buckets > maxCandlesPerRequest {
+		return nil, fmt.Errorf("%w: range spans %d candles, max is %d", ErrInvalidRange, buckets, maxCandlesPerRequest)
+	}
+	// points are fetched to the end of the last bucket, a 'to' partway through a bucket that has
+	// already closed would otherwise store that candle built from only some of its points
+	fetchTo := from.Add(time.Duration(buckets) * width)
+	if fetchTo.After(now) {
+		fetchTo = now
+	}
+	// candles narrower than the provider's points would be sparse or built from a single point
+	if granularity := upstreamGranularity(from, fetchTo, now); width < granularity {
+		return nil, fmt.Errorf("%w: %s candles need %s, provider only has %s points for this range", ErrInvalidRange, interval, rangeLimitFor(width), granularity)
+	}
+
+	// only closed buckets are persisted, so a fully stored window is one where every closed bucket is present
+	closedBuckets := 0
+	for open := from; open.Before(to); open = open.Add(width) {
+		if !open.Add(width).After(now) {
+			closedBuckets++
+		}
+	}
+
+	stored, err := s.repo.FindCandles(ctx, crypto, currency, interval, from, to)
+	if err != nil {
+		log.Printf("Candle lookup failed for %s, fetching with API: %v", crypto, err)
+	} else if closedBuckets == buckets && len(stored) == buckets {
+		log.Printf("Successfully retrieved stored candles for %s", crypto)
+		return stored, nil
+	}
+
+	points, err := s.fetchPricePoints(ctx, crypto, currency, from, fetchTo)
+	if err != nil {
+		return nil, err
+	}
+	candles := buildCandles(points, interval, width)
+
+	// buckets with no source points are never built, so they're fetched again on the next request
+	var closed []OHLCCandle
+	for _, c := range candles {
+		if !c.OpenTime.Add(width).After(now) {
+			closed = append(closed, c)
+		}
+	}
+	if err := s.repo.SaveCandles(ctx, closed); err != nil {
+		log.Printf("Failed to persist candles for %s: %v", crypto, err)
+	}
+	return candles, nil
+}
+
+// calls the provider history api and parses the [timestamp_ms, price] pairs it returns
+func (s *priceHistoryServiceImpl) fetchPricePoints(ctx context.Context, crypto string, currency string, from time.Time, to time.Time) ([]HistoricalPrice, error) {
+	cfg := s.settings.Load()
+	resp, err := FetchPriceRange(ctx, crypto, currency, from, to, cfg.BaseURL, cfg.Timeout)
+	if err != nil {
+		log.Printf("API failure fetching price history for %s: %v", crypto, err)
+		return nil, fmt.Errorf("%w: %w", ErrUpstream, err) // keeps context errors visible to the handler
+	}
+	if resp.IsError() {
+		log.Printf("API returned status %d fetching price history for %s", resp.StatusCode(), crypto)
+		return nil, fmt.Errorf("%w: status %d", ErrUpstream, resp.StatusCode())
+	}
+
+	var points []HistoricalPrice
+	for _, pair := range gjson.Get(resp.String(), "prices").Array() {
+		values := pair.Array()
+		if len(values) != 2 {
+			continue
+		}
+		points = append(points, HistoricalPrice{
+			Crypto:   crypto,
+			Currency: currency,
+			Price:    values[1].Float(),
+			PricedAt: time.UnixMilli(values[0].Int()).UTC(),
+			Source:   historySource,
+		})
+	}
+	if len(points) == 0 {
+		return nil, ErrPriceNotFound
+	}
+	return points, nil
+}
+
+func nearestPricePoint(points []HistoricalPrice, at time.Time) *HistoricalPrice {
+	var nearest *HistoricalPrice
+	for i := range points {
+		if nearest == nil || absDuration(points[i].PricedAt.Sub(at)) < absDuration(nearest.PricedAt.Sub(at)) {
+			nearest = &points[i]
+		}
+	}
+	return nearest
+}
+
+// groups price points into interval buckets, ordered by open time
+func buildCandles(points []HistoricalPrice, interval string, width time.Duration) []OHLCCandle {
+	sorted := make([]HistoricalPrice, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PricedAt.Before(sorted[j].PricedAt) })
+
+	var candles []OHLCCandle
+	for _, p := range sorted {
+		open := p.PricedAt.Truncate(width)
+		if n := len(candles); n > 0 && candles[n-1].OpenTime.Equal(open) {
+			c := &candles[n-1]
+			c.High = math.Max(c.High, p.Price)
+			c.Low = math.Min(c.Low, p.Price)
+			c.Close = p.Price
+			continue
+		}
+		candles = append(candles, OHLCCandle{
+			Crypto:   p.Crypto,
+			Currency: p.Currency,
+			Interval: interval,
+			OpenTime: open,
+			Open:     p.Price,
+			High:     p.Price,
+			Low:      p.Price,
+			Close:    p.Price,
+			Source:   p.Source,
+		})
+	}
+	return candles
+}
+
+// spacing of the points the provider returns for a range. it follows the span of the range,
+// except that ranges reaching back more than a day fall back to hourly points
+func upstreamGranularity(from time.Time, to time.Time, now time.Time) time.Duration {
+	span := to.Sub(from)
+	switch {
+	case span <= historyFiveMinuteSpan && now.Sub(from) <= historyFiveMinuteSpan:
+		return 5 * time.Minute
+	case span <= historyHourlySpan:
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// ranges the provider still returns points at least as fine as width for
+func rangeLimitFor(width time.Duration) string {
+	if width < time.Hour {
+		return fmt.Sprintf("a range within the last %s", historyFiveMinuteSpan)
+	}
+	return fmt.Sprintf("a range of at most %s", historyHourlySpan)
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,121 @@
+package prices
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	platformPostgres "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
+)
+
+// persistence for historical price points and candles so settled lookups never hit the provider twice
+type PriceHistoryRepository interface {
+	SavePricePoints(ctx context.Context, points []HistoricalPrice) error
+	FindNearestPrice(ctx context.Context, crypto string, currency string, at time.Time, tolerance time.Duration) (*HistoricalPrice, error)
+	SaveCandles(ctx context.Context, candles []OHLCCandle) error
+	FindCandles(ctx context.Context, crypto string, currency string, interval string, from time.Time, to time.Time) ([]OHLCCandle, error)
+}
+
+type priceHistoryRepository struct {
+	db platformPostgres.Querier
+}
+
+// db is the client, or a transaction when the writes are part of a larger unit of work
+func NewPriceHistoryRepository(db platformPostgres.Querier) PriceHistoryRepository {
+	return &priceHistoryRepository{db: db}
+}
+
+func (r *priceHistoryRepository) SavePricePoints(ctx context.Context, points []HistoricalPrice) error {
+	if len(points) == 0 {
+		return nil
+	}
+	return r.db.WithTx(ctx, func(tx platformPostgres.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO price_history (crypto, currency, price, priced_at, source)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (crypto, currency, priced_at) DO NOTHING`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare price history insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, p := range points {
+			if _, err := stmt.ExecContext(ctx, p.Crypto, p.Currency, p.Price, p.PricedAt.UTC(), p.Source); err != nil {
+				return fmt.Errorf("failed to insert price point for %s: %w", p.Crypto, err)
+			}
+		}
+		return nil
+	})
+}
+
+// returns the stored price closest to the requested time, or nil when nothing is stored within tolerance
+func (r *priceHistoryRepository) FindNearestPrice(ctx context.Context, crypto string, currency string, at time.Time, tolerance time.Duration) (*HistoricalPrice, error) {
+	at = at.UTC()
+	row := r.db.QueryRowContext(ctx, `
+		SELECT crypto, currency, price, priced_at, source
+		FROM price_history
+		WHERE crypto = $1 AND currency = $2 AND priced_at BETWEEN $3 AND $4
+		ORDER BY ABS(EXTRACT(EPOCH FROM (priced_at - $5::timestamp)))
+		LIMIT 1`,
+		crypto, currency, at.Add(-tolerance), at.Add(tolerance), at)
+
+	var p HistoricalPrice
+	err := row.Scan(&p.Crypto, &p.Currency, &p.Price, &p.PricedAt, &p.Source)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	p.PricedAt = p.PricedAt.UTC()
+	return &p, nil
+}
+
+func (r *priceHistoryRepository) SaveCandles(ctx context.Context, candles []OHLCCandle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+	return r.db.WithTx(ctx, func(tx platformPostgres.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO price_ohlc (crypto, currency, candle_interval, open_time, open, high, low, close, source)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (crypto, currency, candle_interval, open_time) DO NOTHING`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare candle insert: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, c := range candles {
+			if _, err := stmt.ExecContext(ctx, c.Crypto, c.Currency, c.Interval, c.OpenTime.UTC(), c.Open, c.High, c.Low, c.Close, c.Source); err != nil {
+				return fmt.Errorf("failed to insert candle for %s: %w", c.Crypto, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *priceHistoryRepository) FindCandles(ctx context.Context, crypto string, currency string, interval string, from time.Time, to time.Time) ([]OHLCCandle, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT crypto, currency, candle_interval, open_time, open, high, low, close, source
+		FROM price_ohlc
+		WHERE crypto = $1 AND currency = $2 AND candle_interval = $3 AND open_time >= $4 AND open_time < $5
+		ORDER BY open_time`,
+		crypto, currency, interval, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query candles: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []OHLCCandle
+	for rows.Next() {
+		var c OHLCCandle
+		if err := rows.Scan(&c.Crypto, &c.Currency, &c.Interval, &c.OpenTime, &c.Open, &c.High, &c.Low, &c.Close, &c.Source); err != nil {
+			return nil, fmt.Errorf("failed to scan candle: %w", err)
+		}
+		c.OpenTime = c.OpenTime.UTC()
+		candles = append(candles, c)
+	}
+	return candles, rows.Err()
+}
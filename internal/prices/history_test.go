@@ -0,0 +1,277 @@
+package prices
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	platformPostgres "github.com/undersleep7x/cryo-project/internal/platform/postgresstore"
+	"github.com/undersleep7x/cryo-project/internal/platform/postgresstore/pgfake"
+)
+
+// mock price history repository for testing
+type MockHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockHistoryRepository) SavePricePoints(ctx context.Context, points []HistoricalPrice) error {
+	args := m.Mock.Called(ctx, points)
+	return args.Error(0)
+}
+func (m *MockHistoryRepository) FindNearestPrice(ctx context.Context, crypto string, currency string, at time.Time, tolerance time.Duration) (*HistoricalPrice, error) {
+	args := m.Mock.Called(ctx, crypto, currency, at, tolerance)
+	price, _ := args.Get(0).(*HistoricalPrice)
+	return price, args.Error(1)
+}
+func (m *MockHistoryRepository) SaveCandles(ctx context.Context, candles []OHLCCandle) error {
+	args := m.Mock.Called(ctx, candles)
+	return args.Error(0)
+}
+func (m *MockHistoryRepository) FindCandles(ctx context.Context, crypto string, currency string, interval string, from time.Time, to time.Time) ([]OHLCCandle, error) {
+	args := m.Mock.Called(ctx, crypto, currency, interval, from, to)
+	candles, _ := args.Get(0).([]OHLCCandle)
+	return candles, args.Error(1)
+}
+
+func TestFetchHistoricalPrice(t *testing.T) {
+	testConfig := Config{BaseURL: "https://dummy-coingecko.com", Timeout: 5}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := now.Add(-48 * time.Hour)
+
+	t.Run("Stored Hit", func(t *testing.T) {
+		mockRepo := new(MockHistoryRepository)
+		service := &priceHistoryServiceImpl{repo: mockRepo, settings: NewSettings(testConfig), now: func() time.Time { return now }}
+
+		stored := &HistoricalPrice{Crypto: "bitcoin", Currency: "usd", Price: 61000, PricedAt: at, Source: historySource}
+		mockRepo.Mock.On("FindNearestPrice", mock.Anything, "bitcoin", "usd", at, historyRecentTolerance).Return(stored, nil)
+
+		// provider should never be called when history is stored
+		originalFetchPriceRange := FetchPriceRange
+		defer func() { FetchPriceRange = originalFetchPriceRange }()
+		FetchPriceRange = func(ctx context.Context, crypto string, currency string, from time.Time, to time.Time, baseURL string, timeoutVal int) (*resty.Response, error) {
+			t.Fatal("unexpected upstream call")
+			return nil, nil
+		}
+
+		price, err := service.FetchHistoricalPrice(context.Background(), "bitcoin", "usd", at)
+		assert.NoError(t, err)
+		assert.Equal(t, 61000.00, price.Price)
+	})
+
+	t.Run("Stored Miss - API Success", func(t *testing.T) {
+		mockRepo := new(MockHistoryRepository)
+		service := &priceHistoryServiceImpl{repo: mockRepo, settings: NewSettings(testConfig), now: func() time.Time { return now }}
+
+		mockRepo.Mock.On("FindNearestPrice", mock.Anything, "bitcoin", "usd", at, historyRecentTolerance).Return(nil, nil)
+		mockRepo.Mock.On("SavePricePoints", mock.Anything, mock.Anything).Return(nil)
+
+		originalFetchPriceRange := FetchPriceRange
+		defer func() { FetchPriceRange = originalFetchPriceRange }()
+		FetchPriceRange = func(ctx context.Context, crypto string, currency string, from time.Time, to time.Time, baseURL string, timeoutVal int) (*resty.Response, error) {
+			dummyResponse := &resty.Response{}
+			dummyResponse.SetBody([]byte(`{"prices":[[` +
+				formatMillis(at.Add(-20*time.Minute)) + `,60000],[` +
+				formatMillis(at.Add(5*time.Minute)) + `,60500],[` +
+				formatMillis(at.Add(40*time.Minute)) + `,61000]]}`))
+			return dummyResponse, nil
+		}
+
+		price, err := service.FetchHistoricalPrice(context.Background(), "bitcoin", "usd", at)
+		assert.NoError(t, err)
+		assert.Equal(t, 60500.00, price.Price)
+		mockRepo.Mock.AssertCalled(t, "SavePricePoints", mock.Anything, mock.MatchedBy(func(points []HistoricalPrice) bool { return len(points) == 3 }))
+	})
+
+	t.Run("Caller Cancelled", func(t *testing.T) {
+		mockRepo := new(MockHistoryRepository)
+		service := &priceHistoryServiceImpl{repo: mockRepo, settings: NewSettings(testConfig), now: func() time.Time { return now }}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		mockRepo.Mock.On("FindNearestPrice", ctx, "bitcoin", "usd", at, historyRecentTolerance).Return(nil, context.Canceled)
+
+		// the provider call must see the caller's context rather than a fresh one
+		originalFetchPriceRange := FetchPriceRange
+		defer func() { FetchPriceRange = originalFetchPriceRange }()
+		FetchPriceRange = func(ctx context.Context, crypto string, currency string, from time.Time, to time.Time, baseURL string, timeoutVal int) (*resty.Response, error) {
+			return nil, ctx.Err()
+		}
+
+		_, err := service.FetchHistoricalPrice(ctx, "bitcoin", "usd", at)
+		assert.ErrorIs(t, err, ErrUpstream)
+		assert.ErrorIs(t, err, context.Canceled)
+		mockRepo.Mock.AssertNotCalled(t, "SavePricePoints", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Future Timestamp", func(t *testing.T) {
+		service := &priceHistoryServiceImpl{repo: new(MockHistoryRepository), settings: NewSettings(testConfig), now: func() time.Time { return now }}
+
+		_, err := service.FetchHistoricalPrice(context.Background(), "bitcoin", "usd", now.Add(time.Hour))
+		assert.ErrorIs(t, err, ErrInvalidRange)
+	})
+}
+
+func TestFetchOHLC(t *testing.T) {
+	testConfig := Config{BaseURL: "https://dummy-coingecko.com", Timeout: 5}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	from := now.Add(-3 * time.Hour)
+	to := now.Add(-time.Hour)
+
+	t.Run("Invalid Interval", func(t *testing.T) {
+		service := &priceHistoryServiceImpl{repo: new(MockHistoryRepository), settings: NewSettings(testConfig), now: func() time.Time { return now }}
+
+		_, err := service.FetchOHLC(context.Background(), "bitcoin", "usd", "3m", from, to)
+		assert.ErrorIs(t, err, ErrInvalidInterval)
+	})
+
+	t.Run("Stored Hit", func(t *testing.T) {
+		mockRepo := new(MockHistoryRepository)
+		service := &priceHistoryServiceImpl{repo: mockRepo, settings: NewSettings(testConfig), now: func() time.Time { return now }}
+
+		stored := []OHLCCandle{{OpenTime: from, Close: 1}, {OpenTime: from.Add(time.Hour), Close: 2}}
+		mockRepo.Mock.On("FindCandles", mock.Anything, "bitcoin", "usd", "1h", from, to).Return(stored, nil)
+
+		candles, err := service.FetchOHLC(context.Background(), "bitcoin", "usd", "1h", from, to)
+		assert.NoError(t, err)
+		assert.Equal(t, stored, candles)
+	})
+
+	t.Run("Stored Miss - API Success", func(t *testing.T) {
+		mockRepo := new(MockHistoryRepository)
+		service := &priceHistoryServiceImpl{repo: mockRepo, settings: NewSettings(testConfig), now: func() time.Time { return now }}
+
+		mockRepo.Mock.On("FindCandles", mock.Anything, "bitcoin", "usd", "1h", from, to).Return(nil, nil)
+		mockRepo.Mock.On("SaveCandles", mock.Anything, mock.Anything).Return(nil)
+
+		originalFetchPriceRange := FetchPriceRange
+		defer func() { FetchPriceRange = originalFetchPriceRange }()
+		FetchPriceRange = func(ctx context.Context, crypto string, currency string, from time.Time, to time.Time, baseURL string, timeoutVal int) (*resty.Response, error) {
+			dummyResponse := &resty.Response{}
+			dummyResponse.SetBody([]byte(`{"prices":[[` +
+				formatMillis(from.Add(10*time.Minute)) + `,100],[` +
+				formatMillis(from.Add(30*time.Minute)) + `,120],[` +
+				formatMillis(from.Add(50*time.Minute)) + `,90],[` +
+				formatMillis(from.Add(70*time.Minute)) + `,95]]}`))
+			return dummyResponse, nil
+		}
+
+		candles, err := service.FetchOHLC(context.Background(), "bitcoin", "usd", "1h", from, to)
+		assert.NoError(t, err)
+		assert.Len(t, candles, 2)
+		assert.Equal(t, 100.00, candles[0].Open)
+		assert.Equal(t, 120.00, candles[0].High)
+		assert.Equal(t, 90.00, candles[0].Low)
+		assert.Equal(t, 90.00, candles[0].Close)
+		assert.Equal(t, 95.00, candles[1].Open)
+	})
+
+	t.Run("Interval Finer Than Provider Points", func(t *testing.T) {
+		service := &priceHistoryServiceImpl{repo: new(MockHistoryRepository), settings: NewSettings(testConfig), now: func() time.Time { return now }}
+
+		// ranges over a day only come back as hourly points
+		_, err := service.FetchOHLC(context.Background(), "bitcoin", "usd", "5m", now.Add(-36*time.Hour), now)
+		assert.ErrorIs(t, err, ErrInvalidRange)
+	})
+
+	t.Run("Five Minute Candles Older Than A Day", func(t *testing.T) {
+		service := &priceHistoryServiceImpl{repo: new(MockHistoryRepository), settings: NewSettings(testConfig), now: func() time.Time { return now }}
+
+		// a short range, but 5 minute points past the last day are already gone
+		_, err := service.FetchOHLC(context.Background(), "bitcoin", "usd", "5m", now.Add(-72*time.Hour), now.Add(-71*time.Hour))
+		assert.ErrorIs(t, err, ErrInvalidRange)
+		assert.ErrorContains(t, err, "within the last 24h0m0s")
+	})
+
+	t.Run("Range Ends Mid Bucket", func(t *testing.T) {
+		mockRepo := new(MockHistoryRepository)
+		service := &priceHistoryServiceImpl{repo: mockRepo, settings: NewSettings(testConfig), now: func() time.Time { return now }}
+		midBucket := to.Add(-30 * time.Minute) // the 10:00 bucket closed an hour before now
+
+		mockRepo.Mock.On("FindCandles", mock.Anything, "bitcoin", "usd", "1h", from, midBucket).Return(nil, nil)
+		mockRepo.Mock.On("SaveCandles", mock.Anything, mock.Anything).Return(nil)
+
+		originalFetchPriceRange := FetchPriceRange
+		defer func() { FetchPriceRange = originalFetchPriceRange }()
+		var fetchedTo time.Time
+		FetchPriceRange = func(ctx context.Context, crypto string, currency string, rangeFrom time.Time, rangeTo time.Time, baseURL string, timeoutVal int) (*resty.Response, error) {
+			fetchedTo = rangeTo
+			dummyResponse := &resty.Response{}
+			dummyResponse.SetBody([]byte(`{"prices":[[` +
+				formatMillis(from.Add(10*time.Minute)) + `,100],[` +
+				formatMillis(from.Add(70*time.Minute)) + `,95],[` +
+				formatMillis(from.Add(110*time.Minute)) + `,130]]}`))
+			return dummyResponse, nil
+		}
+
+		candles, err := service.FetchOHLC(context.Background(), "bitcoin", "usd", "1h", from, midBucket)
+		assert.NoError(t, err)
+		assert.Equal(t, to, fetchedTo)
+		saved := mockRepo.Calls[1].Arguments.Get(1).([]OHLCCandle)
+		require.Len(t, saved, 2)
+		assert.Equal(t, candles, saved)
+		assert.Equal(t, 130.00, saved[1].High) // the point after midBucket still belongs to the stored candle
+		assert.Equal(t, 130.00, saved[1].Close)
+	})
+
+	t.Run("Gaps Not Persisted", func(t *testing.T) {
+		mockRepo := new(MockHistoryRepository)
+		service := &priceHistoryServiceImpl{repo: mockRepo, settings: NewSettings(testConfig), now: func() time.Time { return now }}
+		windowFrom := now.Add(-3 * time.Hour)
+
+		mockRepo.Mock.On("FindCandles", mock.Anything, "bitcoin", "usd", "1h", windowFrom, now).Return(nil, nil)
+		mockRepo.Mock.On("SaveCandles", mock.Anything, mock.Anything).Return(nil)
+
+		originalFetchPriceRange := FetchPriceRange
+		defer func() { FetchPriceRange = originalFetchPriceRange }()
+		FetchPriceRange = func(ctx context.Context, crypto string, currency string, from time.Time, to time.Time, baseURL string, timeoutVal int) (*resty.Response, error) {
+			dummyResponse := &resty.Response{}
+			dummyResponse.SetBody([]byte(`{"prices":[[` +
+				formatMillis(from.Add(10*time.Minute)) + `,100],[` +
+				formatMillis(from.Add(130*time.Minute)) + `,110]]}`))
+			return dummyResponse, nil
+		}
+
+		candles, err := service.FetchOHLC(context.Background(), "bitcoin", "usd", "1h", windowFrom, now)
+		assert.NoError(t, err)
+		assert.Len(t, candles, 2)
+		saved := mockRepo.Calls[1].Arguments.Get(1).([]OHLCCandle)
+		require.Len(t, saved, 2) // the empty middle hour isn't stored as a candle
+		assert.Equal(t, windowFrom, saved[0].OpenTime)
+		assert.Equal(t, windowFrom.Add(2*time.Hour), saved[1].OpenTime)
+	})
+}
+
+func formatMillis(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+func TestPriceHistoryRepositoryUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	points := []HistoricalPrice{{Crypto: "bitcoin", Currency: "usd", Price: 46000, PricedAt: time.Now(), Source: SourceCoinGecko}}
+
+	t.Run("Standalone Runs Own Transaction", func(t *testing.T) {
+		fake, client := pgfake.New()
+		assert.NoError(t, NewPriceHistoryRepository(client).SavePricePoints(ctx, points))
+		statements := fake.Statements()
+		assert.Equal(t, "BEGIN", statements[0])
+		assert.Equal(t, "COMMIT", statements[len(statements)-1])
+	})
+
+	t.Run("Inside Unit Of Work Uses Savepoint", func(t *testing.T) {
+		fake, client := pgfake.New()
+		err := client.WithTx(ctx, func(tx platformPostgres.Tx) error {
+			return NewPriceHistoryRepository(tx).SavePricePoints(ctx, points)
+		})
+		assert.NoError(t, err)
+		statements := fake.Statements()
+		assert.Equal(t, "SAVEPOINT sp_1", statements[1])
+		assert.Equal(t, "RELEASE SAVEPOINT sp_1", statements[len(statements)-2])
+		assert.Equal(t, 1, strings.Count(strings.Join(statements, "\n"), "BEGIN"))
+	})
+}
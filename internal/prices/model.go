@@ -0,0 +1,67 @@
+package prices
+
+import "time"
+
+// per-asset outcome of a price lookup
+type PriceStatus string
+
+const (
+	StatusOK            PriceStatus = "ok"             // fresh price from cache or provider
+	StatusNotFound      PriceStatus = "not_found"      // provider doesn't know the asset/currency pair
+	StatusUpstreamError PriceStatus = "upstream_error" // provider call failed and nothing usable was cached
+	StatusStale         PriceStatus = "stale"          // provider call failed, last known price served instead
+)
+
+const (
+	SourceCache     = "cache"
+	SourceCoinGecko = "coingecko"
+)
+
+type AssetPrice struct {
+	Crypto    string      `json:"crypto"`
+	Status    PriceStatus `json:"status"`
+	Price     float64     `json:"price,omitempty"`
+	Source    string      `json:"source,omitempty"`
+	FetchedAt *time.Time  `json:"fetched_at,omitempty"` // when the price was fetched from the provider
+	Error     string      `json:"error,omitempty"`
+}
+
+// usable reports whether the entry carries a price, fresh or stale
+func (a AssetPrice) Usable() bool {
+	return a.Status == StatusOK || a.Status == StatusStale
+}
+
+type PriceResult struct {
+	Currency string                `json:"currency"`
+	Prices   map[string]AssetPrice `json:"prices"` // keyed by provider id
+}
+
+func newPriceResult(currency string) *PriceResult {
+	return &PriceResult{Currency: currency, Prices: make(map[string]AssetPrice)}
+}
+
+// fresh price for a crypto, false when the lookup failed or only a stale price is available
+func (r *PriceResult) Price(crypto string) (float64, bool) {
+	p, ok := r.Prices[crypto]
+	if !ok || p.Status != StatusOK {
+		return 0, false
+	}
+	return p.Price, true
+}
+
+// true when at least one asset failed to resolve to a usable price
+func (r *PriceResult) Partial() bool {
+	for _, p := range r.Prices {
+		if !p.Usable() {
+			return true
+		}
+	}
+	return false
+}
+
+// cache entry format, carrying fetch metadata so cache hits report the original fetch time
+type cachedPrice struct {
+	Price     float64   `json:"price"`
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
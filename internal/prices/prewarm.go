@@ -0,0 +1,202 @@
+package prices
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultPrewarmLead        = 5 * time.Second
+	defaultPrewarmMinInterval = 2 * time.Second
+	maxPrewarmBackoff         = 5 * time.Minute
+)
+
+type WatchPair struct {
+	Crypto   string `json:"crypto"` // provider id
+	Currency string `json:"currency"`
+}
+
+type PrewarmConfig struct {
+	Watchlist   []WatchPair
+	Lead        time.Duration // refresh this long before cached prices expire
+	MinInterval time.Duration // floor between upstream calls to stay inside provider rate limits
+}
+
+// refresh state for a single watched pair
+type PrewarmStatus struct {
+	WatchPair
+	LastRefresh         *time.Time `json:"last_refresh,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	Failures            int        `json:"failures"`             // total failed refreshes since startup
+	ConsecutiveFailures int        `json:"consecutive_failures"` // reset on the next successful refresh
+}
+
+// keeps watched pairs warm in the price cache with one batched provider call per refresh
+type Prewarmer struct {
+	service *fetchCryptoPriceServiceImpl
+	config  PrewarmConfig
+
+	mu       sync.Mutex
+	status   map[WatchPair]*PrewarmStatus
+	failures int // consecutive failed batches, drives backoff
+}
+
+func NewPrewarmer(cache PricesCache, settings *Settings, prewarmCfg PrewarmConfig) *Prewarmer {
+	status := make(map[WatchPair]*PrewarmStatus, len(prewarmCfg.Watchlist))
+	for _, pair := range prewarmCfg.Watchlist {
+		status[pair] = &PrewarmStatus{WatchPair: pair}
+	}
+	return &Prewarmer{
+		service: &fetchCryptoPriceServiceImpl{Cache: cache, settings: settings},
+		config:  prewarmCfg,
+		status:  status,
+	}
+}
+
+// run the refresh loop in the background until ctx is cancelled
+func (p *Prewarmer) Start(ctx context.Context) {
+	if len(p.config.Watchlist) == 0 {
+		log.Println("Price watchlist empty, pre-warming disabled")
+		return
+	}
+	log.Printf("Pre-warming %d watched price pairs", len(p.config.Watchlist))
+	go func() {
+		var delay time.Duration
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = p.Refresh(ctx)
+		}
+	}()
+}
+
+// fetch every watched pair in one provider call and cache the results, returning the delay before the next refresh
+func (p *Prewarmer) Refresh(ctx context.Context) time.Duration {
+	cfg := p.service.settings.Load()
+	ids, currencies := p.batch()
+	resp, err := FetchPrices(ctx, ids, strings.Join(currencies, ","), cfg.BaseURL, cfg.Timeout)
+	if err == nil && resp.IsError() {
+		err = fmt.Errorf("provider returned status %d", resp.StatusCode())
+	}
+	if err != nil {
+		log.Printf("Price pre-warm failed: %v", err)
+		backoff := p.recordBatchFailure(cfg, err)
+		if resp != nil && resp.StatusCode() == http.StatusTooManyRequests {
+			if retryAfter, convErr := strconv.Atoi(resp.Header().Get("Retry-After")); convErr == nil && time.Duration(retryAfter)*time.Second > backoff {
+				backoff = time.Duration(retryAfter) * time.Second
+			}
+		}
+		return backoff
+	}
+
+	fetchedAt := time.Now().UTC()
+	for _, pair := range p.config.Watchlist {
+		price := gjson.Get(resp.String(), fmt.Sprintf("%s.%s", pair.Crypto, pair.Currency))
+		if !price.Exists() {
+			p.recordFailure(pair, fmt.Errorf("no %s price for %s", pair.Currency, pair.Crypto))
+			continue
+		}
+		p.service.cachePrice(ctx, cfg, pair.Crypto, pair.Currency, cachedPrice{Price: price.Float(), Source: SourceCoinGecko, FetchedAt: fetchedAt})
+		p.recordSuccess(pair, fetchedAt)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures = 0
+	return p.interval(cfg)
+}
+
+// change refresh timing on a running prewarmer, picked up from the next refresh
+func (p *Prewarmer) SetTiming(lead time.Duration, minInterval time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config.Lead = lead
+	p.config.MinInterval = minInterval
+}
+
+// delay between successful refreshes, callers hold p.mu
+func (p *Prewarmer) interval(cfg Config) time.Duration {
+	lead, minInterval := p.config.Lead, p.config.MinInterval
+	if lead <= 0 || lead >= cfg.cacheTTL() {
+		lead = defaultPrewarmLead
+	}
+	if minInterval <= 0 {
+		minInterval = defaultPrewarmMinInterval
+	}
+	return max(cfg.cacheTTL()-lead, minInterval)
+}
+
+// snapshot of refresh state for every watched pair
+func (p *Prewarmer) Status() []PrewarmStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]PrewarmStatus, 0, len(p.config.Watchlist))
+	for _, pair := range p.config.Watchlist {
+		s := *p.status[pair]
+		out = append(out, s)
+	}
+	return out
+}
+
+// unique ids and currencies across the watchlist, preserving order
+func (p *Prewarmer) batch() ([]string, []string) {
+	var ids, currencies []string
+	seenIDs := make(map[string]bool)
+	seenCurrencies := make(map[string]bool)
+	for _, pair := range p.config.Watchlist {
+		if !seenIDs[pair.Crypto] {
+			seenIDs[pair.Crypto] = true
+			ids = append(ids, pair.Crypto)
+		}
+		if !seenCurrencies[pair.Currency] {
+			seenCurrencies[pair.Currency] = true
+			currencies = append(currencies, pair.Currency)
+		}
+	}
+	return ids, currencies
+}
+
+func (p *Prewarmer) recordSuccess(pair WatchPair, at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.status[pair]
+	s.LastRefresh = &at
+	s.LastError = ""
+	s.ConsecutiveFailures = 0
+}
+
+func (p *Prewarmer) recordFailure(pair WatchPair, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.status[pair]
+	s.LastError = err.Error()
+	s.Failures++
+	s.ConsecutiveFailures++
+}
+
+// mark every pair failed and return an exponential backoff capped at maxPrewarmBackoff
+func (p *Prewarmer) recordBatchFailure(cfg Config, err error) time.Duration {
+	for _, pair := range p.config.Watchlist {
+		p.recordFailure(pair, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures++
+	backoff := p.interval(cfg)
+	for i := 1; i < p.failures && backoff < maxPrewarmBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxPrewarmBackoff)
+}
@@ -0,0 +1,91 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/undersleep7x/cryo-project/internal/infra/cache"
+	"github.com/undersleep7x/cryo-project/internal/platform/redisstore/redisfake"
+)
+
+func TestPrewarmer(t *testing.T) {
+	testConfig := Config{BaseURL: "https://dummy-coingecko.com", Timeout: 5}
+	prewarmConfig := PrewarmConfig{
+		Watchlist: []WatchPair{
+			{Crypto: "bitcoin", Currency: "usd"},
+			{Crypto: "ethereum", Currency: "usd"},
+			{Crypto: "bitcoin", Currency: "eur"},
+		},
+		Lead:        5 * time.Second,
+		MinInterval: 2 * time.Second,
+	}
+
+	t.Run("Batched Refresh", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		fakeRedis := redisfake.New()
+		prewarmer := NewPrewarmer(cache.NewPriceCache(fakeRedis), NewSettings(testConfig), prewarmConfig)
+
+		originalFetchPrices := FetchPrices
+		defer func() { FetchPrices = originalFetchPrices }()
+		FetchPrices = func(ctx context.Context, cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+			return mockAPI.FetchPrices(cryptoList, currency, baseURL, timeoutVal)
+		}
+
+		// ethereum/usd is missing from the response and should be reported as a failure
+		dummyResponse := &resty.Response{}
+		dummyResponse.SetBody([]byte(`{"bitcoin":{"usd":46000.00,"eur":42000.00},"ethereum":{}}`))
+		mockAPI.Mock.On("FetchPrices", []string{"bitcoin", "ethereum"}, "usd,eur", testConfig.BaseURL, testConfig.Timeout).Return(dummyResponse, nil).Once()
+
+		delay := prewarmer.Refresh(context.Background())
+		assert.Equal(t, 25*time.Second, delay)
+		mockAPI.Mock.AssertNumberOfCalls(t, "FetchPrices", 1)
+		for _, key := range []string{"prices:bitcoin:usd", "prices:bitcoin:eur"} {
+			ttl, ok := fakeRedis.TTL(key)
+			assert.True(t, ok, key)
+			assert.Equal(t, 30*time.Second, ttl.Round(time.Second), key)
+		}
+		_, ok := fakeRedis.Value("prices:ethereum:usd")
+		assert.False(t, ok)
+
+		status := prewarmer.Status()
+		assert.NotNil(t, status[0].LastRefresh)
+		assert.Equal(t, 0, status[0].Failures)
+		assert.Nil(t, status[1].LastRefresh)
+		assert.Equal(t, 1, status[1].Failures)
+		assert.NotEmpty(t, status[1].LastError)
+	})
+
+	t.Run("Failure Backoff", func(t *testing.T) {
+		prewarmer := NewPrewarmer(cache.NewPriceCache(redisfake.New()), NewSettings(testConfig), prewarmConfig)
+
+		originalFetchPrices := FetchPrices
+		defer func() { FetchPrices = originalFetchPrices }()
+		FetchPrices = func(ctx context.Context, cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+			return &resty.Response{}, errors.New("timeout")
+		}
+
+		assert.Equal(t, 25*time.Second, prewarmer.Refresh(context.Background()))
+		assert.Equal(t, 50*time.Second, prewarmer.Refresh(context.Background()))
+		assert.Equal(t, 100*time.Second, prewarmer.Refresh(context.Background()))
+		assert.Equal(t, 3, prewarmer.Status()[0].ConsecutiveFailures)
+	})
+
+	t.Run("Rate Limited Honors Retry-After", func(t *testing.T) {
+		prewarmer := NewPrewarmer(cache.NewPriceCache(redisfake.New()), NewSettings(testConfig), prewarmConfig)
+
+		originalFetchPrices := FetchPrices
+		defer func() { FetchPrices = originalFetchPrices }()
+		FetchPrices = func(ctx context.Context, cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+			header := http.Header{}
+			header.Set("Retry-After", "120")
+			return &resty.Response{RawResponse: &http.Response{StatusCode: http.StatusTooManyRequests, Header: header}}, nil
+		}
+
+		assert.Equal(t, 120*time.Second, prewarmer.Refresh(context.Background()))
+	})
+}
@@ -3,86 +3,145 @@ package prices
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/tidwall/gjson"
-	"github.com/undersleep7x/cryo-project/internal/infra/cache"
+	"github.com/undersleep7x/cryo-project/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const tracerName = "github.com/undersleep7x/cryo-project/internal/prices"
+
+const (
+	priceCacheTTL = 30 * time.Second
+	staleCacheTTL = 24 * time.Hour // last known prices kept around to serve when the provider is down
+)
+
+// entries written before prices were cached with their source, e.g. {"bitcoin":45000}, parse
+// without error into a zero price, so they're treated as a miss and overwritten
+var errCacheEntryFormat = errors.New("cached price has no price")
+
 type FetchCryptoPriceService interface {
-	FetchCryptoPrice(cryptoSymbols []string, currency string) (map[string]float64, error)
+	FetchCryptoPrice(ctx context.Context, cryptoSymbols []string, currency string) (*PriceResult, error)
 }
 
 type fetchCryptoPriceServiceImpl struct{
 	Cache PricesCache
-	config Config
+	settings *Settings
 }
 
-func NewFetchCryptoPriceService(cache *cache.PriceCache, cfg Config) FetchCryptoPriceService {
-	return &fetchCryptoPriceServiceImpl{Cache: cache, config: cfg}
+func NewFetchCryptoPriceService(cache PricesCache, settings *Settings) FetchCryptoPriceService {
+	return &fetchCryptoPriceServiceImpl{Cache: cache, settings: settings}
 }
 
-func(s *fetchCryptoPriceServiceImpl) FetchCryptoPrice(cryptoSymbols []string, currency string) (map[string]float64, error) {
-	// kick off redis context and close at the end
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func(s *fetchCryptoPriceServiceImpl) FetchCryptoPrice(ctx context.Context, cryptoSymbols []string, currency string) (*PriceResult, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "FetchCryptoPriceService.FetchCryptoPrice",
+		trace.WithAttributes(attribute.StringSlice("price.cryptos", cryptoSymbols), attribute.String("price.currency", currency)))
+	defer span.End()
+	cfg := s.settings.Load() // one snapshot for the whole request, even across a config reload
 
-	priceData := make(map[string]float64) // init return variable
+	currency = strings.ToLower(currency) // cache keys and api response keys are lowercase
+	result := newPriceResult(currency) // init return variable
 	var missingCryptos []string           // init missingcrypto variable
 
 	// loop through array and check cache for any saved data
 	for _, crypto := range cryptoSymbols {
-		cacheKey := fmt.Sprintf("prices:%s:%s", crypto, currency)
-		cachedData, err :=s.Cache.GetCachedPrices(ctx, cacheKey)
+		cached, err := s.getCachedPrice(ctx, priceCacheKey(crypto, currency))
 
 		if err == nil {
-			var cachedPrice map[string]float64
-			err := json.Unmarshal([]byte(cachedData), &cachedPrice)
-			if err != nil {
-				log.Printf("Failed to parse Redis JSON for %s: %v", crypto, err)
-			} else {
-				log.Printf("Successfully retrieved cached price data for %s", crypto)
-				priceData[crypto] = cachedPrice[crypto]
-			}
-		} else if err.Error() == "redis: nil" { // if any error, add crypto to missing array and move it api
+			log.Printf("Successfully retrieved cached price data for %s", crypto)
+			metrics.PriceCacheResults.WithLabelValues("hit").Inc()
+			fetchedAt := cached.FetchedAt
+			result.Prices[crypto] = AssetPrice{Crypto: crypto, Status: StatusOK, Price: cached.Price, Source: SourceCache, FetchedAt: &fetchedAt}
+		} else if err.Error() == "redis: nil" || errors.Is(err, errCacheEntryFormat) { // if any error, add crypto to missing array and move it api
 			log.Printf("No cache for %s in Redis cache, fetching with API", crypto)
+			metrics.PriceCacheResults.WithLabelValues("miss").Inc()
 			missingCryptos = append(missingCryptos, crypto)
 		} else {
 			log.Printf("Redis error for %s: %v", crypto, err)
+			metrics.PriceCacheResults.WithLabelValues("error").Inc()
 			missingCryptos = append(missingCryptos, crypto)
 		}
 	}
 
 	if len(missingCryptos) > 0 { // if any were not in cache
 
-		pricesCall, err := FetchPrices(missingCryptos, currency, s.config.BaseURL, s.config.Timeout) // make api call for remaining cryptos
+		span.SetAttributes(attribute.Int("price.cache_misses", len(missingCryptos)))
+		pricesCall, err := FetchPrices(ctx, missingCryptos, currency, cfg.BaseURL, cfg.Timeout) // make api call for remaining cryptos
+		if err == nil && pricesCall.IsError() {
+			err = fmt.Errorf("provider returned status %d", pricesCall.StatusCode())
+		}
 
-		if err != nil { // set fallback prices if api call fails entirely
-			log.Printf("API failure, setting fallback prices: %v", err)
+		if err != nil { // serve last known prices if api call fails entirely
+			log.Printf("API failure, falling back to stale prices: %v", err)
 			for _, crypto := range missingCryptos {
-				priceData[crypto] = -1
+				result.Prices[crypto] = s.stalePrice(ctx, crypto, currency, err)
 			}
 		} else {
+			fetchedAt := time.Now().UTC()
 			for _, crypto := range missingCryptos {
 				price := gjson.Get(pricesCall.String(), fmt.Sprintf("%s.%s", crypto, currency))
 				if price.Exists() { // add existing prices to return value
-					priceData[crypto] = price.Float()
-					cacheKey := fmt.Sprintf("prices:%s:%s", crypto, currency)
-					cachedEntry, _ := json.Marshal(map[string]float64{crypto: price.Float()}) // after adding, cache value
-					err := s.Cache.CachePrices(ctx, cacheKey, cachedEntry, 30*time.Second)
-					if err != nil {
-						log.Printf("Failed to cache price for %s: %v", crypto, err)
-					}
+					result.Prices[crypto] = AssetPrice{Crypto: crypto, Status: StatusOK, Price: price.Float(), Source: SourceCoinGecko, FetchedAt: &fetchedAt}
+					s.cachePrice(ctx, cfg, crypto, currency, cachedPrice{Price: price.Float(), Source: SourceCoinGecko, FetchedAt: fetchedAt})
 				} else {
-					// if not found in api call or cache, set fallback value
-					log.Printf("Price for %s not found in API, setting fallback price", crypto)
-					priceData[crypto] = -1
+					log.Printf("Price for %s not found in API", crypto)
+					result.Prices[crypto] = AssetPrice{Crypto: crypto, Status: StatusNotFound, Error: fmt.Sprintf("no %s price for %s", currency, crypto)}
 				}
 			}
 		}
 	}
 
-	return priceData, nil
+	return result, nil
+}
+
+func (s *fetchCryptoPriceServiceImpl) getCachedPrice(ctx context.Context, cacheKey string) (*cachedPrice, error) {
+	cachedData, err := s.Cache.GetCachedPrices(ctx, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	var cached cachedPrice
+	if err := json.Unmarshal([]byte(cachedData), &cached); err != nil {
+		return nil, fmt.Errorf("failed to parse cached price %s: %w", cacheKey, err)
+	}
+	if cached.Price <= 0 || cached.FetchedAt.IsZero() {
+		return nil, fmt.Errorf("%w: %s", errCacheEntryFormat, cacheKey)
+	}
+	return &cached, nil
+}
+
+// write the fresh entry and the long lived stale fallback
+func (s *fetchCryptoPriceServiceImpl) cachePrice(ctx context.Context, cfg Config, crypto string, currency string, entry cachedPrice) {
+	cachedEntry, _ := json.Marshal(entry)
+	if err := s.Cache.CachePrices(ctx, priceCacheKey(crypto, currency), cachedEntry, cfg.cacheTTL()); err != nil {
+		log.Printf("Failed to cache price for %s: %v", crypto, err)
+	}
+	if err := s.Cache.CachePrices(ctx, staleCacheKey(crypto, currency), cachedEntry, cfg.staleTTL()); err != nil {
+		log.Printf("Failed to cache stale price for %s: %v", crypto, err)
+	}
+}
+
+// last known price when the provider is unavailable, or an upstream error entry if there isn't one
+func (s *fetchCryptoPriceServiceImpl) stalePrice(ctx context.Context, crypto string, currency string, upstreamErr error) AssetPrice {
+	cached, err := s.getCachedPrice(ctx, staleCacheKey(crypto, currency))
+	if err != nil {
+		return AssetPrice{Crypto: crypto, Status: StatusUpstreamError, Error: upstreamErr.Error()}
+	}
+	metrics.PriceCacheResults.WithLabelValues("stale").Inc()
+	fetchedAt := cached.FetchedAt
+	return AssetPrice{Crypto: crypto, Status: StatusStale, Price: cached.Price, Source: cached.Source, FetchedAt: &fetchedAt}
+}
+
+func priceCacheKey(crypto string, currency string) string {
+	return fmt.Sprintf("prices:%s:%s", crypto, currency)
+}
+
+func staleCacheKey(crypto string, currency string) string {
+	return fmt.Sprintf("prices:stale:%s:%s", crypto, currency)
 }
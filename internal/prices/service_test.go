@@ -3,33 +3,22 @@ package prices
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	resty "github.com/go-resty/resty/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/undersleep7x/cryo-project/internal/infra/cache"
+	"github.com/undersleep7x/cryo-project/internal/metrics"
+	"github.com/undersleep7x/cryo-project/internal/platform/redisstore/redisfake"
+	"github.com/undersleep7x/cryo-project/internal/tracing/tracingtest"
 )
 
-// mock redis and api call for testing
-type MockRedisClient struct {
-	mock.Mock
-}
-
-func (m *MockRedisClient) Get(ctx context.Context, key string) (string, error) {
-	args := m.Mock.Called(ctx, key)
-	return args.String(0), args.Error(1)
-}
-func (m *MockRedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	args := m.Mock.Called(ctx, key, value, expiration)
-	return args.Error(0)
-}
-func (m *MockRedisClient) Ping(ctx context.Context) error {
-	args := m.Mock.Called(ctx)
-	return args.Error(0)
-}
-
+// mock api call for testing
 type MockAPI struct {
 	mock.Mock
 }
@@ -48,108 +37,233 @@ func TestFetchCryptoPrice(t *testing.T) {
 		Timeout:       5,
 		RetryAttempts: 1,
 	}
+	ctx := context.Background()
 
 	// test response if value is found in cache
 	t.Run("Cache Hit", func(t *testing.T) {
-		// set mock redis cache and test data
-		mockRedis := new(MockRedisClient)
-		mockPriceCache := cache.NewPriceCache(mockRedis)
-		service := NewFetchCryptoPriceService(mockPriceCache, testConfig)
+		// set fake redis cache and test data
+		fakeRedis := redisfake.New()
+		mockPriceCache := cache.NewPriceCache(fakeRedis)
+		service := NewFetchCryptoPriceService(mockPriceCache, NewSettings(testConfig))
 
-		cachedData := `{"bitcoin": 45000.00}`
-		mockRedis.Mock.On("Get", mock.Anything, "prices:bitcoin:usd").Return(cachedData, nil)
+		cachedData := `{"price": 45000.00, "source": "coingecko", "fetched_at": "2025-01-01T00:00:00Z"}`
+		_ = fakeRedis.Set(ctx, "prices:bitcoin:usd", cachedData, 30*time.Second)
+		hits := testutil.ToFloat64(metrics.PriceCacheResults.WithLabelValues("hit"))
 
 		// make method call and record response, should have no error and match test data
-		prices, err := service.FetchCryptoPrice(cryptoSymbols, currency)
+		prices, err := service.FetchCryptoPrice(ctx, cryptoSymbols, currency)
 		assert.NoError(t, err)
-		assert.Equal(t, 45000.00, prices["bitcoin"])
+		assert.Equal(t, 45000.00, prices.Prices["bitcoin"].Price)
+		assert.Equal(t, StatusOK, prices.Prices["bitcoin"].Status)
+		assert.Equal(t, SourceCache, prices.Prices["bitcoin"].Source)
+		assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), *prices.Prices["bitcoin"].FetchedAt)
+		assert.Equal(t, hits+1, testutil.ToFloat64(metrics.PriceCacheResults.WithLabelValues("hit")))
 	})
 
-	// check and fail api after cache failure
-	t.Run("Cache Miss - API Failure", func(t *testing.T) {
-		// set mock redis and api call
+	// entries from before prices were cached with their source are refetched, never served as a zero price
+	t.Run("Cache Miss - Old Entry Format", func(t *testing.T) {
 		mockAPI := new(MockAPI)
-		mockRedis := new(MockRedisClient)
-		mockPriceCache := cache.NewPriceCache(mockRedis)
-		service := NewFetchCryptoPriceService(mockPriceCache, testConfig)
+		fakeRedis := redisfake.New()
+		mockPriceCache := cache.NewPriceCache(fakeRedis)
+		service := NewFetchCryptoPriceService(mockPriceCache, NewSettings(testConfig))
+
+		originalFetchPrices := FetchPrices
+		defer func() { FetchPrices = originalFetchPrices }()
+		FetchPrices = func(ctx context.Context, cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+			return mockAPI.FetchPrices(cryptoList, currency, baseURL, timeoutVal)
+		}
+
+		_ = fakeRedis.Set(ctx, "prices:bitcoin:usd", `{"bitcoin": 45000}`, 30*time.Second)
+		_ = fakeRedis.Set(ctx, "prices:stale:bitcoin:usd", `{"bitcoin": 45000}`, 24*time.Hour)
+		apiResponse := &resty.Response{}
+		apiResponse.SetBody([]byte(`{"bitcoin": {"usd": 46000}}`))
+		mockAPI.Mock.On("FetchPrices", cryptoSymbols, currency, testConfig.BaseURL, testConfig.Timeout).Return(apiResponse, nil)
+
+		prices, err := service.FetchCryptoPrice(ctx, cryptoSymbols, currency)
+		assert.NoError(t, err)
+		assert.Equal(t, StatusOK, prices.Prices["bitcoin"].Status)
+		assert.Equal(t, SourceCoinGecko, prices.Prices["bitcoin"].Source)
+		assert.Equal(t, 46000.00, prices.Prices["bitcoin"].Price)
+		mockAPI.AssertExpectations(t)
+
+		// the refetched price replaces the old entry
+		prices, err = service.FetchCryptoPrice(ctx, cryptoSymbols, currency)
+		assert.NoError(t, err)
+		assert.Equal(t, SourceCache, prices.Prices["bitcoin"].Source)
+		assert.Equal(t, 46000.00, prices.Prices["bitcoin"].Price)
+	})
+
+	// check api after cache failure, coin missing from api response
+	t.Run("Cache Miss - Not Found", func(t *testing.T) {
+		// set fake redis and mock api call
+		mockAPI := new(MockAPI)
+		fakeRedis := redisfake.New()
+		mockPriceCache := cache.NewPriceCache(fakeRedis)
+		service := NewFetchCryptoPriceService(mockPriceCache, NewSettings(testConfig))
 
 		// switch the method for the mock method and revert after ending test
 		originalFetchPrices := FetchPrices
 		defer func() { FetchPrices = originalFetchPrices }()
-		FetchPrices = func(cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+		FetchPrices = func(ctx context.Context, cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
 			return mockAPI.FetchPrices(cryptoList, currency, baseURL, timeoutVal)
 		}
 
-		// set mock redis behavior to trigger api check
-		mockRedis.Mock.On("Get", mock.Anything, "prices:bitcoin:usd").Return("", errors.New("redis connection error"))
+		// fail redis reads to trigger api check
+		fakeRedis.Fail("Get", errors.New("redis connection error"), -1)
 
 		// set dummy response from resty call in method
 		dummyErrorResponse := &resty.Response{}
 		dummyErrorResponse.SetBody([]byte(`{}`))
 
-		// set mock api behavior and post call redis behavior
+		// set mock api behavior
 		mockAPI.Mock.On("FetchPrices", cryptoSymbols, currency, testConfig.BaseURL, testConfig.Timeout).Return(dummyErrorResponse, nil)
-		mockRedis.Mock.On("Set", mock.Anything, "prices:bitcoin:usd", mock.Anything, 30*time.Second).Return(nil)
 
-		// make method call, should return fallback price for crypto
-		prices, err := service.FetchCryptoPrice(cryptoSymbols, currency)
+		// make method call, should report the crypto as not found rather than a fallback price
+		prices, err := service.FetchCryptoPrice(ctx, cryptoSymbols, currency)
+		assert.NoError(t, err)
+		assert.Equal(t, StatusNotFound, prices.Prices["bitcoin"].Status)
+		assert.Equal(t, 0.00, prices.Prices["bitcoin"].Price)
+		_, ok := prices.Price("bitcoin")
+		assert.False(t, ok)
+	})
+
+	// api call fails entirely, last known price served as stale
+	t.Run("API Failure - Stale Fallback", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		fakeRedis := redisfake.New()
+		mockPriceCache := cache.NewPriceCache(fakeRedis)
+		service := NewFetchCryptoPriceService(mockPriceCache, NewSettings(testConfig))
+
+		originalFetchPrices := FetchPrices
+		defer func() { FetchPrices = originalFetchPrices }()
+		FetchPrices = func(ctx context.Context, cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+			return mockAPI.FetchPrices(cryptoList, currency, baseURL, timeoutVal)
+		}
+
+		_ = fakeRedis.Set(ctx, "prices:stale:bitcoin:usd", `{"price": 44000.00, "source": "coingecko", "fetched_at": "2025-01-01T00:00:00Z"}`, 24*time.Hour)
+		mockAPI.Mock.On("FetchPrices", cryptoSymbols, currency, testConfig.BaseURL, testConfig.Timeout).Return(&resty.Response{}, errors.New("timeout"))
+
+		stale := testutil.ToFloat64(metrics.PriceCacheResults.WithLabelValues("stale"))
+		prices, err := service.FetchCryptoPrice(ctx, cryptoSymbols, currency)
 		assert.NoError(t, err)
-		assert.Equal(t, -1.00, prices["bitcoin"])
+		assert.Equal(t, StatusStale, prices.Prices["bitcoin"].Status)
+		assert.Equal(t, stale+1, testutil.ToFloat64(metrics.PriceCacheResults.WithLabelValues("stale")))
+		assert.Equal(t, 44000.00, prices.Prices["bitcoin"].Price)
+		assert.True(t, prices.Prices["bitcoin"].Usable())
+	})
+
+	// api call fails entirely with nothing cached
+	t.Run("API Failure - Upstream Error", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		fakeRedis := redisfake.New()
+		mockPriceCache := cache.NewPriceCache(fakeRedis)
+		service := NewFetchCryptoPriceService(mockPriceCache, NewSettings(testConfig))
+
+		originalFetchPrices := FetchPrices
+		defer func() { FetchPrices = originalFetchPrices }()
+		FetchPrices = func(ctx context.Context, cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+			return mockAPI.FetchPrices(cryptoList, currency, baseURL, timeoutVal)
+		}
+
+		mockAPI.Mock.On("FetchPrices", cryptoSymbols, currency, testConfig.BaseURL, testConfig.Timeout).Return(&resty.Response{}, errors.New("timeout"))
+
+		prices, err := service.FetchCryptoPrice(ctx, cryptoSymbols, currency)
+		assert.NoError(t, err)
+		assert.Equal(t, StatusUpstreamError, prices.Prices["bitcoin"].Status)
+		assert.True(t, prices.Partial())
 	})
 
 	t.Run("Redis Error", func(t *testing.T) {
-		// set mock redis and api
+		// set fake redis and mock api
 		mockAPI := new(MockAPI)
-		mockRedis := new(MockRedisClient)
-		mockPriceCache := cache.NewPriceCache(mockRedis)
-		service := NewFetchCryptoPriceService(mockPriceCache, testConfig)
+		fakeRedis := redisfake.New()
+		mockPriceCache := cache.NewPriceCache(fakeRedis)
+		service := NewFetchCryptoPriceService(mockPriceCache, NewSettings(testConfig))
 
-		// set mock redis response
-		mockRedis.Mock.On("Get", mock.Anything, "prices:bitcoin:usd").Return("", errors.New("redis connection error"))
+		// fail redis reads
+		fakeRedis.Fail("Get", errors.New("redis connection error"), -1)
 
 		// switch the method for the mock method and revert after ending test
 		originalFetchPrices := FetchPrices
 		defer func() { FetchPrices = originalFetchPrices }()
-		FetchPrices = func(cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+		FetchPrices = func(ctx context.Context, cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
 			return mockAPI.FetchPrices(cryptoList, currency, baseURL, timeoutVal)
 		}
 
-		//set dummy resty response and mock api response/ post api call redis action
+		//set dummy resty response and mock api response
 		dummyResponse := &resty.Response{}
 		dummyResponse.SetBody([]byte(`{"bitcoin":{"usd":47000.00}}`))
 		mockAPI.Mock.On("FetchPrices", cryptoSymbols, currency, testConfig.BaseURL, testConfig.Timeout).Return(dummyResponse, nil)
-		mockRedis.Mock.On("Set", mock.Anything, "prices:bitcoin:usd", mock.Anything, 30*time.Second).Return(nil)
 
 		// make method call, should return expected price for crypto
-		prices, err := service.FetchCryptoPrice(cryptoSymbols, currency)
+		prices, err := service.FetchCryptoPrice(ctx, cryptoSymbols, currency)
 		assert.NoError(t, err)
-		assert.Equal(t, 47000.00, prices["bitcoin"])
+		assert.Equal(t, 47000.00, prices.Prices["bitcoin"].Price)
 	})
 
 	t.Run("Cache Miss - API Success", func(t *testing.T) {
-		// set mock redis and api
+		// set fake redis and mock api
 		mockAPI := new(MockAPI)
-		mockRedis := new(MockRedisClient)
-		mockPriceCache := cache.NewPriceCache(mockRedis)
-		service := NewFetchCryptoPriceService(mockPriceCache, testConfig)
+		fakeRedis := redisfake.New()
+		mockPriceCache := cache.NewPriceCache(fakeRedis)
+		service := NewFetchCryptoPriceService(mockPriceCache, NewSettings(testConfig))
 
 		// switch the method for the mock method and revert after ending test
 		originalFetchPrices := FetchPrices
 		defer func() { FetchPrices = originalFetchPrices }()
-		FetchPrices = func(cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+		FetchPrices = func(ctx context.Context, cryptoList []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
 			return mockAPI.FetchPrices(cryptoList, currency, baseURL, timeoutVal)
 		}
 
-		// set mock responses from redis and api call
-		mockRedis.Mock.On("Get", mock.Anything, "prices:bitcoin:usd").Return("", errors.New("redis: nil"))
+		// set mock response from api call, redis starts empty
 		dummyResponse := &resty.Response{}
 		dummyResponse.SetBody([]byte(`{"bitcoin":{"usd":46000.00}}`))
 		mockAPI.Mock.On("FetchPrices", cryptoSymbols, currency, testConfig.BaseURL, testConfig.Timeout).Return(dummyResponse, nil)
-		mockRedis.Mock.On("Set", mock.Anything, "prices:bitcoin:usd", mock.Anything, 30*time.Second).Return(nil)
 
 		// make method call, should fail to find in redis and return from api call
-		prices, err := service.FetchCryptoPrice(cryptoSymbols, currency)
+		prices, err := service.FetchCryptoPrice(ctx, cryptoSymbols, currency)
 		assert.NoError(t, err)
-		assert.Equal(t, 46000.00, prices["bitcoin"])
+		assert.Equal(t, 46000.00, prices.Prices["bitcoin"].Price)
+		assert.Equal(t, SourceCoinGecko, prices.Prices["bitcoin"].Source)
+		assert.NotNil(t, prices.Prices["bitcoin"].FetchedAt)
+		assert.False(t, prices.Partial())
+
+		// fresh and stale copies written with their own ttls
+		ttl, ok := fakeRedis.TTL("prices:bitcoin:usd")
+		assert.True(t, ok)
+		assert.Equal(t, 30*time.Second, ttl.Round(time.Second))
+		ttl, ok = fakeRedis.TTL("prices:stale:bitcoin:usd")
+		assert.True(t, ok)
+		assert.Equal(t, 24*time.Hour, ttl.Round(time.Second))
 	})
 }
+
+func TestFetchCryptoPriceTracing(t *testing.T) {
+	spans := tracingtest.New(t)
+
+	// provider stub that records the propagated trace context
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		_, _ = w.Write([]byte(`{"bitcoin":{"usd":46000.00}}`))
+	}))
+	defer server.Close()
+
+	service := NewFetchCryptoPriceService(cache.NewPriceCache(redisfake.New()), NewSettings(Config{BaseURL: server.URL, Timeout: 5}))
+	prices, err := service.FetchCryptoPrice(context.Background(), []string{"bitcoin"}, "usd")
+	assert.NoError(t, err)
+	assert.Equal(t, 46000.00, prices.Prices["bitcoin"].Price)
+
+	recorded := spans.GetSpans()
+	names := tracingtest.SpanNames(spans)
+	assert.Contains(t, names, "redis GET")
+	assert.Contains(t, names, "redis SET")
+	assert.Equal(t, "FetchCryptoPriceService.FetchCryptoPrice", names[len(names)-1])
+
+	// cache, provider and outbound header all belong to the service span's trace
+	root := recorded[len(recorded)-1]
+	for _, span := range recorded[:len(recorded)-1] {
+		assert.Equal(t, root.SpanContext.TraceID(), span.SpanContext.TraceID(), span.Name)
+	}
+	assert.Contains(t, traceparent, root.SpanContext.TraceID().String())
+}
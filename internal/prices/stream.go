@@ -0,0 +1,236 @@
+package prices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStreamPollInterval = 5 * time.Second
+	subscriberBufferSize      = 8
+	maxStreamSubscribeBackoff = 30 * time.Second
+)
+
+// first retry delay after the broker subscription fails or drops, doubled up to maxStreamSubscribeBackoff
+var streamSubscribeBackoff = time.Second
+
+// pub/sub transport used to fan price updates out across replicas
+type PriceBroker interface {
+	Publish(ctx context.Context, channel string, payload string) error
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+}
+
+type PriceUpdate struct {
+	Crypto    string    `json:"crypto"`
+	Currency  string    `json:"currency"`
+	Price     float64   `json:"price"`
+	Previous  float64   `json:"previous,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// one poller and one broker subscription per (crypto, currency) pair, shared by every local subscriber
+type pairStream struct {
+	crypto      string
+	currency    string
+	subscribers map[chan PriceUpdate]struct{}
+	last        *PriceUpdate
+	cancel      context.CancelFunc
+}
+
+type PriceStreamer struct {
+	service  FetchCryptoPriceService
+	broker   PriceBroker
+	settings *Settings
+
+	mu    sync.Mutex
+	pairs map[string]*pairStream
+}
+
+func NewPriceStreamer(service FetchCryptoPriceService, broker PriceBroker, settings *Settings) *PriceStreamer {
+	return &PriceStreamer{service: service, broker: broker, settings: settings, pairs: make(map[string]*pairStream)}
+}
+
+// register for updates on a pair, returning the update channel and a func to unsubscribe.
+// the channel is never closed, callers stop reading once they unsubscribe
+func (s *PriceStreamer) Subscribe(crypto string, currency string) (<-chan PriceUpdate, func()) {
+	key := streamKey(crypto, currency)
+	ch := make(chan PriceUpdate, subscriberBufferSize)
+
+	s.mu.Lock()
+	pair, ok := s.pairs[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		pair = &pairStream{crypto: crypto, currency: currency, subscribers: make(map[chan PriceUpdate]struct{}), cancel: cancel}
+		s.pairs[key] = pair
+		go s.run(ctx, pair)
+	}
+	pair.subscribers[ch] = struct{}{}
+	if pair.last != nil { // send the last known price straight away so clients don't wait for a change
+		ch <- *pair.last
+	}
+	s.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(pair.subscribers, ch)
+			if len(pair.subscribers) == 0 && s.pairs[key] == pair {
+				pair.cancel()
+				delete(s.pairs, key)
+			}
+		})
+	}
+	return ch, unsubscribe
+}
+
+// periodically refresh the pair and publish when the price moves beyond the threshold
+func (s *PriceStreamer) poll(ctx context.Context, pair *pairStream) {
+	var published float64
+	for {
+		prices, err := s.service.FetchCryptoPrice(ctx, []string{pair.crypto}, pair.currency)
+		if err != nil {
+			log.Printf("Price stream refresh failed for %s/%s: %v", pair.crypto, pair.currency, err)
+		} else if price, ok := prices.Price(pair.crypto); ok && s.changed(published, price) {
+			update := PriceUpdate{Crypto: pair.crypto, Currency: pair.currency, Price: price, Previous: published, ChangedAt: time.Now().UTC()}
+			payload, _ := json.Marshal(update)
+			if err := s.broker.Publish(ctx, streamChannel(pair.crypto, pair.currency), string(payload)); err != nil {
+				log.Printf("Failed to publish price update for %s/%s: %v", pair.crypto, pair.currency, err)
+			} else {
+				published = price
+			}
+		}
+
+		// interval is re-read every round so a config reload applies to running streams
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.settings.Load().streamPollInterval()):
+		}
+	}
+}
+
+// subscribe to the broker before polling so the first published price isn't missed,
+// then forward updates (published by this or any other replica) to local subscribers.
+// a failed or dropped subscription is retried with backoff until the last subscriber leaves,
+// since subscribers already registered on the pair have no other way to get updates
+func (s *PriceStreamer) run(ctx context.Context, pair *pairStream) {
+	polling := false
+	backoff := streamSubscribeBackoff
+	for {
+		msgs, err := s.broker.Subscribe(ctx, streamChannel(pair.crypto, pair.currency))
+		if err != nil {
+			log.Printf("Failed to subscribe to price updates for %s/%s, retrying in %s: %v", pair.crypto, pair.currency, backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxStreamSubscribeBackoff)
+			continue
+		}
+		backoff = streamSubscribeBackoff
+		if !polling {
+			polling = true
+			go s.poll(ctx, pair)
+		}
+
+		s.forward(pair, msgs)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Price update subscription for %s/%s ended, resubscribing", pair.crypto, pair.currency)
+	}
+}
+
+// deliver broker messages to the pair's subscribers until the subscription ends
+func (s *PriceStreamer) forward(pair *pairStream, msgs <-chan string) {
+	for msg := range msgs {
+		var update PriceUpdate
+		if err := json.Unmarshal([]byte(msg), &update); err != nil {
+			log.Printf("Failed to parse price update for %s/%s: %v", pair.crypto, pair.currency, err)
+			continue
+		}
+		s.deliver(pair, update)
+	}
+}
+
+func (s *PriceStreamer) deliver(pair *pairStream, update PriceUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// replicas poll independently, so drop updates that don't move the price from what was last delivered
+	if pair.last != nil && !s.changed(pair.last.Price, update.Price) {
+		return
+	}
+	pair.last = &update
+	for ch := range pair.subscribers {
+		select {
+		case ch <- update:
+		default: // slow subscriber, skip rather than block the pair
+		}
+	}
+}
+
+func (s *PriceStreamer) changed(previous float64, current float64) bool {
+	if previous <= 0 {
+		return true
+	}
+	return math.Abs(current-previous)/previous > s.settings.Load().StreamThreshold
+}
+
+func streamKey(crypto string, currency string) string {
+	return fmt.Sprintf("%s:%s", strings.ToLower(crypto), strings.ToLower(currency))
+}
+
+func streamChannel(crypto string, currency string) string {
+	return "prices:stream:" + streamKey(crypto, currency)
+}
+
+// in-process broker for single replica deployments and tests
+type localPriceBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan string]struct{}
+}
+
+func NewLocalPriceBroker() PriceBroker {
+	return &localPriceBroker{subscribers: make(map[string]map[chan string]struct{})}
+}
+
+func (b *localPriceBroker) Publish(ctx context.Context, channel string, payload string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[channel] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *localPriceBroker) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	ch := make(chan string, subscriberBufferSize)
+	b.mu.Lock()
+	if b.subscribers[channel] == nil {
+		b.subscribers[channel] = make(map[chan string]struct{})
+	}
+	b.subscribers[channel][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers[channel], ch)
+		b.mu.Unlock()
+		close(ch)
+	}()
+	return ch, nil
+}
@@ -0,0 +1,234 @@
+package prices
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+)
+
+const (
+	streamHeartbeatInterval = 30 * time.Second
+	wsWriteTimeout          = 10 * time.Second
+	maxStreamPairs          = 20
+)
+
+var upgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+
+// setup interface for streaming price updates
+type PriceStreamHandler struct {
+	streamer *PriceStreamer
+	registry assets.Registry
+}
+
+func NewPriceStreamHandler(streamer *PriceStreamer, registry assets.Registry) *PriceStreamHandler {
+	return &PriceStreamHandler{streamer: streamer, registry: registry}
+}
+
+// message sent by websocket clients to change their subscriptions
+type streamCommand struct {
+	Action   string `json:"action"` // subscribe or unsubscribe
+	Crypto   string `json:"crypto"`
+	Currency string `json:"currency"`
+}
+
+// handle /price/stream route call, upgrading to websocket when requested and falling back to server-sent events
+func (f *PriceStreamHandler) StreamPrices(c *gin.Context) {
+	cryptos := c.Query("crypto")
+	currency := c.Query("currency")
+	isWebsocket := websocket.IsWebSocketUpgrade(c.Request)
+
+	// sse clients can only subscribe through query params, websocket clients can also send commands later
+	if !isWebsocket && cryptos == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'crypto' query parameter"})
+		return
+	}
+	if cryptos != "" && currency == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'currency' query parameter"})
+		return
+	}
+
+	var cryptoList []string
+	if cryptos != "" {
+		cryptoList = strings.Split(cryptos, ",")
+	}
+	if len(cryptoList) > maxStreamPairs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many pairs requested"})
+		return
+	}
+	cryptoList, ok := resolveAssets(c, f.registry, cryptoList)
+	if !ok {
+		return
+	}
+	currency = normalizeCurrency(currency)
+
+	if isWebsocket {
+		f.streamWebsocket(c, cryptoList, currency)
+		return
+	}
+	f.streamSSE(c, cryptoList, currency)
+}
+
+func (f *PriceStreamHandler) streamSSE(c *gin.Context, cryptoList []string, currency string) {
+	updates := make(chan PriceUpdate, subscriberBufferSize)
+	stop := f.fanIn(cryptoList, currency, updates)
+	defer stop()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // stop proxies from buffering the stream
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case update := <-updates:
+			c.SSEvent("price", update)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", gin.H{"time": time.Now().UTC()})
+			return true
+		}
+	})
+}
+
+func (f *PriceStreamHandler) streamWebsocket(c *gin.Context, cryptoList []string, currency string) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Websocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	updates := make(chan PriceUpdate, subscriberBufferSize)
+	subs := newSubscriptionSet(f.streamer, updates)
+	defer subs.closeAll()
+	for _, crypto := range cryptoList {
+		subs.add(crypto, currency)
+	}
+
+	// reader goroutine handles subscribe/unsubscribe commands and notices disconnects
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var cmd streamCommand
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			asset, ok := f.registry.Lookup(cmd.Crypto)
+			if !ok || cmd.Currency == "" {
+				continue
+			}
+			switch cmd.Action {
+			case "subscribe":
+				if subs.count() < maxStreamPairs {
+					subs.add(asset.CoinGeckoID, normalizeCurrency(cmd.Currency))
+				}
+			case "unsubscribe":
+				subs.remove(asset.CoinGeckoID, normalizeCurrency(cmd.Currency))
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case update := <-updates:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(update); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe to every pair and merge their updates into one channel
+func (f *PriceStreamHandler) fanIn(cryptoList []string, currency string, out chan PriceUpdate) func() {
+	subs := newSubscriptionSet(f.streamer, out)
+	for _, crypto := range cryptoList {
+		subs.add(crypto, currency)
+	}
+	return subs.closeAll
+}
+
+// tracks the pairs a single client is subscribed to, forwarding each into a shared channel
+type subscriptionSet struct {
+	streamer *PriceStreamer
+	out      chan PriceUpdate
+	mu       sync.Mutex
+	active   map[string]func()
+}
+
+func newSubscriptionSet(streamer *PriceStreamer, out chan PriceUpdate) *subscriptionSet {
+	return &subscriptionSet{streamer: streamer, out: out, active: make(map[string]func())}
+}
+
+func (s *subscriptionSet) add(crypto string, currency string) {
+	key := streamKey(crypto, currency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.active[key]; ok {
+		return
+	}
+
+	updates, unsubscribe := s.streamer.Subscribe(crypto, currency)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case update := <-updates:
+				select {
+				case s.out <- update:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	s.active[key] = func() {
+		unsubscribe()
+		close(stop)
+	}
+}
+
+func (s *subscriptionSet) remove(crypto string, currency string) {
+	key := streamKey(crypto, currency)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stop, ok := s.active[key]; ok {
+		stop()
+		delete(s.active, key)
+	}
+}
+
+func (s *subscriptionSet) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.active)
+}
+
+func (s *subscriptionSet) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, stop := range s.active {
+		stop()
+		delete(s.active, key)
+	}
+}
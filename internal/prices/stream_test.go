@@ -0,0 +1,124 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// returns queued prices in order, repeating the last one once exhausted
+type sequencePriceService struct {
+	FetchCryptoPriceService
+	mu     sync.Mutex
+	prices []float64
+	calls  int
+}
+
+func (m *sequencePriceService) FetchCryptoPrice(ctx context.Context, cryptoList []string, currency string) (*PriceResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := m.calls
+	if idx >= len(m.prices) {
+		idx = len(m.prices) - 1
+	}
+	m.calls++
+	return &PriceResult{Currency: currency, Prices: map[string]AssetPrice{
+		cryptoList[0]: {Crypto: cryptoList[0], Status: StatusOK, Price: m.prices[idx]},
+	}}, nil
+}
+
+func receiveUpdate(t *testing.T, ch <-chan PriceUpdate) PriceUpdate {
+	t.Helper()
+	select {
+	case update := <-ch:
+		return update
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for price update")
+		return PriceUpdate{}
+	}
+}
+
+func TestPriceStreamer(t *testing.T) {
+	testConfig := Config{StreamPollInterval: 10 * time.Millisecond, StreamThreshold: 0.01}
+
+	t.Run("Shared Poller Pushes Changes Beyond Threshold", func(t *testing.T) {
+		// second price moves under the threshold and should never be pushed
+		service := &sequencePriceService{prices: []float64{100, 100.5, 110}}
+		streamer := NewPriceStreamer(service, NewLocalPriceBroker(), NewSettings(testConfig))
+
+		first, unsubscribeFirst := streamer.Subscribe("bitcoin", "usd")
+		defer unsubscribeFirst()
+		assert.Equal(t, 100.00, receiveUpdate(t, first).Price)
+
+		second, unsubscribeSecond := streamer.Subscribe("bitcoin", "usd")
+		defer unsubscribeSecond()
+		assert.Equal(t, 100.00, receiveUpdate(t, second).Price) // last known price replayed on subscribe
+
+		assert.Equal(t, 110.00, receiveUpdate(t, first).Price)
+		assert.Equal(t, 110.00, receiveUpdate(t, second).Price)
+
+		streamer.mu.Lock()
+		assert.Len(t, streamer.pairs, 1)
+		streamer.mu.Unlock()
+	})
+
+	t.Run("Poller Stops After Last Unsubscribe", func(t *testing.T) {
+		service := &sequencePriceService{prices: []float64{100}}
+		streamer := NewPriceStreamer(service, NewLocalPriceBroker(), NewSettings(testConfig))
+
+		updates, unsubscribe := streamer.Subscribe("bitcoin", "usd")
+		receiveUpdate(t, updates)
+		unsubscribe()
+
+		streamer.mu.Lock()
+		assert.Empty(t, streamer.pairs)
+		streamer.mu.Unlock()
+
+		service.mu.Lock()
+		calls := service.calls
+		service.mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		service.mu.Lock()
+		assert.LessOrEqual(t, service.calls, calls+1)
+		service.mu.Unlock()
+	})
+}
+
+// fails the first failures subscribe calls, then behaves like the local broker
+type flakyPriceBroker struct {
+	PriceBroker
+	mu       sync.Mutex
+	failures int
+}
+
+func (b *flakyPriceBroker) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures > 0 {
+		b.failures--
+		return nil, errors.New("redis unavailable")
+	}
+	return b.PriceBroker.Subscribe(ctx, channel)
+}
+
+func TestPriceStreamerSubscribeRetry(t *testing.T) {
+	defer func(backoff time.Duration) { streamSubscribeBackoff = backoff }(streamSubscribeBackoff)
+	streamSubscribeBackoff = 5 * time.Millisecond
+
+	service := &sequencePriceService{prices: []float64{100}}
+	broker := &flakyPriceBroker{PriceBroker: NewLocalPriceBroker(), failures: 2}
+	streamer := NewPriceStreamer(service, broker, NewSettings(Config{StreamPollInterval: 10 * time.Millisecond, StreamThreshold: 0.01}))
+
+	updates, unsubscribe := streamer.Subscribe("bitcoin", "usd")
+	defer unsubscribe()
+	assert.Equal(t, 100.00, receiveUpdate(t, updates).Price) // the pair recovers instead of going silent
+
+	// a later subscriber to the same pair is served by the recovered stream
+	later, unsubscribeLater := streamer.Subscribe("bitcoin", "usd")
+	defer unsubscribeLater()
+	assert.Equal(t, 100.00, receiveUpdate(t, later).Price)
+}
@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+// backend that secret values are read from by name, e.g. "db_password"
+type Provider interface {
+	Name() string
+	Fetch(ctx context.Context, name string) (string, error)
+}
+
+// reads secrets from environment variables, db_password -> DB_PASSWORD
+type envProvider struct{}
+
+func NewEnvProvider() Provider {
+	return &envProvider{}
+}
+
+func (p *envProvider) Name() string { return "env" }
+
+func (p *envProvider) Fetch(ctx context.Context, name string) (string, error) {
+	val, ok := os.LookupEnv(strings.ToUpper(name))
+	if !ok || val == "" {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return val, nil
+}
+
+// reads secrets from a mounted directory with one file per secret, as docker and kubernetes secrets are laid out
+type fileProvider struct {
+	dir string
+}
+
+func NewFileProvider(dir string) Provider {
+	return &fileProvider{dir: dir}
+}
+
+func (p *fileProvider) Name() string { return "file" }
+
+func (p *fileProvider) Fetch(ctx context.Context, name string) (string, error) {
+	// names are plain identifiers, never paths
+	if name != filepath.Base(name) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	raw, err := os.ReadFile(filepath.Join(p.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	val := strings.TrimRight(string(raw), "\r\n") // editors and echo leave a trailing newline
+	if val == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrSecretNotFound, name)
+	}
+	return val, nil
+}
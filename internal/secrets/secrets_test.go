@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "from-env")
+		val, err := NewEnvProvider().Fetch(ctx, "db_password")
+		assert.NoError(t, err)
+		assert.Equal(t, "from-env", val)
+
+		_, err = NewEnvProvider().Fetch(ctx, "missing_secret")
+		assert.True(t, errors.Is(err, ErrSecretNotFound))
+	})
+
+	t.Run("File", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "db_password"), []byte("from-file\n"), 0600))
+		provider := NewFileProvider(dir)
+
+		val, err := provider.Fetch(ctx, "db_password")
+		assert.NoError(t, err)
+		assert.Equal(t, "from-file", val)
+
+		_, err = provider.Fetch(ctx, "redis_password")
+		assert.True(t, errors.Is(err, ErrSecretNotFound))
+		_, err = provider.Fetch(ctx, "../db_password")
+		assert.Error(t, err)
+	})
+
+	t.Run("Vault", func(t *testing.T) {
+		stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != "root" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if r.URL.Path != "/v1/secret/data/cryo" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"data":{"data":{"hmac_reference_key":"from-vault"},"metadata":{"version":3}}}`))
+		}))
+		defer stub.Close()
+
+		provider := NewVaultProvider(VaultConfig{Addr: stub.URL, Token: "root", Path: "cryo", Timeout: time.Second})
+		val, err := provider.Fetch(ctx, "hmac_reference_key")
+		assert.NoError(t, err)
+		assert.Equal(t, "from-vault", val)
+
+		_, err = provider.Fetch(ctx, "db_password")
+		assert.True(t, errors.Is(err, ErrSecretNotFound))
+
+		_, err = NewVaultProvider(VaultConfig{Addr: stub.URL, Token: "wrong", Path: "cryo", Timeout: time.Second}).Fetch(ctx, "hmac_reference_key")
+		assert.ErrorContains(t, err, "status 403")
+		assert.False(t, errors.Is(err, ErrSecretNotFound))
+	})
+}
+
+type countingProvider struct {
+	calls atomic.Int32
+	value atomic.Value
+	err   error
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Fetch(ctx context.Context, name string) (string, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return "", p.err
+	}
+	return p.value.Load().(string), nil
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Lazy Fetch And Refresh", func(t *testing.T) {
+		provider := &countingProvider{}
+		provider.value.Store("v1")
+		store := NewStore(provider)
+
+		secret := store.Secret("hmac_reference_key", "")
+		assert.Equal(t, int32(0), provider.calls.Load())
+		val, _ := secret.Value(ctx)
+		assert.Equal(t, "v1", val)
+		val, _ = secret.Value(ctx)
+		assert.Equal(t, "v1", val)
+		assert.Equal(t, int32(1), provider.calls.Load())
+
+		provider.value.Store("v2")
+		store.(*storeImpl).refreshAll(ctx)
+		val, _ = secret.Value(ctx)
+		assert.Equal(t, "v2", val)
+	})
+
+	t.Run("Fallback When Not Found", func(t *testing.T) {
+		store := NewStore(&countingProvider{err: ErrSecretNotFound})
+		val, err := store.Secret("db_password", "cryopass").Value(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "cryopass", val)
+	})
+
+	t.Run("Provider Errors Surface", func(t *testing.T) {
+		store := NewStore(&countingProvider{err: errors.New("vault sealed")})
+		_, err := store.Secret("db_password", "cryopass").Value(ctx)
+		assert.ErrorContains(t, err, "vault sealed")
+	})
+
+	t.Run("Failed Refresh Keeps Last Value", func(t *testing.T) {
+		provider := &countingProvider{}
+		provider.value.Store("v1")
+		store := NewStore(provider)
+		secret := store.Secret("db_password", "")
+		_, _ = secret.Value(ctx)
+
+		provider.err = errors.New("timeout")
+		store.(*storeImpl).refreshAll(ctx)
+		val, err := secret.Value(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", val)
+	})
+}
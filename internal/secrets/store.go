@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// handle to a named secret, resolved on first use and kept current by the store
+type Secret interface {
+	Value(ctx context.Context) (string, error)
+}
+
+// lazily fetches secrets from a provider and periodically refreshes the ones in use
+type Store interface {
+	// fallback is used when the provider has no value for name, and may be empty
+	Secret(name string, fallback string) Secret
+	Start(ctx context.Context, interval time.Duration)
+}
+
+type storeImpl struct {
+	provider Provider
+	mu       sync.Mutex
+	secrets  map[string]*secretImpl
+}
+
+func NewStore(provider Provider) Store {
+	return &storeImpl{provider: provider, secrets: make(map[string]*secretImpl)}
+}
+
+func (s *storeImpl) Secret(name string, fallback string) Secret {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if secret, ok := s.secrets[name]; ok {
+		return secret
+	}
+	secret := &secretImpl{name: name, fallback: fallback, provider: s.provider}
+	s.secrets[name] = secret
+	return secret
+}
+
+// refresh every secret that has been resolved at least once until ctx is cancelled
+func (s *storeImpl) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		log.Println("Secret refresh disabled")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.refreshAll(ctx)
+			}
+		}
+	}()
+}
+
+func (s *storeImpl) refreshAll(ctx context.Context) {
+	s.mu.Lock()
+	var loaded []*secretImpl
+	for _, secret := range s.secrets {
+		if secret.isLoaded() {
+			loaded = append(loaded, secret)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, secret := range loaded {
+		if err := secret.refresh(ctx); err != nil {
+			// keep serving the last good value rather than failing callers mid rotation
+			log.Printf("Failed to refresh secret %s from %s provider: %v", secret.name, s.provider.Name(), err)
+		}
+	}
+}
+
+type secretImpl struct {
+	name     string
+	fallback string
+	provider Provider
+
+	mu     sync.Mutex
+	value  string
+	loaded bool
+}
+
+func (s *secretImpl) Value(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	if s.loaded {
+		defer s.mu.Unlock()
+		return s.value, nil
+	}
+	s.mu.Unlock()
+
+	if err := s.refresh(ctx); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value, nil
+}
+
+func (s *secretImpl) refresh(ctx context.Context) error {
+	value, err := s.provider.Fetch(ctx, s.name)
+	if errors.Is(err, ErrSecretNotFound) {
+		value, err = s.fallback, nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to resolve secret %s: %w", s.name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value = value
+	s.loaded = true
+	return nil
+}
+
+func (s *secretImpl) isLoaded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loaded
+}
+
+// fixed secret value, for tests and values that never rotate
+type staticSecret string
+
+func Static(value string) Secret {
+	return staticSecret(value)
+}
+
+func (s staticSecret) Value(ctx context.Context) (string, error) {
+	return string(s), nil
+}
@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+	"github.com/tidwall/gjson"
+)
+
+type VaultConfig struct {
+	Addr    string // e.g. https://vault.internal:8200
+	Token   string
+	Mount   string // kv v2 mount, "secret" by default
+	Path    string // path under the mount holding every app secret as a key
+	Timeout time.Duration
+}
+
+// reads keys from a single kv v2 secret over vault's http api
+type vaultProvider struct {
+	client *resty.Client
+	config VaultConfig
+}
+
+func NewVaultProvider(cfg VaultConfig) Provider {
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	client := resty.New().
+		SetBaseURL(strings.TrimRight(cfg.Addr, "/")).
+		SetHeader("X-Vault-Token", cfg.Token).
+		SetTimeout(cfg.Timeout)
+	return &vaultProvider{client: client, config: cfg}
+}
+
+func (p *vaultProvider) Name() string { return "vault" }
+
+func (p *vaultProvider) Fetch(ctx context.Context, name string) (string, error) {
+	url := fmt.Sprintf("/v1/%s/data/%s", strings.Trim(p.config.Mount, "/"), strings.Trim(p.config.Path, "/"))
+	resp, err := p.client.R().SetContext(ctx).Get(url)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s (no secret at %s)", ErrSecretNotFound, name, p.config.Path)
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("vault returned status %d", resp.StatusCode())
+	}
+
+	// kv v2 nests the stored keys under data.data
+	val := gjson.GetBytes(resp.Body(), "data.data."+gjson.Escape(name))
+	if !val.Exists() || val.String() == "" {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return val.String(), nil
+}
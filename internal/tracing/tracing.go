@@ -0,0 +1,46 @@
+// Package tracing installs the global OpenTelemetry tracer provider and W3C propagators.
+// packages create spans through otel.Tracer, which stays a no-op until Setup installs an exporter
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// flushes buffered spans and stops the exporter
+type Shutdown func(ctx context.Context) error
+
+// install propagators and, for the otlp exporter, a batching tracer provider
+func Setup(ctx context.Context, cfg config.TracingConfig) (Shutdown, error) {
+	// always propagate so upstream trace ids reach outbound calls even when we export nothing
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Exporter != "otlp" {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	log.Printf("Exporting traces to %s (sample ratio %v)", cfg.Endpoint, cfg.SampleRatio)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("No-op Default Still Propagates", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), config.Defaults().Tracing)
+		assert.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+		assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+	})
+
+	t.Run("OTLP Exporter", func(t *testing.T) {
+		previous := otel.GetTracerProvider()
+		defer otel.SetTracerProvider(previous)
+
+		cfg := config.Defaults().Tracing
+		cfg.Exporter = "otlp"
+		shutdown, err := Setup(context.Background(), cfg)
+		assert.NoError(t, err)
+		assert.IsType(t, &sdktrace.TracerProvider{}, otel.GetTracerProvider())
+		assert.NoError(t, shutdown(context.Background())) // nothing buffered, so nothing is sent
+	})
+}
+
+func TestInboundTraceparent(t *testing.T) {
+	_, err := Setup(context.Background(), config.Defaults().Tracing)
+	assert.NoError(t, err)
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(otelgin.Middleware("cryo", otelgin.WithTracerProvider(provider)))
+	var handlerTrace trace.TraceID
+	router.GET("/price", func(c *gin.Context) {
+		handlerTrace = trace.SpanContextFromContext(c.Request.Context()).TraceID()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/price", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the request span continues the caller's trace rather than starting a new one
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", handlerTrace.String())
+	assert.Equal(t, "/price", exporter.GetSpans()[0].Name)
+}
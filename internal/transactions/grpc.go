@@ -0,0 +1,113 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	cryov1 "github.com/undersleep7x/cryo-project/api/proto/cryo/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpc counterpart of TransactionsHandler, sharing its service
+type TransactionGRPCServer struct {
+	cryov1.UnimplementedTransactionServiceServer
+	service TransactionService
+}
+
+func NewTransactionGRPCServer(service TransactionService) *TransactionGRPCServer {
+	return &TransactionGRPCServer{service: service}
+}
+
+func (s *TransactionGRPCServer) CreateInvoice(ctx context.Context, req *cryov1.CreateInvoiceRequest) (*cryov1.CreateInvoiceResponse, error) {
+	inv, err := s.service.CreateInvoice(ctx, InvoiceRequest{
+		RecipientId:  req.GetRecipientId(),
+		Currency:     req.GetCurrency(),
+		Amount:       req.GetAmount(),
+		FiatCurrency: req.GetFiatCurrency(),
+		FiatAmount:   req.GetFiatAmount(),
+		ExternalRef:  req.ExternalRef,
+		SenderType:   req.GetSenderType(),
+		RefundRef:    req.RefundRef,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp := &cryov1.CreateInvoiceResponse{
+		TransactionId: inv.TransactionId,
+		Status:        inv.Status,
+		ExternalRef:   inv.ExternalRef,
+		Currency:      inv.Currency,
+		Amount:        inv.Amount,
+	}
+	if q := inv.Quote; q != nil {
+		resp.Quote = &cryov1.PriceQuote{
+			FiatCurrency: q.FiatCurrency,
+			FiatAmount:   q.FiatAmount,
+			Rate:         q.Rate,
+			CryptoAmount: q.CryptoAmount,
+			LockedAt:     timestamppb.New(q.LockedAt),
+			ExpiresAt:    timestamppb.New(q.ExpiresAt),
+		}
+	}
+	return resp, nil
+}
+
+func (s *TransactionGRPCServer) SendPayment(ctx context.Context, req *cryov1.SendPaymentRequest) (*cryov1.SendPaymentResponse, error) {
+	txn, err := s.service.SendPayment(ctx, PaymentRequest{
+		SenderId:    req.GetSenderId(),
+		Currency:    req.GetCurrency(),
+		Amount:      req.GetAmount(),
+		PaymentAddr: req.GetPaymentAddress(),
+		SenderType:  req.GetSenderType(),
+		WalletRef:   req.GetWalletRef(),
+		InvoiceId:   req.GetInvoiceId(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &cryov1.SendPaymentResponse{
+		TransactionId:  txn.TransactionId,
+		PaymentAddress: txn.PaymentAddr,
+		Status:         txn.Status,
+		ExternalRef:    txn.ExternalRef,
+	}, nil
+}
+
+func (s *TransactionGRPCServer) WatchTransaction(req *cryov1.WatchTransactionRequest, stream cryov1.TransactionService_WatchTransactionServer) error {
+	updates, err := s.service.WatchTransaction(stream.Context(), req.GetTransactionId())
+	if err != nil {
+		return grpcError(err)
+	}
+	for update := range updates {
+		if err := stream.Send(&cryov1.TransactionStatus{
+			TransactionId: update.TransactionId,
+			Status:        update.Status,
+			UpdatedAt:     timestamppb.New(update.UpdatedAt),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// same mapping as the http handlers, onto grpc codes
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidInvoice):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrPaymentAmountMismatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrQuoteUnavailable):
+		return status.Error(codes.Unavailable, "exchange rate unavailable, try again shortly")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "request timed out")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	default:
+		log.Printf("Internal Server Error in grpc call: %v", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
@@ -1,10 +1,12 @@
 package transactions
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
 )
 
 type TransactionsHandler struct {
@@ -23,7 +25,21 @@ func (f *TransactionsHandler) CreateInvoice(c *gin.Context) {
 		return
 	}
 
-	inv, err := f.service.CreateInvoice(request) // call service for invoices
+	inv, err := f.service.CreateInvoice(c.Request.Context(), request) // call service for invoices
+	if err != nil && c.Request.Context().Err() != nil { // route deadline hit or client went away mid request
+		log.Printf("CreateInvoice abandoned: %v", err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+		return
+	}
+	if errors.Is(err, ErrInvalidInvoice) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrQuoteUnavailable) {
+		log.Printf("Quote unavailable when calling CreateInvoice: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Exchange rate unavailable, try again shortly"})
+		return
+	}
 	if err != nil { //catch for service failure
 		log.Printf("Internal Server Error when calling CreateInvoice: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
@@ -41,7 +57,21 @@ func (f *TransactionsHandler) SendPayment(c *gin.Context) {
 		return
 	}
 
-	txn, err := f.service.SendPayment(request) // call service for invoices
+	txn, err := f.service.SendPayment(c.Request.Context(), request) // call service for invoices
+	if err != nil && c.Request.Context().Err() != nil { // route deadline hit or client went away mid request
+		log.Printf("SendPayment abandoned: %v", err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+		return
+	}
+	if errors.Is(err, ErrPaymentAmountMismatch) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrQuoteUnavailable) {
+		log.Printf("Quote unavailable when calling SendPayment: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Exchange rate unavailable, try again shortly"})
+		return
+	}
 	if err != nil { //catch for service failure
 		log.Printf("Internal Server Error when calling SendPayment: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send payment"})
@@ -49,4 +79,59 @@ func (f *TransactionsHandler) SendPayment(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, txn)
-}
\ No newline at end of file
+}
+func (f *TransactionsHandler) GetTransaction(c *gin.Context) {
+	txn, err := f.service.GetTransaction(c.Request.Context(), c.Param("id"))
+	if err != nil && c.Request.Context().Err() != nil { // route deadline hit or client went away mid request
+		log.Printf("GetTransaction abandoned: %v", err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+		return
+	}
+	if errors.Is(err, ErrTransactionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil { //catch for service failure
+		log.Printf("Internal Server Error when calling GetTransaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, txn)
+}
+
+// a merchant's transactions, the merchant comes from the merchant_id query param and its
+// account number from the X-Account-Number header
+func (f *TransactionsHandler) ListTransactions(c *gin.Context) {
+	merchantId := c.Query("merchant_id")
+	if merchantId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "merchant_id is required"})
+		return
+	}
+
+	txns, err := f.service.ListTransactions(c.Request.Context(), merchantId, c.GetHeader(accounts.AccountNumberHeader))
+	if err != nil && c.Request.Context().Err() != nil { // route deadline hit or client went away mid request
+		log.Printf("ListTransactions abandoned: %v", err)
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+		return
+	}
+	if errors.Is(err, accounts.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, accounts.ErrUnauthorized) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account number required in " + accounts.AccountNumberHeader})
+		return
+	}
+	if errors.Is(err, accounts.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil { //catch for service failure
+		log.Printf("Internal Server Error when calling ListTransactions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list transactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transactions": txns})
+}
@@ -20,8 +20,10 @@ type Transaction interface {
 
 type InvoiceRequest struct {
 	RecipientId string `json:"recipient_id"` //invoices can only be sent by merchants, this would essentially be a ref to merchant id
-	Currency string `json:"currency"`
-	Amount float64 `json:"amount"`
+	Currency string `json:"currency"` // crypto the invoice will be paid in
+	Amount float64 `json:"amount"` // crypto amount, ignored when the invoice is priced in fiat
+	FiatCurrency string `json:"fiat_currency,omitempty"` // when set, the invoice is priced in fiat and converted with a locked quote
+	FiatAmount float64 `json:"fiat_amount,omitempty"`
 	ExternalRef *string `json:"external_ref,omitempty"`
 	SenderType string `json:"sender_type"`
 	RefundRef *string `json:"refund_ref,omitempty"`
@@ -33,6 +35,9 @@ type InvoiceResponse struct {
 	// shouldn't need to return payment address if we link to the wallet where money will be going
 	Status string `json:"status"` // invoice, pending, confirmed, failed
 	ExternalRef *string `json:"external_ref,omitempty"`
+	Currency string `json:"currency"`
+	Amount float64 `json:"amount"` // crypto amount due
+	Quote *PriceQuote `json:"quote,omitempty"` // locked exchange rate for fiat priced invoices
 }
 
 type Invoice struct {
@@ -48,6 +53,7 @@ type Invoice struct {
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	ExternalRef *string `json:"external_ref,omitempty" gorm:"index"` //optional tracking id for merchants external systems
+	Quote *PriceQuote `json:"quote,omitempty" gorm:"embedded;embeddedPrefix:quote_"` // locked fiat exchange rate, nil for crypto priced invoices
 }
 
 // invoice -> transaction implementation func's
@@ -90,6 +96,9 @@ func (i Invoice) Updated() time.Time {
 func (i *Invoice) SetUpdate(t time.Time) {
 	i.UpdatedAt = t
 }
+func (i Invoice) GetQuote() *PriceQuote {
+	return i.Quote
+}
 func (i Invoice) GetExternalRef() *string {
 	if i.ExternalRef != nil{
 		return i.ExternalRef
@@ -187,3 +196,41 @@ func (p Payment) Updated() time.Time {
 // 		return nil
 // 	}
 // }
+
+// invoice or payment as returned by the read routes, without the hashed refs it's stored under
+type TransactionDetails struct {
+	TransactionId string `json:"transaction_id"`
+	Type string `json:"type"` // invoice or payment
+	Status string `json:"status"` // invoice, pending, confirmed, failed
+	Currency string `json:"currency"`
+	Amount float64 `json:"amount"`
+	TxnHash string `json:"tx_hash,omitempty"` // set once the transaction is on chain
+	ExternalRef *string `json:"external_ref,omitempty"`
+	Quote *PriceQuote `json:"quote,omitempty"` // locked exchange rate for fiat priced invoices
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func transactionDetails(txn Transaction) TransactionDetails {
+	details := TransactionDetails{
+		TransactionId: txn.GetID(),
+		Type: "payment",
+		Status: txn.GetStatus(),
+		Currency: txn.GetCurrency(),
+		Amount: txn.GetAmount(),
+		TxnHash: txn.GetTxnHash(),
+		CreatedAt: txn.Created(),
+		UpdatedAt: txn.Updated(),
+	}
+	switch inv := txn.(type) {
+	case Invoice:
+		details.Type = "invoice"
+		details.ExternalRef = inv.GetExternalRef()
+		details.Quote = inv.GetQuote()
+	case *Invoice:
+		details.Type = "invoice"
+		details.ExternalRef = inv.GetExternalRef()
+		details.Quote = inv.GetQuote()
+	}
+	return details
+}
@@ -0,0 +1,101 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"github.com/undersleep7x/cryo-project/internal/crypto/keys"
+)
+
+var (
+	ErrInvalidInvoice        = errors.New("invalid invoice request")
+	ErrQuoteUnavailable      = errors.New("exchange rate unavailable")
+	ErrPaymentAmountMismatch = errors.New("payment amount outside quote tolerance")
+)
+
+type Config struct {
+	QuoteLockWindow time.Duration  // how long a fiat -> crypto rate is honored after it is quoted
+	QuoteTolerance  float64        // fractional under/over payment accepted against the quoted amount (0.01 = 1%)
+	Keys            keys.Deriver   // purpose scoped keys recipient references are derived with
+}
+
+// fiat -> crypto exchange rate locked onto an invoice
+type PriceQuote struct {
+	FiatCurrency string    `json:"fiat_currency"`
+	FiatAmount   float64   `json:"fiat_amount"`
+	Rate         float64   `json:"rate"` // price of one unit of crypto in fiat
+	CryptoAmount float64   `json:"crypto_amount"`
+	LockedAt     time.Time `json:"locked_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func (q PriceQuote) Expired(at time.Time) bool {
+	return !at.Before(q.ExpiresAt)
+}
+
+// quote a fiat amount in the requested crypto using the current price and lock it for the configured window
+func (s *transactionsServiceImpl) lockQuote(ctx context.Context, asset assets.Asset, fiatCurrency string, fiatAmount float64, at time.Time) (*PriceQuote, error) {
+	fiatCurrency = strings.ToLower(fiatCurrency)
+	prices, err := s.prices.FetchCryptoPrice(ctx, []string{asset.CoinGeckoID}, fiatCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrQuoteUnavailable, err)
+	}
+	rate, ok := prices.Price(asset.CoinGeckoID) // stale prices are never locked into a quote
+	if !ok || rate <= 0 {
+		return nil, fmt.Errorf("%w: no %s price for %s", ErrQuoteUnavailable, fiatCurrency, asset.Ticker)
+	}
+
+	return &PriceQuote{
+		FiatCurrency: fiatCurrency,
+		FiatAmount:   fiatAmount,
+		Rate:         rate,
+		CryptoAmount: roundToDecimals(fiatAmount/rate, asset.Decimals),
+		LockedAt:     at,
+		ExpiresAt:    at.Add(s.config.QuoteLockWindow),
+	}, nil
+}
+
+// compare a detected payment against the invoice quote, re-quoting first if the lock has expired
+func (s *transactionsServiceImpl) reconcileQuotedPayment(ctx context.Context, inv *Invoice, received float64, at time.Time) error {
+	if inv.Quote == nil {
+		return nil
+	}
+
+	if inv.Quote.Expired(at) {
+		log.Printf("Quote for invoice %s expired at %s, re-quoting", inv.ID, inv.Quote.ExpiresAt.Format(time.RFC3339))
+		asset, ok := s.assets.Lookup(inv.Currency)
+		if !ok {
+			return fmt.Errorf("%w: unsupported currency %q", ErrQuoteUnavailable, inv.Currency)
+		}
+		quote, err := s.lockQuote(ctx, asset, inv.Quote.FiatCurrency, inv.Quote.FiatAmount, at)
+		if err != nil {
+			return err
+		}
+		inv.Quote = quote
+		inv.Amount = quote.CryptoAmount
+	}
+
+	expected := inv.Quote.CryptoAmount
+	if math.Abs(received-expected) > expected*s.config.QuoteTolerance {
+		return fmt.Errorf("%w: received %s %s, expected %s", ErrPaymentAmountMismatch, formatAmount(received), inv.Currency, formatAmount(expected))
+	}
+	return nil
+}
+
+// crypto amounts converted from fiat are rounded to the asset's smallest unit (satoshi, wei, piconero...)
+func roundToDecimals(amount float64, decimals int) float64 {
+	precision := math.Pow10(decimals)
+	return math.Round(amount*precision) / precision
+}
+
+// shortest decimal form, assets differ in how many places are significant
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -0,0 +1,162 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"github.com/undersleep7x/cryo-project/internal/crypto/keys"
+	"github.com/undersleep7x/cryo-project/internal/prices"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+)
+
+type mockPriceService struct {
+	prices.FetchCryptoPriceService
+	rate  float64
+	err   error
+	calls int
+}
+
+func (m *mockPriceService) FetchCryptoPrice(ctx context.Context, cryptoList []string, currency string) (*prices.PriceResult, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &prices.PriceResult{Currency: currency, Prices: map[string]prices.AssetPrice{
+		cryptoList[0]: {Crypto: cryptoList[0], Status: prices.StatusOK, Price: m.rate},
+	}}, nil
+}
+
+type mockTxnRepository struct {
+	TxnRepository
+	saved []Transaction
+}
+
+func (m *mockTxnRepository) SaveTransaction(ctx context.Context, txn Transaction) error {
+	m.saved = append(m.saved, txn)
+	return nil
+}
+
+func TestFiatInvoiceQuote(t *testing.T) {
+	testConfig := Config{QuoteLockWindow: 15 * time.Minute, QuoteTolerance: 0.01, Keys: keys.NewDeriver(secrets.Static("test-key"))}
+	registry, _ := assets.NewRegistry(assets.DefaultAssets)
+	accountService, merchantId := newTestMerchant(t, registry, accounts.WalletRequest{Currency: "BTC", Type: accounts.WalletOTA})
+
+	t.Run("Locks Quote", func(t *testing.T) {
+		priceService := &mockPriceService{rate: 50000}
+		repo := &mockTxnRepository{}
+		service := NewTransactionsService(repo, priceService, registry, accountService, prices.NewLocalPriceBroker(), testConfig)
+
+		resp, err := service.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: merchantId, Currency: "bitcoin", FiatCurrency: "usd", FiatAmount: 100})
+		assert.NoError(t, err)
+		assert.Equal(t, 0.002, resp.Amount)
+		assert.Equal(t, "BTC", resp.Currency)
+		assert.NotNil(t, resp.Quote)
+		assert.Equal(t, 50000.00, resp.Quote.Rate)
+		assert.Equal(t, 15*time.Minute, resp.Quote.ExpiresAt.Sub(resp.Quote.LockedAt))
+		assert.Len(t, repo.saved, 1)
+	})
+
+	t.Run("Price Unavailable", func(t *testing.T) {
+		service := NewTransactionsService(&mockTxnRepository{}, &mockPriceService{err: errors.New("down")}, registry, accountService, prices.NewLocalPriceBroker(), testConfig)
+
+		_, err := service.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: merchantId, Currency: "bitcoin", FiatCurrency: "usd", FiatAmount: 100})
+		assert.ErrorIs(t, err, ErrQuoteUnavailable)
+	})
+
+	t.Run("Unsupported Currency", func(t *testing.T) {
+		service := NewTransactionsService(&mockTxnRepository{}, &mockPriceService{rate: 50000}, registry, accountService, prices.NewLocalPriceBroker(), testConfig)
+
+		_, err := service.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: merchantId, Currency: "dogecoin", Amount: 10})
+		assert.ErrorIs(t, err, ErrInvalidInvoice)
+	})
+
+	t.Run("Missing Fiat Amount", func(t *testing.T) {
+		service := NewTransactionsService(&mockTxnRepository{}, &mockPriceService{rate: 50000}, registry, accountService, prices.NewLocalPriceBroker(), testConfig)
+
+		_, err := service.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: merchantId, Currency: "bitcoin", FiatCurrency: "usd"})
+		assert.ErrorIs(t, err, ErrInvalidInvoice)
+	})
+}
+
+func TestQuoteDecimals(t *testing.T) {
+	testConfig := Config{QuoteLockWindow: 15 * time.Minute, QuoteTolerance: 0.01, Keys: keys.NewDeriver(secrets.Static("test-key"))}
+	registry, _ := assets.NewRegistry(assets.DefaultAssets)
+	service := &transactionsServiceImpl{prices: &mockPriceService{rate: 3000}, assets: registry, config: testConfig}
+
+	// one cent at 3000 a unit, rounded to each asset's smallest unit
+	tests := map[string]struct {
+		ticker   string
+		decimals int
+		expected float64
+	}{
+		"6 Decimals":  {ticker: "USDT", decimals: 6, expected: 0.000003},
+		"8 Decimals":  {ticker: "BTC", decimals: 8, expected: 0.00000333},
+		"12 Decimals": {ticker: "XMR", decimals: 12, expected: 0.000003333333},
+		"18 Decimals": {ticker: "ETH", decimals: 18, expected: 0.000003333333333333},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			asset, ok := registry.Lookup(tt.ticker)
+			assert.True(t, ok)
+			assert.Equal(t, tt.decimals, asset.Decimals)
+
+			quote, err := service.lockQuote(context.Background(), asset, "usd", 0.01, time.Now())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, quote.CryptoAmount)
+		})
+	}
+}
+
+func TestReconcileQuotedPayment(t *testing.T) {
+	testConfig := Config{QuoteLockWindow: 15 * time.Minute, QuoteTolerance: 0.01, Keys: keys.NewDeriver(secrets.Static("test-key"))}
+	registry, _ := assets.NewRegistry(assets.DefaultAssets)
+	lockedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	newInvoice := func() *Invoice {
+		return &Invoice{
+			ID:       "txn_test",
+			Currency: "BTC",
+			Amount:   0.002,
+			Quote: &PriceQuote{
+				FiatCurrency: "usd",
+				FiatAmount:   100,
+				Rate:         50000,
+				CryptoAmount: 0.002,
+				LockedAt:     lockedAt,
+				ExpiresAt:    lockedAt.Add(15 * time.Minute),
+			},
+		}
+	}
+
+	t.Run("Within Tolerance", func(t *testing.T) {
+		priceService := &mockPriceService{rate: 40000}
+		service := &transactionsServiceImpl{prices: priceService, assets: registry, config: testConfig}
+
+		err := service.reconcileQuotedPayment(context.Background(), newInvoice(), 0.00199, lockedAt.Add(5*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, priceService.calls)
+	})
+
+	t.Run("Outside Tolerance", func(t *testing.T) {
+		service := &transactionsServiceImpl{prices: &mockPriceService{rate: 50000}, assets: registry, config: testConfig}
+
+		err := service.reconcileQuotedPayment(context.Background(), newInvoice(), 0.0015, lockedAt.Add(5*time.Minute))
+		assert.ErrorIs(t, err, ErrPaymentAmountMismatch)
+	})
+
+	t.Run("Expired Lock Requotes", func(t *testing.T) {
+		priceService := &mockPriceService{rate: 40000}
+		service := &transactionsServiceImpl{prices: priceService, assets: registry, config: testConfig}
+		inv := newInvoice()
+
+		err := service.reconcileQuotedPayment(context.Background(), inv, 0.0025, lockedAt.Add(20*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, priceService.calls)
+		assert.Equal(t, 0.0025, inv.Amount)
+		assert.Equal(t, 40000.00, inv.Quote.Rate)
+	})
+}
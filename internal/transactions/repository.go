@@ -1,31 +1,77 @@
 package transactions
 
 import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
 	// "log"
 )
 
+var ErrTransactionNotFound = errors.New("transaction not found")
+
 type TxnRepository interface {
-	SaveTransaction(txn Transaction) error
-	FindTransactionById(txnId string) Invoice
-	UpdateTransactionById(txx Transaction) error
+	SaveTransaction(ctx context.Context, txn Transaction) error
+	FindTransactionById(ctx context.Context, txnId string) Invoice
+	UpdateTransactionById(ctx context.Context, txx Transaction) error
+	GetTransaction(ctx context.Context, txnId string) (Transaction, error)
+	ListTransactions(ctx context.Context, recipientRef string) ([]Transaction, error) // newest first
 }
 
+// kept in memory until transactions move onto the postgres schema, so reads only see what
+// this replica saved since it started
 type txnRepository struct {
-
+	mu   sync.RWMutex
+	txns map[string]Transaction
 }
 
 func NewTxnRepository() TxnRepository {
-	return &txnRepository{}
+	return &txnRepository{txns: map[string]Transaction{}}
 }
 
-func (r *txnRepository) SaveTransaction(txn Transaction) error {
+func (r *txnRepository) SaveTransaction(ctx context.Context, txn Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.txns[txn.GetID()] = txn
 	return nil
 }
 
-func (r *txnRepository) FindTransactionById(txnId string) Invoice {
-	return Invoice{}
+func (r *txnRepository) FindTransactionById(ctx context.Context, txnId string) Invoice {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	inv, _ := r.txns[txnId].(Invoice)
+	return inv
 }
 
-func (r *txnRepository) UpdateTransactionById(txx Transaction) error {
+// only replaces a saved transaction, an unknown id is left alone rather than inserted
+func (r *txnRepository) UpdateTransactionById(ctx context.Context, txx Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.txns[txx.GetID()]; ok {
+		r.txns[txx.GetID()] = txx
+	}
 	return nil
 }
+
+func (r *txnRepository) GetTransaction(ctx context.Context, txnId string) (Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	txn, ok := r.txns[txnId]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	return txn, nil
+}
+
+func (r *txnRepository) ListTransactions(ctx context.Context, recipientRef string) ([]Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var txns []Transaction
+	for _, txn := range r.txns {
+		if txn.GetRecipientRef() == recipientRef {
+			txns = append(txns, txn)
+		}
+	}
+	sort.Slice(txns, func(i, j int) bool { return txns[i].Created().After(txns[j].Created()) })
+	return txns, nil
+}
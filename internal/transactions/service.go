@@ -1,53 +1,112 @@
 package transactions
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"github.com/undersleep7x/cryo-project/internal/crypto/keys"
+	"github.com/undersleep7x/cryo-project/internal/metrics"
+	"github.com/undersleep7x/cryo-project/internal/prices"
 	utils "github.com/undersleep7x/cryo-project/internal/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 )
 
+const tracerName = "github.com/undersleep7x/cryo-project/internal/transactions"
+
 // interface for transaction service
 type TransactionService interface {
-	CreateInvoice(InvoiceRequest) (*InvoiceResponse, error)
-	SendPayment(PaymentRequest) (*PaymentResponse, error)
+	CreateInvoice(context.Context, InvoiceRequest) (*InvoiceResponse, error)
+	SendPayment(context.Context, PaymentRequest) (*PaymentResponse, error)
+	WatchTransaction(ctx context.Context, txnId string) (<-chan StatusUpdate, error)
+	// public by id like WatchTransaction, ErrTransactionNotFound when unknown
+	GetTransaction(ctx context.Context, txnId string) (*TransactionDetails, error)
+	// a merchant's invoices, newest first. needs the merchant's account number, ErrUnauthorized
+	// without one and ErrForbidden when it's another account's
+	ListTransactions(ctx context.Context, merchantId string, accountNumber string) ([]TransactionDetails, error)
 }
 type transactionsServiceImpl struct{
 	r TxnRepository
+	prices prices.FetchCryptoPriceService
+	assets assets.Registry
+	accounts accounts.AccountService
+	statuses StatusBroker
+	config Config
 }
-func NewTransactionsService(repository TxnRepository) TransactionService {
-	return &transactionsServiceImpl{r: repository}
+func NewTransactionsService(repository TxnRepository, priceService prices.FetchCryptoPriceService, registry assets.Registry, accountService accounts.AccountService, statuses StatusBroker, cfg Config) TransactionService {
+	return &transactionsServiceImpl{r: repository, prices: priceService, assets: registry, accounts: accountService, statuses: statuses, config: cfg}
 }
 
 // service function for creating new invoice and saving to db
-func (s *transactionsServiceImpl) CreateInvoice(r InvoiceRequest) (*InvoiceResponse, error) {
-	currTime := time.Now()
-	userCreateTime := time.Now() //TODO will be replaced with user creation time when db flow more solidified
-	concatRef := utils.BuildReferenceString(r.RecipientId, currTime.Format(time.RFC3339), userCreateTime.Format(time.RFC3339))
-	recipientHash := utils.GenerateRef("hmac-key", concatRef, "dev") //TODO key will be merchant.account_ref
+func (s *transactionsServiceImpl) CreateInvoice(ctx context.Context, r InvoiceRequest) (*InvoiceResponse, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "TransactionService.CreateInvoice")
+	defer span.End()
 
+	currTime := time.Now()
 	resp := InvoiceResponse{}
 
+	// invoices are stored against the canonical ticker so any supported alias (BTC, btc, bitcoin) is accepted
+	asset, ok := s.assets.Lookup(r.Currency)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported currency %q, did you mean %v", ErrInvalidInvoice, r.Currency, s.assets.Suggest(r.Currency, 3))
+	}
+
+	// the recipient is a registered merchant, paid into the wallet it holds for the currency
+	merchant, wallet, err := s.recipient(ctx, r.RecipientId, asset.Ticker)
+	if err != nil {
+		return nil, err
+	}
+	recipientHash, err := s.recipientRef(ctx, merchant.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// fiat priced invoices lock a quote now and derive the crypto amount from it
+	amount := r.Amount
+	var quote *PriceQuote
+	if r.FiatCurrency != "" {
+		if r.FiatAmount <= 0 {
+			return nil, fmt.Errorf("%w: fiat_amount must be positive", ErrInvalidInvoice)
+		}
+		q, err := s.lockQuote(ctx, asset, r.FiatCurrency, r.FiatAmount, currTime)
+		if err != nil {
+			log.Printf("Error quoting invoice amount: %v", err)
+			return nil, err
+		}
+		quote = q
+		amount = q.CryptoAmount
+	} else if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidInvoice)
+	}
+
 	inv := Invoice {
 		ID: "txn_" + uuid.NewString(),
 		SenderType: r.SenderType,
 		RecipientRef: recipientHash,
-		WalletRef: GenerateOneTimeAddress(r.Currency),
+		WalletRef: receivingAddress(wallet),
 		RefundRef: r.ExternalRef,
-		Amount: r.Amount,
-		Currency: r.Currency,
+		Amount: amount,
+		Currency: asset.Ticker,
 		Status: "invoice",
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		ExternalRef: r.ExternalRef,
+		Quote: quote,
 	}
 
-	err := s.r.SaveTransaction(inv)
+	err = s.r.SaveTransaction(ctx, inv)
 	if err != nil {
 		log.Printf("Error saving new invoice to database: %v", err)
 		return nil, err
 	}
+	metrics.Invoices.WithLabelValues("created").Inc()
+	s.publishStatus(ctx, inv)
 
 	//TODO after creation and save to db, there must be logic that allows for tracking of the invoice
 	//such as identifying when payment has been made, following blockchain for confirmation, etc
@@ -55,6 +114,9 @@ func (s *transactionsServiceImpl) CreateInvoice(r InvoiceRequest) (*InvoiceRespo
 	resp.ExternalRef = inv.GetExternalRef()
 	resp.TransactionId = inv.GetID()
 	resp.Status = inv.GetStatus()
+	resp.Currency = inv.GetCurrency()
+	resp.Amount = inv.GetAmount()
+	resp.Quote = inv.GetQuote()
 	return &resp, nil
 
 	//TODO other todos to be mindful of
@@ -66,7 +128,22 @@ func (s *transactionsServiceImpl) CreateInvoice(r InvoiceRequest) (*InvoiceRespo
 
 }
 
-func (s *transactionsServiceImpl) SendPayment(r PaymentRequest) (*PaymentResponse, error) {
+func (s *transactionsServiceImpl) SendPayment(ctx context.Context, r PaymentRequest) (*PaymentResponse, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "TransactionService.SendPayment")
+	defer span.End()
+
+	response, err := s.sendPayment(ctx, r)
+	if err != nil {
+		metrics.Payments.WithLabelValues("failed").Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	metrics.Payments.WithLabelValues("sent").Inc()
+	return response, nil
+}
+
+func (s *transactionsServiceImpl) sendPayment(ctx context.Context, r PaymentRequest) (*PaymentResponse, error) {
 	senderRef := r.SenderId + "hash"
 	recipRef := r.PaymentAddr + "hash"
 	response := PaymentResponse{}
@@ -87,35 +164,42 @@ func (s *transactionsServiceImpl) SendPayment(r PaymentRequest) (*PaymentRespons
 			UpdatedAt: time.Now(),
 		}
 
-		err := s.r.SaveTransaction(pay)
+		err := s.r.SaveTransaction(ctx, pay)
 		if err != nil {
 			log.Printf("Error saving new invoice to database: %v", err)
 			return nil, err
 		}
+		s.publishStatus(ctx, pay)
 
 		response.PaymentAddr = pay.PaymentAddr
 		response.Status = pay.Status
 		response.TransactionId = pay.ID
 
 	} else { // flow for invoice payment
-		inv := s.r.FindTransactionById(r.InvoiceId)
+		inv := s.r.FindTransactionById(ctx, r.InvoiceId)
 		// stubbed info
 		externalRef := "anexternalref"
 		inv.ExternalRef = &externalRef
 		inv.ID = "txn_" + uuid.NewString()
 		inv.Status = "Invoice"
 		inv.ID = "1234555x05"
-		
+
+		// fiat priced invoices must be paid within tolerance of the locked (or refreshed) quote
+		if err := s.reconcileQuotedPayment(ctx, &inv, r.Amount, time.Now()); err != nil {
+			log.Printf("Payment for invoice %s rejected: %v", r.InvoiceId, err)
+			return nil, err
+		}
 
 		inv.SetTxnHash("txnHashFromBlockChain") //txnhash should be present even if txn is still "otw"
 		inv.SetStatus("Pending") //txn is on the way, will next be confirmed or failed
 		inv.SetUpdate(time.Now())
 
-		err := s.r.UpdateTransactionById(inv)
+		err := s.r.UpdateTransactionById(ctx, inv)
 		if err != nil {
 			log.Printf("Error saving new invoice to database: %v", err)
 			return nil, err
 		}
+		s.publishStatus(ctx, &inv)
 
 		response.ExternalRef = inv.ExternalRef
 		response.PaymentAddr = r.PaymentAddr
@@ -126,6 +210,81 @@ func (s *transactionsServiceImpl) SendPayment(r PaymentRequest) (*PaymentRespons
 	return &response, nil
 }
 
+func (s *transactionsServiceImpl) GetTransaction(ctx context.Context, txnId string) (*TransactionDetails, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "TransactionService.GetTransaction")
+	defer span.End()
+
+	txn, err := s.r.GetTransaction(ctx, txnId)
+	if err != nil {
+		return nil, err
+	}
+	details := transactionDetails(txn)
+	return &details, nil
+}
+
+func (s *transactionsServiceImpl) ListTransactions(ctx context.Context, merchantId string, accountNumber string) ([]TransactionDetails, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "TransactionService.ListTransactions")
+	defer span.End()
+
+	// GetMerchant treats a missing number as a public read, listings are owner only
+	if accountNumber == "" {
+		return nil, accounts.ErrUnauthorized
+	}
+	merchant, err := s.accounts.GetMerchant(ctx, merchantId, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	recipientHash, err := s.recipientRef(ctx, merchant.ID)
+	if err != nil {
+		return nil, err
+	}
+	txns, err := s.r.ListTransactions(ctx, recipientHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	details := make([]TransactionDetails, 0, len(txns))
+	for _, txn := range txns {
+		details = append(details, transactionDetails(txn))
+	}
+	return details, nil
+}
+
+func (s *transactionsServiceImpl) recipient(ctx context.Context, merchantId string, ticker string) (*accounts.Merchant, *accounts.Wallet, error) {
+	merchant, err := s.accounts.GetMerchant(ctx, merchantId, "")
+	if errors.Is(err, accounts.ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: unknown recipient %q", ErrInvalidInvoice, merchantId)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load recipient: %w", err)
+	}
+	wallet, err := s.accounts.ReceivingWallet(ctx, merchant.ID, ticker)
+	if errors.Is(err, accounts.ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: recipient has no wallet that can receive %s", ErrInvalidInvoice, ticker)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load recipient wallet: %w", err)
+	}
+	return merchant, wallet, nil
+}
+
+// ota wallets get a fresh address per invoice, the rest are paid at their fixed one
+func receivingAddress(wallet *accounts.Wallet) string {
+	if wallet.Type == accounts.WalletOTA || wallet.Address == nil {
+		return GenerateOneTimeAddress(wallet.Currency)
+	}
+	return *wallet.Address
+}
+
+// deterministic per merchant under its own recipient_ref key, so a merchant's transactions
+// are found by recomputing it rather than storing the merchant id
+func (s *transactionsServiceImpl) recipientRef(ctx context.Context, merchantId string) (string, error) {
+	ref, err := s.config.Keys.Ref(ctx, keys.PurposeRecipientRef, merchantId, merchantId, utils.TruncateNone)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive recipient ref: %w", err)
+	}
+	return ref, nil
+}
+
 var GenerateOneTimeAddress = func(currency string) string {
 	var genOta = "STUBOTA12345678"
 	log.Printf("One time address successfully generated")
@@ -0,0 +1,85 @@
+package transactions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+	"github.com/undersleep7x/cryo-project/internal/accounts/accountsfake"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"github.com/undersleep7x/cryo-project/internal/crypto/keys"
+	"github.com/undersleep7x/cryo-project/internal/prices"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+	utils "github.com/undersleep7x/cryo-project/internal/utils"
+)
+
+// registers an account and a merchant under it holding the given wallets
+func newTestMerchant(t *testing.T, registry assets.Registry, wallets ...accounts.WalletRequest) (accounts.AccountService, string) {
+	t.Helper()
+	ctx := context.Background()
+	service := accounts.NewAccountService(accountsfake.New(), registry, accounts.Config{Keys: keys.NewDeriver(secrets.Static("test-key"))})
+
+	registration, err := service.RegisterUser(ctx)
+	require.NoError(t, err)
+	merchant, err := service.CreateMerchant(ctx, accounts.MerchantRequest{AccountNumber: registration.AccountNumber, Name: "Test Merchant"})
+	require.NoError(t, err)
+	for _, wallet := range wallets {
+		_, err := service.AttachWallet(ctx, accounts.OwnerMerchant, merchant.ID, registration.AccountNumber, wallet)
+		require.NoError(t, err)
+	}
+	return service, merchant.ID
+}
+
+func TestInvoiceRecipient(t *testing.T) {
+	testConfig := Config{QuoteLockWindow: 15 * time.Minute, QuoteTolerance: 0.01, Keys: keys.NewDeriver(secrets.Static("test-key"))}
+	registry, _ := assets.NewRegistry(assets.DefaultAssets)
+	address := "bc1qstaticaddress"
+	accountService, merchantId := newTestMerchant(t, registry,
+		accounts.WalletRequest{Currency: "BTC", Type: accounts.WalletCold, Address: &address},
+		accounts.WalletRequest{Currency: "BTC", Type: accounts.WalletStatic, Address: &address},
+		accounts.WalletRequest{Currency: "ETH", Type: accounts.WalletOTA})
+
+	t.Run("Static Wallet", func(t *testing.T) {
+		repo := &mockTxnRepository{}
+		service := NewTransactionsService(repo, &mockPriceService{}, registry, accountService, prices.NewLocalPriceBroker(), testConfig)
+
+		_, err := service.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: merchantId, Currency: "btc", Amount: 0.1})
+		require.NoError(t, err)
+		require.Len(t, repo.saved, 1)
+		inv := repo.saved[0].(Invoice)
+		assert.Equal(t, address, inv.WalletRef) // never the cold wallet
+		expected, err := testConfig.Keys.Ref(context.Background(), keys.PurposeRecipientRef, merchantId, merchantId, utils.TruncateNone)
+		require.NoError(t, err)
+		assert.Equal(t, expected, inv.RecipientRef) // recomputable from the merchant id alone
+
+		_, err = service.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: merchantId, Currency: "btc", Amount: 0.2})
+		require.NoError(t, err)
+		assert.Equal(t, inv.RecipientRef, repo.saved[1].(Invoice).RecipientRef)
+	})
+
+	t.Run("OTA Wallet", func(t *testing.T) {
+		repo := &mockTxnRepository{}
+		service := NewTransactionsService(repo, &mockPriceService{}, registry, accountService, prices.NewLocalPriceBroker(), testConfig)
+
+		_, err := service.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: merchantId, Currency: "eth", Amount: 1})
+		require.NoError(t, err)
+		assert.Equal(t, GenerateOneTimeAddress("ETH"), repo.saved[0].(Invoice).WalletRef)
+	})
+
+	t.Run("No Wallet For Currency", func(t *testing.T) {
+		service := NewTransactionsService(&mockTxnRepository{}, &mockPriceService{}, registry, accountService, prices.NewLocalPriceBroker(), testConfig)
+
+		_, err := service.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: merchantId, Currency: "xmr", Amount: 1})
+		assert.ErrorIs(t, err, ErrInvalidInvoice)
+	})
+
+	t.Run("Unknown Recipient", func(t *testing.T) {
+		service := NewTransactionsService(&mockTxnRepository{}, &mockPriceService{}, registry, accountService, prices.NewLocalPriceBroker(), testConfig)
+
+		_, err := service.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: "merchant", Currency: "btc", Amount: 1})
+		assert.ErrorIs(t, err, ErrInvalidInvoice)
+	})
+}
@@ -0,0 +1,62 @@
+package transactions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// pub/sub transport for status changes, the price broker satisfies it so every replica sees them
+type StatusBroker interface {
+	Publish(ctx context.Context, channel string, payload string) error
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+}
+
+type StatusUpdate struct {
+	TransactionId string    `json:"transaction_id"`
+	Status        string    `json:"status"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func statusChannel(txnId string) string {
+	return "transactions:status:" + txnId
+}
+
+// best effort, a watcher that misses an update still sees the next one
+func (s *transactionsServiceImpl) publishStatus(ctx context.Context, txn Transaction) {
+	payload, _ := json.Marshal(StatusUpdate{TransactionId: txn.GetID(), Status: txn.GetStatus(), UpdatedAt: txn.Updated().UTC()})
+	if err := s.statuses.Publish(ctx, statusChannel(txn.GetID()), string(payload)); err != nil {
+		log.Printf("Failed to publish status for %s: %v", txn.GetID(), err)
+	}
+}
+
+// stream status changes for one transaction until ctx is done, the channel is closed then
+func (s *transactionsServiceImpl) WatchTransaction(ctx context.Context, txnId string) (<-chan StatusUpdate, error) {
+	if txnId == "" {
+		return nil, fmt.Errorf("%w: transaction id must be set", ErrInvalidInvoice)
+	}
+	msgs, err := s.statuses.Subscribe(ctx, statusChannel(txnId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch transaction %s: %w", txnId, err)
+	}
+
+	updates := make(chan StatusUpdate)
+	go func() {
+		defer close(updates)
+		for msg := range msgs {
+			var update StatusUpdate
+			if err := json.Unmarshal([]byte(msg), &update); err != nil {
+				log.Printf("Failed to parse status update for %s: %v", txnId, err)
+				continue
+			}
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
@@ -0,0 +1,42 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/internal/prices"
+)
+
+func TestWatchTransaction(t *testing.T) {
+	service := NewTransactionsService(&mockTxnRepository{}, &mockPriceService{}, nil, nil, prices.NewLocalPriceBroker(), Config{}).(*transactionsServiceImpl)
+
+	t.Run("Requires Id", func(t *testing.T) {
+		_, err := service.WatchTransaction(context.Background(), "")
+		assert.True(t, errors.Is(err, ErrInvalidInvoice))
+	})
+
+	t.Run("Receives Own Updates", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		updates, err := service.WatchTransaction(ctx, "txn-1")
+		require.NoError(t, err)
+
+		updatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		service.publishStatus(ctx, &Invoice{ID: "txn-2", Status: "Pending", UpdatedAt: updatedAt})
+		service.publishStatus(ctx, &Invoice{ID: "txn-1", Status: "Confirmed", UpdatedAt: updatedAt})
+
+		select {
+		case update := <-updates:
+			assert.Equal(t, StatusUpdate{TransactionId: "txn-1", Status: "Confirmed", UpdatedAt: updatedAt}, update)
+		case <-time.After(time.Second):
+			t.Fatal("no status update received")
+		}
+
+		cancel()
+		for range updates {
+		} // closed once the watcher's context ends
+	})
+}
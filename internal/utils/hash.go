@@ -7,14 +7,23 @@ import (
 	"strings"
 )
 
-func GenerateRef(input string, value string, truncate string) string {
-	key := []byte(input)
+// how much of a 64 char hex hmac a reference keeps
+type Truncation int
+
+const (
+	TruncateNone Truncation = iota // full 64 chars, for refs that must not collide
+	TruncateHard                   // 32 chars from the middle of the digest
+	TruncateSoft                   // first 16 chars, for display or short lived lookups
+)
+
+func GenerateRef(key []byte, value string, truncate Truncation) string {
 	h := hmac.New(sha256.New, key)
 	h.Write([]byte(value))
 	hashed := hex.EncodeToString(h.Sum(nil))
-	if truncate == "hard" {
+	switch truncate {
+	case TruncateHard:
 		return hashed[12:44]
-	} else if truncate == "soft" {
+	case TruncateSoft:
 		return hashed[0:16]
 	}
 	return hashed
@@ -22,4 +31,4 @@ func GenerateRef(input string, value string, truncate string) string {
 
 func BuildReferenceString(parts ...string) string {
 	return strings.Join(parts, "")
-}
\ No newline at end of file
+}
@@ -5,11 +5,11 @@ import (
 )
 
 func TestGenerateRef(t *testing.T) {
-	key := "secret-key"
+	key := []byte("secret-key")
 	value := "test-data"
 
-	full := GenerateRef(key, value, "test")
-	full2 := GenerateRef(key, value, "")
+	full := GenerateRef(key, value, TruncateNone)
+	full2 := GenerateRef(key, value, TruncateNone)
 	if len(full) != 64 {
 		t.Errorf("Expected full hash, got %d", len(full))
 	}
@@ -17,15 +17,18 @@ func TestGenerateRef(t *testing.T) {
 		t.Errorf("Expected consistent hash values, got %s and %s", full, full2)
 	}
 
-	soft := GenerateRef(key, value, "soft")
+	soft := GenerateRef(key, value, TruncateSoft)
 	if len(soft) != 16 {
 		t.Errorf("Expected soft hash with len of 16, got %d", len(soft))
 	}
 
-	hard := GenerateRef(key, value, "hard")
+	hard := GenerateRef(key, value, TruncateHard)
 	if len(hard) != 32 {
 		t.Errorf("Expected hard hash with len of 32, got %d", len(hard))
 	}
+	if hard != full[12:44] || soft != full[:16] {
+		t.Errorf("Expected truncations of the full hash, got %s and %s", hard, soft)
+	}
 }
 
 func TestBuildRefString(t *testing.T) {
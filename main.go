@@ -2,27 +2,115 @@ package main
 
 //start backend service
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/undersleep7x/cryo-project/internal/app"
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"google.golang.org/grpc"
 )
 
-func startServer() *http.Server {
-	a := app.InitApp() //kicks off initialization of necessary precursors like redis and logging
+// how long in-flight requests get to finish once a shutdown signal arrives
+const shutdownTimeout = 5 * time.Second
+
+func startServer(reloader config.Reloader) (*http.Server, *app.App) {
+	a := app.InitApp(reloader) //kicks off initialization of necessary precursors like redis and logging
 
 	port := a.Config.Port
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%s", port),
+		Addr:    fmt.Sprintf(":%d", port),
 		Handler: a.Router,
 	}
-	log.Printf("Cryo started on port %s", port)
-	return server
+	log.Printf("Cryo started on port %d", port)
+	return server, a
+
+}
 
+func serveGRPC(server *grpc.Server, port int) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for gRPC on port %d: %w", port, err)
+	}
+	log.Printf("Cryo gRPC started on port %d", port)
+	if err := server.Serve(lis); err != nil {
+		return fmt.Errorf("gRPC server failed: %w", err)
+	}
+	return nil
 }
 
 func main() {
-	server := startServer()
-	log.Fatal(server.ListenAndServe())
+	configPath := flag.String("config", "", "path to a yaml or toml config file (overrides CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+	flag.Parse()
+
+	// fail fast with every bad setting listed rather than starting half configured
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// `cryo migrate up|down|status|redo` runs migrations and exits without starting the server
+	if flag.Arg(0) == "migrate" {
+		if err := app.RunMigrate(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("Migrate failed: %v", err)
+		}
+		return
+	}
+
+	// `cryo keys rotate|status|retire <version>` manages field encryption key versions and exits
+	if flag.Arg(0) == "keys" {
+		if err := app.RunKeys(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("Keys failed: %v", err)
+		}
+		return
+	}
+
+	if *printConfig {
+		out, err := cfg.Dump()
+		if err != nil {
+			log.Fatalf("Failed to render config: %v", err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server, a := startServer(config.NewReloader(*configPath, cfg))
+	// either server failing shuts down both, the same way a signal does
+	serveErr := make(chan error, 2)
+	if a.GRPCServer != nil {
+		go func() { serveErr <- serveGRPC(a.GRPCServer, a.Config.GRPC.Port) }()
+	}
+	go func() { serveErr <- server.ListenAndServe() }()
+
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down...")
+	case err = <-serveErr:
+		log.Printf("Server failed, shutting down: %v", err)
+	}
+	stop() // a second signal kills the process straight away
+
+	// stop taking requests and let in-flight ones finish, then gracefully stop grpc and flush traces
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Failed to drain HTTP requests: %v", err)
+	}
+	a.Close(shutdownCtx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Cryo stopped")
 }
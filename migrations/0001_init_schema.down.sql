@@ -0,0 +1,7 @@
+-- Cryo DB Schema v1.1 - rollback
+
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS refunds;
+DROP TABLE IF EXISTS merchants;
+DROP TABLE IF EXISTS wallets;
+DROP TABLE IF EXISTS users;
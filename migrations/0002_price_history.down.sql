@@ -0,0 +1,4 @@
+-- Cryo DB Schema - price history rollback
+
+DROP TABLE IF EXISTS price_ohlc;
+DROP TABLE IF EXISTS price_history;
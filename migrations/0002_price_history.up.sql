@@ -0,0 +1,33 @@
+-- Cryo DB Schema - price history
+
+-- PRICE HISTORY TABLE
+CREATE TABLE price_history (
+    id BIGSERIAL PRIMARY KEY,
+    crypto TEXT NOT NULL,                          -- provider asset id (ex. bitcoin)
+    currency TEXT NOT NULL,                        -- quote currency (ex. usd)
+    price NUMERIC(36, 18) NOT NULL,
+    priced_at TIMESTAMP NOT NULL,                  -- provider timestamp for the price point (UTC)
+    source TEXT NOT NULL,                          -- provider the price was sourced from
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    UNIQUE (crypto, currency, priced_at)
+);
+
+CREATE INDEX idx_price_history_lookup ON price_history (crypto, currency, priced_at);
+
+-- PRICE OHLC TABLE
+CREATE TABLE price_ohlc (
+    id BIGSERIAL PRIMARY KEY,
+    crypto TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    candle_interval TEXT NOT NULL CHECK (candle_interval IN ('5m', '1h', '4h', '1d')),
+    open_time TIMESTAMP NOT NULL,                  -- start of the candle bucket (UTC)
+    open NUMERIC(36, 18) NOT NULL,
+    high NUMERIC(36, 18) NOT NULL,
+    low NUMERIC(36, 18) NOT NULL,
+    close NUMERIC(36, 18) NOT NULL,
+    source TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    UNIQUE (crypto, currency, candle_interval, open_time)
+);
@@ -0,0 +1,9 @@
+-- Cryo DB Schema - locked exchange-rate quotes rollback
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS quote_fiat_currency,
+    DROP COLUMN IF EXISTS quote_fiat_amount,
+    DROP COLUMN IF EXISTS quote_rate,
+    DROP COLUMN IF EXISTS quote_crypto_amount,
+    DROP COLUMN IF EXISTS quote_locked_at,
+    DROP COLUMN IF EXISTS quote_expires_at;
@@ -0,0 +1,9 @@
+-- Cryo DB Schema - locked exchange-rate quotes for fiat priced invoices
+
+ALTER TABLE transactions
+    ADD COLUMN quote_fiat_currency TEXT,               -- fiat the invoice was priced in (null for crypto priced invoices)
+    ADD COLUMN quote_fiat_amount NUMERIC(36, 18),
+    ADD COLUMN quote_rate NUMERIC(36, 18),             -- fiat price of one unit of currency when locked
+    ADD COLUMN quote_crypto_amount NUMERIC(36, 18),
+    ADD COLUMN quote_locked_at TIMESTAMP,
+    ADD COLUMN quote_expires_at TIMESTAMP;             -- payments detected after this are re-quoted
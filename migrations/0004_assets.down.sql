@@ -0,0 +1,3 @@
+-- Cryo DB Schema - supported asset registry rollback
+
+DROP TABLE IF EXISTS assets;
@@ -0,0 +1,23 @@
+-- Cryo DB Schema - supported asset registry
+
+-- ASSETS TABLE
+CREATE TABLE assets (
+    ticker TEXT PRIMARY KEY,                       -- canonical symbol (ex. BTC)
+    name TEXT NOT NULL,
+    coingecko_id TEXT NOT NULL UNIQUE,             -- id used by the coingecko price api (ex. bitcoin)
+    provider_ids JSONB NOT NULL DEFAULT '{}',      -- ids for other price providers keyed by provider name
+    decimals INT NOT NULL CHECK (decimals >= 0),
+    chain TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP
+);
+
+INSERT INTO assets (ticker, name, coingecko_id, decimals, chain) VALUES
+    ('BTC', 'Bitcoin', 'bitcoin', 8, 'bitcoin'),
+    ('ETH', 'Ethereum', 'ethereum', 18, 'ethereum'),
+    ('XMR', 'Monero', 'monero', 12, 'monero'),
+    ('LTC', 'Litecoin', 'litecoin', 8, 'litecoin'),
+    ('SOL', 'Solana', 'solana', 9, 'solana'),
+    ('USDT', 'Tether', 'tether', 6, 'ethereum'),
+    ('USDC', 'USD Coin', 'usd-coin', 6, 'ethereum');
@@ -0,0 +1,3 @@
+-- Cryo DB Schema - fencing tokens for postgres advisory lock leases rollback
+
+DROP SEQUENCE IF EXISTS lock_fencing_tokens;
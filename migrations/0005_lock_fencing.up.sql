@@ -0,0 +1,4 @@
+-- Cryo DB Schema - fencing tokens for postgres advisory lock leases
+
+-- shared by every lock name, only ordering matters so gaps are fine
+CREATE SEQUENCE lock_fencing_tokens;
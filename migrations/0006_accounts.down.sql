@@ -0,0 +1,12 @@
+-- Cryo DB Schema - accounts rollback
+
+DROP INDEX IF EXISTS idx_merchants_account;
+DROP INDEX IF EXISTS idx_wallets_owner;
+
+ALTER TABLE wallets
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS address;
+
+-- only restorable while every owner_ref is still a user account number
+ALTER TABLE wallets
+    ADD CONSTRAINT wallets_owner_ref_fkey FOREIGN KEY (owner_ref) REFERENCES users(account_number) ON DELETE CASCADE;
@@ -0,0 +1,11 @@
+-- Cryo DB Schema - accounts (users, merchants and wallets)
+
+-- owner_ref is derived per owner (HMAC(owner + 'wallet_owner')) and merchants own wallets too,
+-- so it can't reference users(account_number)
+ALTER TABLE wallets
+    DROP CONSTRAINT IF EXISTS wallets_owner_ref_fkey,
+    ADD COLUMN address TEXT,                          -- receiving address, null for ota wallets which derive one per invoice
+    ADD COLUMN updated_at TIMESTAMP;
+
+CREATE INDEX idx_wallets_owner ON wallets (owner_ref, currency);
+CREATE INDEX idx_merchants_account ON merchants (account_ref);
@@ -0,0 +1,3 @@
+-- Cryo DB Schema - key rotation progress rollback
+
+DROP TABLE IF EXISTS key_rotation_progress;
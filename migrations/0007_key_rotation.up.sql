@@ -0,0 +1,15 @@
+-- Cryo DB Schema - progress of moving encrypted columns onto a new key encryption key version
+
+CREATE TABLE key_rotation_progress (
+    key_version INT NOT NULL,                      -- version the column is being moved onto
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    last_id UUID NOT NULL,                         -- resume point, rows are walked in id order
+    scanned BIGINT NOT NULL DEFAULT 0,
+    rewrapped BIGINT NOT NULL DEFAULT 0,
+    pass_rewrapped BIGINT NOT NULL DEFAULT 0,      -- a full pass that moves nothing verifies the column
+    completed_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (key_version, table_name, column_name)
+);
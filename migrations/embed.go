@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// numbered schema migrations, NNNN_name.up.sql with a matching NNNN_name.down.sql.
+// compiled into the binary so `cryo migrate` and startup checks need no files on disk
+//
+//go:embed *.sql
+var FS embed.FS
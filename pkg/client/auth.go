@@ -0,0 +1,29 @@
+package client
+
+import "net/http"
+
+// sets credentials on each outgoing attempt
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// adapts a plain function, e.g. one that fetches a fresh token per attempt
+type AuthenticatorFunc func(req *http.Request) error
+
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+func BearerToken(token string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+func APIKey(header string, key string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set(header, key)
+		return nil
+	})
+}
@@ -0,0 +1,233 @@
+// Package client is a Go client for the Cryo API.
+//
+// Requests go to the /v1 routes. Reads are retried on transient failures. Writes carry an
+// Idempotency-Key header that stays the same across retries of one call, but the server
+// doesn't deduplicate on it yet, so a write is only retried when the connection couldn't be
+// made and the request never left the client.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	apiPrefix            = "/v1"
+	IdempotencyKeyHeader = "Idempotency-Key"
+	AccountNumberHeader  = "X-Account-Number" // proves ownership of a merchant or user
+
+	defaultTimeout      = 10 * time.Second
+	defaultMaxRetries   = 2
+	defaultRetryWait    = 200 * time.Millisecond
+	defaultRetryMaxWait = 2 * time.Second
+)
+
+// statuses a read is retried on
+var retryableStatuses = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+type Config struct {
+	BaseURL      string        // scheme and host of the api, e.g. https://cryo.example.com
+	Auth         Authenticator // optional, applied to every attempt
+	HTTPClient   *http.Client  // optional, for custom transports or proxies
+	Timeout      time.Duration // per attempt, defaultTimeout when zero
+	MaxRetries   int           // retries after the first attempt, defaultMaxRetries when zero, negative disables
+	RetryWait    time.Duration // first backoff, doubled per retry, defaultRetryWait when zero
+	RetryMaxWait time.Duration // backoff cap, defaultRetryMaxWait when zero
+}
+
+type Client interface {
+	CreateInvoice(ctx context.Context, req InvoiceRequest) (*InvoiceResponse, error)
+	SendPayment(ctx context.Context, req PaymentRequest) (*PaymentResponse, error)
+	GetTransaction(ctx context.Context, transactionId string) (*TransactionDetails, error)
+	ListTransactions(ctx context.Context, merchantId string, accountNumber string) (*TransactionList, error)
+	FetchPrices(ctx context.Context, cryptos []string, currency string) (*PriceResponse, error)
+}
+
+type clientImpl struct {
+	http *resty.Client
+}
+
+func New(cfg Config) (Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("client: BaseURL must be set")
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	retries := cfg.MaxRetries
+	if retries == 0 {
+		retries = defaultMaxRetries
+	} else if retries < 0 {
+		retries = 0
+	}
+	retryWait := cfg.RetryWait
+	if retryWait == 0 {
+		retryWait = defaultRetryWait
+	}
+	retryMaxWait := cfg.RetryMaxWait
+	if retryMaxWait == 0 {
+		retryMaxWait = defaultRetryMaxWait
+	}
+
+	r := resty.NewWithClient(httpClient).
+		SetBaseURL(strings.TrimSuffix(cfg.BaseURL, "/")+apiPrefix).
+		SetTimeout(timeout).
+		SetHeader("Accept", "application/json").
+		SetRetryCount(retries).
+		SetRetryWaitTime(retryWait).
+		SetRetryMaxWaitTime(retryMaxWait).
+		AddRetryCondition(shouldRetry)
+	if cfg.Auth != nil {
+		// runs on the raw request of every attempt, so short lived tokens can be refreshed between retries
+		r.SetPreRequestHook(func(_ *resty.Client, req *http.Request) error {
+			return cfg.Auth.Authenticate(req)
+		})
+	}
+	return &clientImpl{http: r}, nil
+}
+
+// a write that reached the server may have been applied even when the answer was an error
+// or never came, so only reads are retried once a request could have been sent
+func shouldRetry(resp *resty.Response, err error) bool {
+	if resp != nil && resp.Request != nil && resp.Request.Method != http.MethodGet {
+		return err != nil && notSent(err)
+	}
+	if err != nil {
+		return true
+	}
+	return retryableStatuses[resp.StatusCode()]
+}
+
+// dial and dns failures happen before any of the request is written
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// create an invoice, priced in crypto or in fiat with a locked quote
+func (c *clientImpl) CreateInvoice(ctx context.Context, req InvoiceRequest) (*InvoiceResponse, error) {
+	var out InvoiceResponse
+	if _, err := c.do(ctx, http.MethodPost, "/invoice", req, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// send a direct payment, or pay an invoice when InvoiceId is set
+func (c *clientImpl) SendPayment(ctx context.Context, req PaymentRequest) (*PaymentResponse, error) {
+	var out PaymentResponse
+	if _, err := c.do(ctx, http.MethodPost, "/send-payment", req, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// fetch an invoice or payment by id, a 404 *APIError when it doesn't exist
+func (c *clientImpl) GetTransaction(ctx context.Context, transactionId string) (*TransactionDetails, error) {
+	var out TransactionDetails
+	if _, err := c.do(ctx, http.MethodGet, "/transactions/"+url.PathEscape(transactionId), nil, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// list a merchant's invoices, newest first, with the account number the merchant was created under
+func (c *clientImpl) ListTransactions(ctx context.Context, merchantId string, accountNumber string) (*TransactionList, error) {
+	var out TransactionList
+	query := map[string]string{"merchant_id": merchantId}
+	headers := map[string]string{AccountNumberHeader: accountNumber}
+	if _, err := c.do(ctx, http.MethodGet, "/transactions", nil, query, headers, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// fetch latest prices. a partial result is not an error, failed assets are listed in Errors.
+// when no asset resolves the response is still returned alongside the *APIError
+func (c *clientImpl) FetchPrices(ctx context.Context, cryptos []string, currency string) (*PriceResponse, error) {
+	var out PriceResponse
+	query := map[string]string{"crypto": strings.Join(cryptos, ","), "currency": currency}
+	resp, err := c.do(ctx, http.MethodGet, "/price", nil, query, nil, &out)
+	if err != nil {
+		var apiErr *APIError
+		// no asset resolved, the body still carries the per-asset failures
+		if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusBadGateway) {
+			if jsonErr := json.Unmarshal(resp.Body(), &out); jsonErr == nil {
+				return &out, err
+			}
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+// send one call, retrying per the client config. writes always carry an idempotency key,
+// taken from the context when the caller set one so their own retries reuse it
+func (c *clientImpl) do(ctx context.Context, method string, path string, body any, query map[string]string, headers map[string]string, out any) (*resty.Response, error) {
+	req := c.http.R().SetContext(ctx).SetResult(out).SetError(&APIError{})
+	if body != nil {
+		req.SetBody(body)
+	}
+	if query != nil {
+		req.SetQueryParams(query)
+	}
+	if headers != nil {
+		req.SetHeaders(headers)
+	}
+	if method != http.MethodGet {
+		key, ok := IdempotencyKey(ctx)
+		if !ok {
+			key = uuid.NewString()
+		}
+		req.SetHeader(IdempotencyKeyHeader, key)
+	}
+
+	resp, err := req.Execute(method, path)
+	if err != nil {
+		return nil, fmt.Errorf("cryo %s %s: %w", method, path, err)
+	}
+	if resp.IsError() {
+		apiErr, _ := resp.Error().(*APIError)
+		if apiErr == nil {
+			apiErr = &APIError{}
+		}
+		apiErr.StatusCode = resp.StatusCode()
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode())
+		}
+		return resp, apiErr
+	}
+	return resp, nil
+}
+
+type idempotencyKeyCtx struct{}
+
+// attach a caller chosen idempotency key, for callers that retry a call themselves
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyCtx{}).(string)
+	return key, ok && key != ""
+}
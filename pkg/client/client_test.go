@@ -0,0 +1,396 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	resty "github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/undersleep7x/cryo-project/api/routes"
+	"github.com/undersleep7x/cryo-project/internal/accounts"
+	"github.com/undersleep7x/cryo-project/internal/accounts/accountsfake"
+	"github.com/undersleep7x/cryo-project/internal/assets"
+	"github.com/undersleep7x/cryo-project/internal/config"
+	"github.com/undersleep7x/cryo-project/internal/crypto/keys"
+	"github.com/undersleep7x/cryo-project/internal/infra/cache"
+	"github.com/undersleep7x/cryo-project/internal/platform/redisstore/redisfake"
+	"github.com/undersleep7x/cryo-project/internal/prices"
+	"github.com/undersleep7x/cryo-project/internal/secrets"
+	"github.com/undersleep7x/cryo-project/internal/transactions"
+)
+
+// records what reached the server and can fail the first few requests
+type testServer struct {
+	*httptest.Server
+	mu            sync.Mutex
+	requests      []*http.Request
+	failNext      int
+	merchant      string // registered merchant holding a btc wallet
+	accountNumber string // account the merchant was created under
+}
+
+func (s *testServer) seen() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+func (s *testServer) fail(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+}
+
+// the real router and services, with the price provider stubbed and an in-memory cache
+func newTestServer(t *testing.T) *testServer {
+	gin.SetMode(gin.TestMode)
+	originalFetchPrices := prices.FetchPrices
+	t.Cleanup(func() { prices.FetchPrices = originalFetchPrices })
+	prices.FetchPrices = func(ctx context.Context, cryptos []string, currency string, baseURL string, timeoutVal int) (*resty.Response, error) {
+		dummyResponse := &resty.Response{RawResponse: &http.Response{StatusCode: http.StatusOK}}
+		if strings.Contains(strings.Join(cryptos, ","), "bitcoin") {
+			dummyResponse.SetBody([]byte(`{"bitcoin": {"usd": 50000}}`))
+		} else {
+			dummyResponse.SetBody([]byte(`{}`))
+		}
+		return dummyResponse, nil
+	}
+
+	registry, err := assets.NewRegistry(assets.DefaultAssets)
+	require.NoError(t, err)
+	priceService := prices.NewFetchCryptoPriceService(cache.NewPriceCache(redisfake.New()), prices.NewSettings(prices.Config{BaseURL: "https://dummy-coingecko.com", Timeout: 5}))
+	txnConfig := transactions.Config{QuoteLockWindow: 15 * time.Minute, QuoteTolerance: 0.01, Keys: keys.NewDeriver(secrets.Static("test-key"))}
+	accountService := accounts.NewAccountService(accountsfake.New(), registry, accounts.Config{Keys: keys.NewDeriver(secrets.Static("test-key"))})
+	txnService := transactions.NewTransactionsService(transactions.NewTxnRepository(), priceService, registry, accountService, prices.NewLocalPriceBroker(), txnConfig)
+
+	registration, err := accountService.RegisterUser(context.Background())
+	require.NoError(t, err)
+	merchant, err := accountService.CreateMerchant(context.Background(), accounts.MerchantRequest{AccountNumber: registration.AccountNumber, Name: "Test Merchant"})
+	require.NoError(t, err)
+	_, err = accountService.AttachWallet(context.Background(), accounts.OwnerMerchant, merchant.ID, registration.AccountNumber, accounts.WalletRequest{Currency: "BTC", Type: accounts.WalletOTA})
+	require.NoError(t, err)
+
+	s := &testServer{merchant: merchant.ID, accountNumber: registration.AccountNumber}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		s.mu.Lock()
+		s.requests = append(s.requests, c.Request.Clone(context.Background()))
+		failing := s.failNext > 0
+		if failing {
+			s.failNext--
+		}
+		s.mu.Unlock()
+		if failing {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "try again"})
+		}
+	})
+	routes.SetupRoutes(router, config.ServerConfig{RequestTimeout: 5 * time.Second, HistoryTimeout: 5 * time.Second},
+		prices.NewPriceHandler(priceService, registry), &prices.PriceHistoryHandler{}, &prices.PriceStreamHandler{}, &prices.PrewarmHandler{},
+		transactions.NewTransactionsHandler(txnService), accounts.NewAccountsHandler(accountService))
+	s.Server = httptest.NewServer(router)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestClient(t *testing.T, server *testServer) Client {
+	c, err := New(Config{BaseURL: server.URL, Auth: BearerToken("test-token"), RetryWait: time.Millisecond, RetryMaxWait: 5 * time.Millisecond})
+	require.NoError(t, err)
+	return c
+}
+
+func TestCreateInvoice(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		inv, err := c.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: server.merchant, Currency: "btc", FiatCurrency: "usd", FiatAmount: 100})
+		require.NoError(t, err)
+		assert.Equal(t, "BTC", inv.Currency)
+		assert.Equal(t, 0.002, inv.Amount)
+		assert.Equal(t, 50000.00, inv.Quote.Rate)
+
+		seen := server.seen()
+		require.Len(t, seen, 1)
+		assert.Equal(t, "/v1/invoice", seen[0].URL.Path)
+		assert.Equal(t, "Bearer test-token", seen[0].Header.Get("Authorization"))
+		assert.NotEmpty(t, seen[0].Header.Get(IdempotencyKeyHeader))
+	})
+
+	t.Run("Invalid Invoice", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		_, err := c.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: server.merchant, Currency: "notacoin", Amount: 1})
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Contains(t, apiErr.Message, "unsupported currency")
+		assert.Len(t, server.seen(), 1) // client errors are not retried
+	})
+
+	t.Run("Retries Connection Failures With Same Key", func(t *testing.T) {
+		server := newTestServer(t)
+		var mu sync.Mutex
+		dials, failDials := 0, 2
+		transport := &http.Transport{DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dials++
+			if dials <= failDials {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("connection refused")}
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}}
+		c, err := New(Config{BaseURL: server.URL, Auth: BearerToken("test-token"), HTTPClient: &http.Client{Transport: transport}, RetryWait: time.Millisecond, RetryMaxWait: 5 * time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = c.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: server.merchant, Currency: "btc", Amount: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 3, dials)
+		seen := server.seen()
+		require.Len(t, seen, 1) // the request was only ever sent once
+		assert.NotEmpty(t, seen[0].Header.Get(IdempotencyKeyHeader))
+	})
+
+	t.Run("Not Retried On 5xx", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+		server.fail(1)
+
+		// the server may have created the invoice before failing, a retry could create another
+		_, err := c.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: server.merchant, Currency: "btc", Amount: 1})
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.Len(t, server.seen(), 1)
+	})
+
+	t.Run("Not Retried After Sending", func(t *testing.T) {
+		var hits atomic.Int32
+		dropped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			panic(http.ErrAbortHandler) // connection closed with no response
+		}))
+		defer dropped.Close()
+		c, err := New(Config{BaseURL: dropped.URL, RetryWait: time.Millisecond, RetryMaxWait: 5 * time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = c.SendPayment(context.Background(), PaymentRequest{SenderId: "user", Currency: "BTC", Amount: 0.5, PaymentAddr: "addr"})
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), hits.Load())
+	})
+
+	t.Run("Caller Key", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		ctx := WithIdempotencyKey(context.Background(), "order-42")
+		_, err := c.CreateInvoice(ctx, InvoiceRequest{RecipientId: server.merchant, Currency: "btc", Amount: 1})
+		require.NoError(t, err)
+		assert.Equal(t, "order-42", server.seen()[0].Header.Get(IdempotencyKeyHeader))
+	})
+
+	t.Run("Cancelled Context", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := c.CreateInvoice(ctx, InvoiceRequest{RecipientId: server.merchant, Currency: "btc", Amount: 1})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, server.seen())
+	})
+}
+
+func TestSendPayment(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server)
+
+	payment, err := c.SendPayment(context.Background(), PaymentRequest{SenderId: "user", Currency: "BTC", Amount: 0.5, PaymentAddr: "addr"})
+	require.NoError(t, err)
+	assert.Equal(t, "addr", payment.PaymentAddr)
+	assert.Equal(t, "Pending", payment.Status)
+	assert.Equal(t, "/v1/send-payment", server.seen()[0].URL.Path)
+}
+
+func TestGetTransaction(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+		ref := "order-42"
+		inv, err := c.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: server.merchant, Currency: "btc", FiatCurrency: "usd", FiatAmount: 100, ExternalRef: &ref})
+		require.NoError(t, err)
+
+		txn, err := c.GetTransaction(context.Background(), inv.TransactionId)
+		require.NoError(t, err)
+		assert.Equal(t, inv.TransactionId, txn.TransactionId)
+		assert.Equal(t, "invoice", txn.Type)
+		assert.Equal(t, "invoice", txn.Status)
+		assert.Equal(t, 0.002, txn.Amount)
+		assert.Equal(t, &ref, txn.ExternalRef)
+		assert.Equal(t, 50000.00, txn.Quote.Rate)
+		assert.Equal(t, "/v1/transactions/"+inv.TransactionId, server.seen()[1].URL.Path)
+	})
+
+	t.Run("Payment", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+		payment, err := c.SendPayment(context.Background(), PaymentRequest{SenderId: "user", Currency: "BTC", Amount: 0.5, PaymentAddr: "addr"})
+		require.NoError(t, err)
+
+		txn, err := c.GetTransaction(context.Background(), payment.TransactionId)
+		require.NoError(t, err)
+		assert.Equal(t, "payment", txn.Type)
+		assert.Equal(t, 0.5, txn.Amount)
+		assert.Nil(t, txn.Quote)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		_, err := c.GetTransaction(context.Background(), "txn_missing")
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Len(t, server.seen(), 1)
+	})
+}
+
+func TestListTransactions(t *testing.T) {
+	t.Run("Newest First", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+		first, err := c.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: server.merchant, Currency: "btc", Amount: 1})
+		require.NoError(t, err)
+		second, err := c.CreateInvoice(context.Background(), InvoiceRequest{RecipientId: server.merchant, Currency: "btc", Amount: 2})
+		require.NoError(t, err)
+		_, err = c.SendPayment(context.Background(), PaymentRequest{SenderId: "user", Currency: "BTC", Amount: 0.5, PaymentAddr: "addr"})
+		require.NoError(t, err)
+
+		list, err := c.ListTransactions(context.Background(), server.merchant, server.accountNumber)
+		require.NoError(t, err)
+		require.Len(t, list.Transactions, 2) // the direct payment isn't to the merchant
+		assert.Equal(t, second.TransactionId, list.Transactions[0].TransactionId)
+		assert.Equal(t, first.TransactionId, list.Transactions[1].TransactionId)
+
+		seen := server.seen()
+		last := seen[len(seen)-1]
+		assert.Equal(t, "/v1/transactions", last.URL.Path)
+		assert.Equal(t, server.merchant, last.URL.Query().Get("merchant_id"))
+		assert.Equal(t, server.accountNumber, last.Header.Get(AccountNumberHeader))
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		list, err := c.ListTransactions(context.Background(), server.merchant, server.accountNumber)
+		require.NoError(t, err)
+		assert.NotNil(t, list.Transactions)
+		assert.Empty(t, list.Transactions)
+	})
+
+	t.Run("Ownership", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+		tests := []struct {
+			name          string
+			merchant      string
+			accountNumber string
+			status        int
+		}{
+			{"Missing Account Number", server.merchant, "", http.StatusUnauthorized},
+			{"Other Account", server.merchant, "0000000000000000", http.StatusForbidden},
+			{"Unknown Merchant", "00000000-0000-0000-0000-000000000000", server.accountNumber, http.StatusNotFound},
+			{"Missing Merchant", "", server.accountNumber, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := c.ListTransactions(context.Background(), tt.merchant, tt.accountNumber)
+				var apiErr *APIError
+				require.ErrorAs(t, err, &apiErr)
+				assert.Equal(t, tt.status, apiErr.StatusCode)
+			})
+		}
+	})
+}
+
+func TestFetchPrices(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		result, err := c.FetchPrices(context.Background(), []string{"BTC"}, "USD")
+		require.NoError(t, err)
+		assert.Equal(t, 50000.00, result.Prices["BTC"].Price)
+		assert.Equal(t, StatusOK, result.Prices["BTC"].Status)
+		assert.Empty(t, server.seen()[0].Header.Get(IdempotencyKeyHeader)) // reads don't need a key
+	})
+
+	t.Run("Retries Transient Failures", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+		server.fail(2)
+
+		result, err := c.FetchPrices(context.Background(), []string{"BTC"}, "usd")
+		require.NoError(t, err)
+		assert.Equal(t, 50000.00, result.Prices["BTC"].Price)
+		assert.Len(t, server.seen(), 3)
+	})
+
+	t.Run("Retries Exhausted", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+		server.fail(5)
+
+		_, err := c.FetchPrices(context.Background(), []string{"BTC"}, "usd")
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.Len(t, server.seen(), 1+defaultMaxRetries)
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		result, err := c.FetchPrices(context.Background(), []string{"BTC", "ETH"}, "usd")
+		require.NoError(t, err)
+		assert.Contains(t, result.Prices, "BTC")
+		assert.Equal(t, StatusNotFound, result.Errors["ETH"].Status)
+	})
+
+	t.Run("Nothing Resolved", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		result, err := c.FetchPrices(context.Background(), []string{"ETH"}, "usd")
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		require.NotNil(t, result)
+		assert.Equal(t, StatusNotFound, result.Errors["ETH"].Status)
+	})
+
+	t.Run("Unsupported Asset", func(t *testing.T) {
+		server := newTestServer(t)
+		c := newTestClient(t, server)
+
+		_, err := c.FetchPrices(context.Background(), []string{"notacoin"}, "usd")
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Contains(t, apiErr.Suggestions, "notacoin")
+	})
+}
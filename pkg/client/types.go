@@ -0,0 +1,117 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// request and response shapes are declared here rather than shared with the server, so the
+// sdk doesn't pull the server's dependencies into consumers. the contract test in api/routes
+// checks each of them against the openapi spec the handlers are held to
+
+type PriceStatus string
+
+const (
+	StatusOK            PriceStatus = "ok"             // fresh price from cache or provider
+	StatusNotFound      PriceStatus = "not_found"      // provider doesn't know the asset/currency pair
+	StatusUpstreamError PriceStatus = "upstream_error" // provider call failed and nothing usable was cached
+	StatusStale         PriceStatus = "stale"          // provider call failed, last known price served instead
+)
+
+type InvoiceRequest struct {
+	RecipientId  string  `json:"recipient_id"`            // merchant the invoice is paid to
+	Currency     string  `json:"currency"`                // crypto the invoice will be paid in
+	Amount       float64 `json:"amount"`                  // crypto amount, ignored when the invoice is priced in fiat
+	FiatCurrency string  `json:"fiat_currency,omitempty"` // when set, the invoice is priced in fiat and converted with a locked quote
+	FiatAmount   float64 `json:"fiat_amount,omitempty"`
+	ExternalRef  *string `json:"external_ref,omitempty"`
+	SenderType   string  `json:"sender_type"`
+	RefundRef    *string `json:"refund_ref,omitempty"`
+}
+
+type InvoiceResponse struct {
+	TransactionId string      `json:"transaction_id"`
+	Status        string      `json:"status"` // invoice, pending, confirmed, failed
+	ExternalRef   *string     `json:"external_ref,omitempty"`
+	Currency      string      `json:"currency"`
+	Amount        float64     `json:"amount"`          // crypto amount due
+	Quote         *PriceQuote `json:"quote,omitempty"` // locked exchange rate for fiat priced invoices
+}
+
+type PaymentRequest struct {
+	SenderId    string  `json:"sender_id"`
+	Currency    string  `json:"currency"`
+	Amount      float64 `json:"amount"`
+	PaymentAddr string  `json:"payment_address"`
+	SenderType  string  `json:"sender_type"`
+	WalletRef   string  `json:"wallet_ref"`
+	InvoiceId   string  `json:"invoice_id"` // pays the invoice when set
+}
+
+type PaymentResponse struct {
+	TransactionId string  `json:"transaction_id"`
+	PaymentAddr   string  `json:"payment_address,omitempty"`
+	Status        string  `json:"status"` // invoice, pending, confirmed, failed
+	ExternalRef   *string `json:"external_ref,omitempty"`
+}
+
+// invoice or payment as returned by GetTransaction and ListTransactions
+type TransactionDetails struct {
+	TransactionId string      `json:"transaction_id"`
+	Type          string      `json:"type"`   // invoice or payment
+	Status        string      `json:"status"` // invoice, pending, confirmed, failed
+	Currency      string      `json:"currency"`
+	Amount        float64     `json:"amount"`
+	TxnHash       string      `json:"tx_hash,omitempty"` // set once the transaction is on chain
+	ExternalRef   *string     `json:"external_ref,omitempty"`
+	Quote         *PriceQuote `json:"quote,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// body of GET /v1/transactions
+type TransactionList struct {
+	Transactions []TransactionDetails `json:"transactions"` // newest first
+}
+
+// fiat -> crypto exchange rate locked onto an invoice
+type PriceQuote struct {
+	FiatCurrency string    `json:"fiat_currency"`
+	FiatAmount   float64   `json:"fiat_amount"`
+	Rate         float64   `json:"rate"` // price of one unit of crypto in fiat
+	CryptoAmount float64   `json:"crypto_amount"`
+	LockedAt     time.Time `json:"locked_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type AssetPrice struct {
+	Crypto    string      `json:"crypto"`
+	Status    PriceStatus `json:"status"`
+	Price     float64     `json:"price,omitempty"`
+	Source    string      `json:"source,omitempty"`
+	FetchedAt *time.Time  `json:"fetched_at,omitempty"` // when the price was fetched from the provider
+	Error     string      `json:"error,omitempty"`
+}
+
+// usable reports whether the entry carries a price, fresh or stale
+func (a AssetPrice) Usable() bool {
+	return a.Status == StatusOK || a.Status == StatusStale
+}
+
+// body of GET /v1/price, keyed by the symbols as requested
+type PriceResponse struct {
+	Currency string                `json:"currency"`
+	Prices   map[string]AssetPrice `json:"prices"`
+	Errors   map[string]AssetPrice `json:"errors,omitempty"` // assets without a usable price
+}
+
+// non-2xx answer from the api
+type APIError struct {
+	StatusCode  int                 `json:"-"`
+	Message     string              `json:"error"`
+	Suggestions map[string][]string `json:"suggestions,omitempty"` // closest supported assets for unsupported symbols
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("cryo api: %d %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// header carrying the webhook signature, formatted t=<unix seconds>,v1=<hex hmac-sha256>.
+// the mac covers "<t>.<raw body>", and several v1 entries may be sent while a secret is rotated
+const SignatureHeader = "Cryo-Signature"
+
+const DefaultWebhookTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature timestamp outside tolerance")
+)
+
+var now = time.Now
+
+// check a webhook body against its signature header. tolerance bounds replay of old deliveries,
+// DefaultWebhookTolerance when zero
+func VerifyWebhook(payload []byte, header string, secret string, tolerance time.Duration) error {
+	if tolerance == 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed %s header", ErrInvalidSignature, SignatureHeader)
+	}
+	if age := now().Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrSignatureExpired
+	}
+
+	expected := webhookMAC(payload, timestamp, secret)
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// build a signature header for payload, the counterpart of VerifyWebhook
+func SignWebhook(payload []byte, secret string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(webhookMAC(payload, timestamp, secret)))
+}
+
+func webhookMAC(payload []byte, timestamp string, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	sentAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	originalNow := now
+	defer func() { now = originalNow }()
+	now = func() time.Time { return sentAt.Add(time.Minute) }
+	payload := []byte(`{"event":"invoice.paid","transaction_id":"txn_1"}`)
+
+	t.Run("Valid", func(t *testing.T) {
+		header := SignWebhook(payload, "whsec", sentAt)
+		assert.NoError(t, VerifyWebhook(payload, header, "whsec", 0))
+	})
+
+	t.Run("Rotated Secret", func(t *testing.T) {
+		_, current, _ := strings.Cut(SignWebhook(payload, "whsec", sentAt), ",")
+		header := SignWebhook(payload, "old", sentAt) + "," + current
+		assert.NoError(t, VerifyWebhook(payload, header, "whsec", 0))
+	})
+
+	t.Run("Tampered Body", func(t *testing.T) {
+		header := SignWebhook(payload, "whsec", sentAt)
+		assert.ErrorIs(t, VerifyWebhook([]byte(`{"event":"invoice.paid","transaction_id":"txn_2"}`), header, "whsec", 0), ErrInvalidSignature)
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		header := SignWebhook(payload, "other", sentAt)
+		assert.ErrorIs(t, VerifyWebhook(payload, header, "whsec", 0), ErrInvalidSignature)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		header := SignWebhook(payload, "whsec", sentAt.Add(-time.Hour))
+		assert.ErrorIs(t, VerifyWebhook(payload, header, "whsec", 0), ErrSignatureExpired)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		assert.ErrorIs(t, VerifyWebhook(payload, "v1=abc", "whsec", 0), ErrInvalidSignature)
+	})
+}